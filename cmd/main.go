@@ -1,33 +1,121 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"compman/internal/compose"
 	"compman/internal/config"
 	"compman/internal/docker"
+	"compman/internal/notify"
+	"compman/internal/release"
+	"compman/internal/strategy"
 	"compman/internal/ui"
 	"compman/pkg/types"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	cfgFile       string
-	dryRun        bool
-	verbose       bool
-	composePaths  []string
-	tagStrategy   string
-	excludeImages []string
-	interactive   bool
-	updateAll     bool
-	version       = "1.0.0"
-	buildDate     = "unknown"
+	cfgFile             string
+	dryRun              bool
+	verbose             bool
+	composePaths        []string
+	tagStrategy         string
+	excludeImages       []string
+	interactive         bool
+	updateAll           bool
+	saveReport          string
+	appendReport        bool
+	semverPattern       string
+	cleanVolumes        bool
+	cleanNetworks       bool
+	labelFilters        []string
+	lintCompose         bool
+	webhookURL          string
+	webhookSecret       string
+	composeEnv          string
+	scanWatch           bool
+	apiTimeout          time.Duration
+	restartPolicy       string
+	outputTemplate      string
+	showVersions        int
+	scanStats           bool
+	maxParallel         int
+	serialUpdate        bool
+	composeProfiles     []string
+	compactOutput       bool
+	ignoreErrors        bool
+	sinceTag            string
+	checkReachability   bool
+	tagFormat           string
+	respectDependencies bool
+	backupBeforePull    bool
+	listTagsImage       string
+	tagFilter           string
+	includeHidden       bool
+	pullPlatform        string
+	pinClear            bool
+	onlyOutdated        bool
+	scanOutputYAML      bool
+	directPull          bool
+	imageTagOverrides   []string
+	checkConflicts      bool
+	scanGroupByImage    bool
+	logFile             string
+	skipUnhealthy       bool
+	composeVersion      string
+	validateImages      bool
+	doctorFix           bool
+	doctorYes           bool
+	regexPattern        string
+	noUp                bool
+	noRestart           bool
+	showPorts           bool
+	composeEnvVars      []string
+	versionCheck        bool
+	exportFormat        string
+	showSecrets         bool
+	servicesOnly        string
+	scanCountOnly       bool
+	gracePeriod         time.Duration
+	resultsVerbose      bool
+	statusShowEnv       bool
+	rollbackOnFailure   bool
+	rollbackStrategy    string
+	composeArgs         string
+	scanRecursive       bool
+	imageRegistryMap    []string
+	pathsFile           string
+	scanChangedOnly     bool
+	failFast            bool
+	waitForStable       time.Duration
+	searchLimit         int
+	imageBuild          bool
+	scanAgeReport       bool
+	prePullCheck        bool
+	scanTree            bool
+	validateStrict      bool
+	updateStrategy      string
+	version             = "1.0.0"
+	buildDate           = "unknown"
 )
 
 // rootCmd represents the base command
@@ -42,7 +130,8 @@ var rootCmd = &cobra.Command{
 • 自动清理未使用的镜像
 • 彩色美化输出
 • 支持 1Panel 等编排文件结构`,
-	Version: version,
+	Version:          version,
+	PersistentPreRun: applyDockerConfig,
 }
 
 // updateCmd represents the update command
@@ -60,7 +149,30 @@ var updateCmd = &cobra.Command{
 示例:
   compman update                    # 显示所有 compose 文件并交互选择
   compman update 1-3                # 更新序号 1 到 3 的文件
-  compman update 1,3,5              # 更新序号 1, 3, 5 的文件`,
+  compman update 1,3,5              # 更新序号 1, 3, 5 的文件
+  compman update --label-filter env=production  # 仅更新带有指定标签的项目
+  compman update --restart-policy unless-stopped  # 统一设置服务重启策略
+  compman update --all --ignore-errors  # 部分服务失败时仍以退出码 0 结束
+  compman update --all --since-tag 1.25.0  # 仅更新标签低于 1.25.0 的服务
+  compman update --all --services-only '^(web|api)$'  # 仅更新服务名匹配的服务
+  compman update --all --compose-args '--compatibility'  # 向 docker-compose 传递额外参数
+  compman update --all --image-registry-map docker.io=registry.cn-hangzhou.aliyuncs.com  # 迁移到其他镜像仓库
+  compman update --all --image-build  # 重新构建只有 build 配置的服务
+  compman update --all --pre-pull-check  # 拉取前检查 Docker 数据目录的剩余空间
+  compman update --all --update-strategy batch  # 先拉取所有镜像再依次重启服务
+  compman update --strategy semver --tag-format "release-{{.Version}}"  # 写回 release-1.2.3 格式的标签
+  compman update --strategy regex --regex-pattern '^build-(\d+)-prod$'  # 选择构建号最大的标签
+
+语义版本约束 (配合 --strategy semver):
+  --semver-constraint "~1.2.0"      # 仅补丁版本更新 (>= 1.2.0, < 1.3.0)
+  --semver-constraint "^1.0.0"      # 次版本和补丁版本更新 (>= 1.0.0, < 2.0.0)
+  --semver-constraint ">= 1.0.0, < 2.0.0"  # 组合约束
+
+Compose 版本 (--compose-version):
+  auto  docker compose 插件可用时使用 v2，否则使用 v1 (默认)
+  v1    使用 docker-compose 命令，pull 时逐个服务输出 "Pulling web ... done"
+  v2    使用 docker compose 插件，pull 输出 "[+] Pulling" 汇总及每一层的进度，
+        非终端环境下只输出每个服务的完成状态，进度显示可能不如 v1 详细`,
 	RunE: runUpdate,
 }
 
@@ -72,7 +184,9 @@ var cleanCmd = &cobra.Command{
 
 示例:
   compman clean
-  compman clean --dry-run`,
+  compman clean --dry-run
+  compman clean --volumes           # 同时清理未使用的数据卷
+  compman clean --network           # 同时清理未使用的网络`,
 	RunE: runClean,
 }
 
@@ -84,10 +198,31 @@ var scanCmd = &cobra.Command{
 
 示例:
   compman scan --paths /opt/1panel/docker/compose
-  compman scan --config config.yaml`,
+  compman scan --config config.yaml
+  compman scan --lint               # 同时检查风格和最佳实践问题
+  compman scan --watch              # 持续监控文件变化
+  compman scan --stats              # 显示汇总统计
+  compman scan --check-reachability --max-parallel 8  # 检查镜像是否可以从镜像仓库访问
+  compman scan --age-report         # 按落后天数列出需要优先更新的镜像
+  compman scan --tree               # 以树形显示服务依赖关系
+  compman scan --list-tags nginx --filter '^1\.25\.'  # 列出匹配的镜像标签`,
 	RunE: runScan,
 }
 
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "显示可用的镜像更新",
+	Long: `扫描 Compose 文件，按配置的策略查询每个服务镜像的目标版本，只显示差异而不执行更新。
+对于可更新的服务，同时显示从镜像仓库查询到的目标镜像下载大小（压缩后的层大小之和）。
+
+示例:
+  compman diff                      # 显示所有服务的当前镜像和目标镜像
+  compman diff --strategy semver    # 使用语义版本策略
+  compman diff --strategy semver --show-versions 5  # 同时显示最新的 5 个可用版本`,
+	RunE: runDiff,
+}
+
 // configCmd represents the config command
 var configCmd = &cobra.Command{
 	Use:   "config",
@@ -96,13 +231,146 @@ var configCmd = &cobra.Command{
 
 示例:
   compman config                    # 显示配置文件路径和内容
-  compman config --path-only        # 仅显示配置文件路径`,
+  compman config --path-only        # 仅显示配置文件路径
+  compman config --diff             # 仅显示与默认值不同的配置项
+  compman config add-path /opt/stacks     # 添加 Compose 文件搜索路径
+  compman config remove-path /opt/stacks  # 移除 Compose 文件搜索路径
+
+环境变量:
+  以下环境变量会覆盖配置文件中的对应项，优先级低于命令行参数。
+  列表使用逗号分隔，映射使用 key=value 并以逗号分隔（值为列表的映射以分号分隔）。`,
 	RunE: runConfig,
 }
 
-var showPathOnly bool
+// configAddPathCmd represents the config add-path command
+var configAddPathCmd = &cobra.Command{
+	Use:   "add-path <directory>",
+	Short: "添加 Compose 文件搜索路径",
+	Long: `将目录或 glob 模式添加到配置文件的 compose_paths 中。
+
+示例:
+  compman config add-path /opt/stacks
+  compman config add-path "/srv/*/compose"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigAddPath,
+}
+
+// configRemovePathCmd represents the config remove-path command
+var configRemovePathCmd = &cobra.Command{
+	Use:   "remove-path <directory>",
+	Short: "移除 Compose 文件搜索路径",
+	Long: `从配置文件的 compose_paths 中移除路径。参数为 glob 模式时，同时移除与其匹配的路径。
+
+示例:
+  compman config remove-path /opt/stacks
+  compman config remove-path "/opt/old-*"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigRemovePath,
+}
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "显示 Compose 项目的运行状态",
+	Long: `扫描 Compose 文件并显示每个项目中容器的运行状态和健康检查结果。
+
+示例:
+  compman status                    # 显示配置路径下所有项目的状态
+  compman status --paths /path      # 使用指定路径而非配置文件`,
+	RunE: runStatus,
+}
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "检查 Compose 项目之间的冲突",
+	Long: `扫描 Compose 文件并检查项目之间的冲突，发现问题时以非零退出码结束。
+
+检查项:
+• 宿主机端口冲突：多个服务绑定了同一个宿主机端口，后启动的项目会失败
+• 服务依赖：depends_on 格式或 condition 无效，以及服务之间的循环依赖
+• 未使用的声明：没有服务使用的网络和数据卷，默认只作为提示，--strict 时视为问题
+
+示例:
+  compman validate                    # 检查配置路径下的所有项目
+  compman validate --paths /path      # 使用指定路径而非配置文件
+  compman validate --strict           # 未使用的网络和数据卷声明也以非零退出码结束`,
+	RunE: runValidate,
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "检查运行环境并修复常见问题",
+	Long: `检查 compman 的运行环境：配置文件、Compose 命令、Docker 连接和 Compose 文件路径。
+存在未解决的问题时以非零退出码结束。
+
+使用 --fix 尝试自动修复：
+• 配置文件不存在：生成默认配置文件
+• docker-compose 不存在：通过 curl 下载到 /usr/local/bin
+• Compose 文件目录不存在：创建该目录
+每项修复执行前需要确认，同时指定 --yes 时不再确认；无法自动修复的问题会显示手动处理方法。
+
+示例:
+  compman doctor              # 只检查并报告问题
+  compman doctor --fix        # 逐项确认后自动修复
+  compman doctor --fix --yes  # 不经确认自动修复`,
+	RunE: runDoctor,
+}
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "显示版本信息",
+	Long: `显示 compman 的版本和构建日期。
+
+使用 --check 查询 GitHub 上的最新发布版本，检查结果缓存 24 小时
+(~/.local/share/compman/update_check.json)。设置环境变量 COMPMAN_NO_UPDATE_CHECK=1 可跳过检查，适用于 CI 环境。
+
+示例:
+  compman version           # 显示版本信息
+  compman version --check   # 检查是否有新版本`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
+}
+
+// pinCmd represents the pin command
+var pinCmd = &cobra.Command{
+	Use:   "pin <compose-file> [service...]",
+	Short: "固定 Compose 文件中需要更新的服务",
+	Long: `将服务保存到配置文件的 pinned_services 中。之后更新该文件时只处理固定的服务，
+运行时选择的服务优先于固定的服务。
+
+示例:
+  compman pin /opt/stacks/app/docker-compose.yml web db   # 固定 web 和 db 服务
+  compman pin /opt/stacks/app/docker-compose.yml          # 显示该文件固定的服务
+  compman pin /opt/stacks/app/docker-compose.yml --clear  # 取消固定`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPin,
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "在 Docker Hub 中搜索镜像",
+	Long: `在 Docker Hub 中搜索镜像，以表格显示名称、描述、星标数和拉取次数。
+输入结果序号后可以将镜像作为新服务添加到指定的 Compose 文件中。
+
+示例:
+  compman search nginx                # 搜索 nginx 相关镜像
+  compman search postgres --limit 10  # 只显示前 10 个结果`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearch,
+}
+
+var (
+	showPathOnly bool
+	showDiff     bool
+)
 
 func init() {
+	configCmd.Long += formatEnvVarHelp(config.EnvVarNames())
+
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
@@ -110,27 +378,140 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "干运行模式，不执行实际操作")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "详细输出")
 
+	// Validate command flags
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "尝试自动修复发现的问题")
+	doctorCmd.Flags().BoolVarP(&doctorYes, "yes", "y", false, "修复前不再逐项确认（需配合 --fix）")
+
+	// Version command flags
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "查询 GitHub 上是否有新版本")
+	validateCmd.Flags().StringSliceVarP(&composePaths, "paths", "p", []string{}, "Compose 文件搜索路径")
+	validateCmd.Flags().StringSliceVar(&composeProfiles, "profiles", []string{}, "启用的 Compose profiles，仅处理启用的服务 (覆盖配置中的 compose_profiles)")
+	validateCmd.Flags().BoolVar(&includeHidden, "include-hidden", false, "扫描以 . 开头的隐藏目录（默认跳过 .git、.venv 等）")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "严格模式，声明了但没有服务使用的网络和数据卷也视为问题")
+
+	// Pin command flags
+	pinCmd.Flags().BoolVar(&pinClear, "clear", false, "取消固定该文件的所有服务")
+
+	// Search command flags
+	searchCmd.Flags().IntVar(&searchLimit, "limit", docker.DefaultSearchLimit, "显示的搜索结果数量上限")
+
 	// Update command flags
 	updateCmd.Flags().StringSliceVarP(&composePaths, "paths", "p", []string{}, "覆盖配置文件中的 Compose 文件搜索路径")
-	updateCmd.Flags().StringVarP(&tagStrategy, "strategy", "s", "latest", "镜像标签策略 (latest, semver)")
+	updateCmd.Flags().StringVar(&pathsFile, "paths-file", "", "从文件读取 Compose 文件搜索路径 (每行一个，支持空行和 # 注释)，追加到其他路径之后")
+	updateCmd.Flags().StringVarP(&tagStrategy, "strategy", "s", "latest", "镜像标签策略 (latest, semver, regex)")
 	updateCmd.Flags().StringSliceVarP(&excludeImages, "exclude", "e", []string{}, "排除的镜像列表")
 	updateCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "强制使用交互式模式（已弃用，现在默认行为）")
 	updateCmd.Flags().BoolVarP(&updateAll, "all", "a", false, "更新所有找到的 compose 文件")
+	updateCmd.Flags().StringVar(&semverPattern, "semver-constraint", "", "语义版本约束，支持 ~、^、>=、< 及组合形式 (覆盖配置中的 semver_pattern)")
+	updateCmd.Flags().StringVar(&regexPattern, "regex-pattern", "", "regex 策略匹配标签的正则表达式，按捕获组排序 (覆盖配置中的 regex_pattern)")
+	updateCmd.Flags().StringArrayVar(&imageRegistryMap, "image-registry-map", []string{}, "本次运行将镜像仓库前缀 old 替换为 new 后再拉取和重建，如 docker.io=registry.cn-hangzhou.aliyuncs.com (可多次指定)")
+	updateCmd.Flags().StringArrayVar(&imageTagOverrides, "image-tag", []string{}, "本次运行将镜像更新到指定标签，如 nginx=1.25.4，不使用标签策略 (可多次指定)")
+	updateCmd.Flags().StringArrayVar(&labelFilters, "label-filter", []string{}, "仅更新服务标签匹配 key=value 的 Compose 项目 (可多次指定，需全部满足)")
+	updateCmd.Flags().StringVar(&webhookURL, "notify-webhook", "", "更新完成后将结果以 JSON POST 到指定地址 (覆盖配置中的 webhook_url)")
+	updateCmd.Flags().StringVar(&webhookSecret, "notify-webhook-secret", "", "Webhook 签名密钥，用于生成 X-Compman-Signature 请求头")
+	updateCmd.Flags().StringVar(&composeEnv, "compose-env-file", "", "为 docker-compose 命令加载额外环境变量的 key=value 文件 (覆盖配置中的 compose_env_file)")
+	updateCmd.Flags().DurationVar(&apiTimeout, "api-timeout", 0, "镜像仓库 API 请求超时时间，如 10s、1m (覆盖配置中的 registry_api_timeout)")
+	updateCmd.Flags().StringVar(&restartPolicy, "restart-policy", "", "更新时为所有服务统一设置重启策略 (always, unless-stopped, on-failure)，会写回 Compose 文件")
+	updateCmd.Flags().StringVar(&outputTemplate, "output-template", "", "使用 Go text/template 模板文件格式化更新结果，模板数据为更新结果列表")
+	updateCmd.Flags().IntVar(&maxParallel, "max-parallel", 0, "同时更新的 Compose 文件数量上限 (覆盖配置中的 max_parallel)")
+	updateCmd.Flags().BoolVar(&serialUpdate, "serial", false, "强制逐个文件顺序更新，忽略 max_parallel 配置 (适用于有状态服务)")
+	updateCmd.Flags().StringSliceVar(&composeProfiles, "profiles", []string{}, "启用的 Compose profiles，仅处理启用的服务 (覆盖配置中的 compose_profiles)")
+	updateCmd.Flags().BoolVar(&resultsVerbose, "results-verbose", false, "以表格列出每个服务的更新结果 (服务、原镜像、新镜像、状态、耗时、是否变更)，失败的服务排在最前")
+	updateCmd.Flags().BoolVar(&compactOutput, "compact", false, "以单行格式输出更新结果汇总 (覆盖配置中的 compact_output)")
+	updateCmd.Flags().StringVar(&pullPlatform, "pull-platform", "", "按指定平台拉取镜像，如 linux/amd64 (覆盖配置中的 platform)")
+	updateCmd.Flags().StringVar(&tagFormat, "tag-format", "", "写回 Compose 文件前转换 semver 策略返回标签的 Go 模板，如 release-{{.Version}} (覆盖配置中的 tag_format)")
+	updateCmd.Flags().BoolVar(&respectDependencies, "respect-dependencies", false, "按 depends_on 依赖层级依次拉取镜像，被依赖的服务先拉取 (覆盖配置中的 respect_dependencies)")
+	updateCmd.Flags().BoolVar(&onlyOutdated, "only-outdated", true, "跳过已是目标版本的服务，不拉取镜像也不重启，仅 semver 策略有效 (覆盖配置中的 skip_up_to_date)")
+	updateCmd.Flags().BoolVar(&directPull, "direct-pull", false, "通过 Docker API 直接拉取镜像并显示每一层的进度，代替 docker-compose pull (覆盖配置中的 use_direct_pull)")
+	updateCmd.Flags().BoolVar(&backupBeforePull, "backup-before-pull", false, "拉取镜像前备份 Compose 文件，即使本次更新不修改文件 (覆盖配置中的 backup_before_pull)")
+	updateCmd.Flags().BoolVar(&includeHidden, "include-hidden", false, "扫描以 . 开头的隐藏目录（默认跳过 .git、.venv 等）")
+	updateCmd.Flags().BoolVar(&rollbackOnFailure, "rollback-on-failure", false, "更新后服务未运行或健康检查失败时自动回滚 (回滚方式见 --rollback-strategy)")
+	updateCmd.Flags().StringVar(&rollbackStrategy, "rollback-strategy", "", "回滚方式: compose-file (只恢复 Compose 文件)、containers (使用原镜像重建容器) 或 full (恢复文件并重建容器) (覆盖配置中的 rollback_strategy)")
+	updateCmd.Flags().DurationVar(&gracePeriod, "timeout-grace-period", 0, "up -d 重建容器时等待容器停止的宽限期，如 60s，超时后强制结束容器 (覆盖配置中的 grace_period)")
+	updateCmd.Flags().StringVar(&composeArgs, "compose-args", "", "附加到 docker-compose pull 和 up -d 命令的额外参数，按 shell 规则拆分，如 '--env-file custom.env'")
+	updateCmd.Flags().StringVar(&servicesOnly, "services-only", "", "仅更新服务名匹配指定正则表达式的服务，如 '^(web|api)$'，其余服务记为跳过")
+	updateCmd.Flags().StringVar(&sinceTag, "since-tag", "", "仅更新镜像标签低于指定语义版本的服务，如 1.25.0")
+	updateCmd.Flags().DurationVar(&waitForStable, "wait-for-stable", 0, "每个 Compose 文件更新成功后等待指定时间再处理下一个文件，如 30s，便于数据库等服务先稳定下来")
+	updateCmd.Flags().BoolVar(&failFast, "fail-fast", false, "某个 Compose 文件更新失败后立即中止，不再处理剩余的文件")
+	updateCmd.Flags().StringVar(&updateStrategy, "update-strategy", compose.UpdateStrategyFileByFile, "更新方式: file-by-file (逐个文件完成拉取和重启) 或 batch (先拉取所有文件的镜像，再依次重启服务，缩短停机时间)")
+	updateCmd.Flags().BoolVar(&prePullCheck, "pre-pull-check", false, "拉取镜像前估算需要下载的大小，Docker 数据目录剩余空间不足预计大小的 110% 时中止更新")
+	updateCmd.Flags().BoolVar(&ignoreErrors, "ignore-errors", false, "部分服务更新失败时仍以退出码 0 结束，失败信息以警告形式输出")
+	updateCmd.Flags().StringVar(&saveReport, "save-report", "", "将完整的更新报告以 JSON 格式保存到指定文件")
+	updateCmd.Flags().BoolVar(&appendReport, "append-report", false, "追加到报告文件而不是覆盖（需配合 --save-report）")
+	updateCmd.Flags().BoolVar(&skipUnhealthy, "skip-unhealthy", false, "跳过存在健康检查失败或已退出服务的 Compose 文件，避免更新掩盖原有问题")
+	updateCmd.Flags().StringVar(&composeVersion, "compose-version", "", "使用的 Compose 命令版本: auto (自动检测)、v1 (docker-compose) 或 v2 (docker compose) (覆盖配置中的 compose_version)")
+	updateCmd.Flags().StringVar(&logFile, "log-file", "", "将所有输出以纯文本追加写入指定的日志文件 (覆盖配置中的 log_file)")
+	updateCmd.Flags().StringArrayVar(&composeEnvVars, "env", []string{}, "本次运行传递给 docker-compose 命令的环境变量，如 FEATURE_FLAG=on，用于 Compose 文件中的变量替换 (可多次指定，优先于 compose_env_file)")
+	updateCmd.Flags().BoolVar(&imageBuild, "image-build", false, "对只有 build 配置 (没有 image) 的服务执行 docker-compose build --no-cache 后再重建容器，默认跳过这些服务")
+	updateCmd.Flags().BoolVar(&noUp, "no-up", false, "只拉取镜像和更新 Compose 文件，不执行 up -d 重建容器")
+	updateCmd.Flags().BoolVar(&noRestart, "no-restart", false, "同 --no-up")
+	updateCmd.Flags().MarkDeprecated("no-restart", "请使用 --no-up")
 
 	// Clean command flags
 	cleanCmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "干运行模式")
+	cleanCmd.Flags().BoolVar(&cleanVolumes, "volumes", false, "同时清理未使用的数据卷")
+	cleanCmd.Flags().BoolVar(&cleanNetworks, "network", false, "同时清理未使用的网络")
 
 	// Scan command flags
 	scanCmd.Flags().StringSliceVarP(&composePaths, "paths", "p", []string{}, "Compose 文件搜索路径")
+	scanCmd.Flags().StringVar(&pathsFile, "paths-file", "", "从文件读取 Compose 文件搜索路径 (每行一个，支持空行和 # 注释)，追加到其他路径之后")
+	scanCmd.Flags().StringSliceVar(&composeProfiles, "profiles", []string{}, "启用的 Compose profiles，仅处理启用的服务 (覆盖配置中的 compose_profiles)")
+	scanCmd.Flags().BoolVar(&showPorts, "show-ports", false, "在文件列表中显示每个项目绑定的宿主机端口（终端宽度不小于 120 时显示）")
+	scanCmd.Flags().BoolVar(&scanGroupByImage, "group-by-image", false, "按镜像分组显示使用每个镜像的项目（不区分标签），便于评估基础镜像更新的影响范围")
+	scanCmd.Flags().BoolVar(&checkConflicts, "check-conflicts", false, "检查不同服务之间的宿主机端口冲突")
+	scanCmd.Flags().BoolVar(&validateImages, "validate-images", false, "检查镜像名称格式，发现无效名称时以非零退出码结束")
+	scanCmd.Flags().BoolVar(&lintCompose, "lint", false, "检查 Compose 文件的风格和最佳实践问题")
+	scanCmd.Flags().BoolVarP(&scanWatch, "watch", "w", false, "持续监控 Compose 文件的新增、修改和删除，并通过 Docker 事件提示退出的容器")
+	scanCmd.Flags().BoolVar(&scanCountOnly, "count-only", false, "只输出 Compose 文件数量，按文件名统计而不解析 YAML，适用于 CI 脚本")
+	scanCmd.Flags().BoolVar(&scanOutputYAML, "output-yaml", false, "以 YAML 格式输出所有项目、服务和镜像的清单，便于 Ansible 等工具使用")
+	scanCmd.Flags().BoolVar(&scanChangedOnly, "changed-only", false, "只列出内容在上次成功更新后发生变化的 Compose 文件 (按文件 SHA-256 校验和判断)")
+	scanCmd.Flags().BoolVar(&scanStats, "stats", false, "显示所有 Compose 文件的汇总统计")
+	scanCmd.Flags().BoolVar(&scanTree, "tree", false, "以树形显示每个 Compose 文件中服务的 depends_on 依赖关系，以及服务使用的镜像和数据卷")
+	scanCmd.Flags().BoolVar(&scanAgeReport, "age-report", false, "查询镜像仓库，按落后天数列出当前镜像与最新可用镜像的构建时间差距（落后 90 天以上黄色，180 天以上红色显示）")
+	scanCmd.Flags().BoolVar(&scanRecursive, "recursive", true, "扫描子目录，--recursive=false 时只检查每个扫描路径下的文件")
+	scanCmd.Flags().BoolVar(&includeHidden, "include-hidden", false, "扫描以 . 开头的隐藏目录（默认跳过 .git、.venv 等）")
+	scanCmd.Flags().StringVar(&listTagsImage, "list-tags", "", "列出指定镜像在镜像仓库中的标签，不扫描 Compose 文件")
+	scanCmd.Flags().StringVar(&tagFilter, "filter", "", "配合 --list-tags 使用，仅显示匹配正则表达式的标签")
+	scanCmd.Flags().BoolVar(&checkReachability, "check-reachability", false, "检查每个镜像是否可以从镜像仓库访问（需要网络请求）")
+	scanCmd.Flags().IntVar(&maxParallel, "max-parallel", 0, "检查镜像可访问性和生成年龄报告时的并发请求数量上限 (覆盖配置中的 max_parallel)")
+
+	// Diff command flags
+	diffCmd.Flags().StringSliceVarP(&composePaths, "paths", "p", []string{}, "覆盖配置文件中的 Compose 文件搜索路径")
+	diffCmd.Flags().StringVarP(&tagStrategy, "strategy", "s", "latest", "镜像标签策略 (latest, semver, regex)")
+	diffCmd.Flags().StringSliceVarP(&excludeImages, "exclude", "e", []string{}, "排除的镜像列表")
+	diffCmd.Flags().StringVar(&semverPattern, "semver-constraint", "", "语义版本约束 (覆盖配置中的 semver_pattern)")
+	diffCmd.Flags().StringVar(&regexPattern, "regex-pattern", "", "regex 策略匹配标签的正则表达式 (覆盖配置中的 regex_pattern)")
+	diffCmd.Flags().DurationVar(&apiTimeout, "api-timeout", 0, "镜像仓库 API 请求超时时间，如 10s、1m (覆盖配置中的 registry_api_timeout)")
+	diffCmd.Flags().StringSliceVar(&composeProfiles, "profiles", []string{}, "启用的 Compose profiles，仅处理启用的服务 (覆盖配置中的 compose_profiles)")
+	diffCmd.Flags().IntVar(&showVersions, "show-versions", 0, "显示最新的 N 个可用版本作为升级路径 (仅 semver 策略)")
+	diffCmd.Flags().StringVar(&pullPlatform, "pull-platform", "", "按指定平台计算下载大小，如 linux/amd64 (覆盖配置中的 platform)")
+	diffCmd.Flags().StringVar(&tagFormat, "tag-format", "", "转换 semver 策略返回标签的 Go 模板，如 release-{{.Version}} (覆盖配置中的 tag_format)")
+
+	// Status command flags
+	statusCmd.Flags().BoolVar(&statusShowEnv, "env", false, "显示容器运行时的环境变量，名称包含 PASSWORD、SECRET、TOKEN、KEY 的变量值会被隐藏")
+	statusCmd.Flags().StringSliceVar(&composeProfiles, "profiles", []string{}, "启用的 Compose profiles，仅处理启用的服务 (覆盖配置中的 compose_profiles)")
+	statusCmd.Flags().StringSliceVarP(&composePaths, "paths", "p", []string{}, "Compose 文件搜索路径")
 
 	// Config command flags
 	configCmd.Flags().BoolVarP(&showPathOnly, "path-only", "p", false, "仅显示配置文件路径")
+	configCmd.Flags().BoolVar(&showDiff, "diff", false, "仅显示与默认配置不同的配置项")
+	configCmd.Flags().StringVar(&exportFormat, "export", "", "以指定格式输出当前配置: "+strings.Join(config.ExportFormats, ", "))
+	configCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "导出时显示敏感配置项（如 webhook_url）的原始值")
 
 	// Add subcommands
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(scanCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(versionCmd)
+	configCmd.AddCommand(configAddPathCmd)
+	configCmd.AddCommand(configRemovePathCmd)
 }
 
 func initConfig() {
@@ -143,7 +524,24 @@ func initConfig() {
 	}
 }
 
+// applyDockerConfig 让所有 Docker 客户端使用配置中的连接设置（如 SSH 远程主机）
+// config 命令不连接 Docker，跳过以免提前创建配置文件；加载失败时由各命令报告错误
+func applyDockerConfig(cmd *cobra.Command, args []string) {
+	// doctor 需要在加载配置前检查配置文件是否存在，LoadConfig 会自动创建配置文件
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == configCmd || c == doctorCmd || c == versionCmd {
+			return
+		}
+	}
+
+	if cfg, err := config.LoadConfig(); err == nil {
+		docker.SetDefaultConfig(&cfg.DockerConfig)
+	}
+}
+
 func runUpdate(cmd *cobra.Command, args []string) error {
+	startTime := time.Now()
+
 	ui.PrintEmptyLine()
 	ui.PrintInfo("🚀 开始更新 Docker Compose 服务镜像...")
 	ui.PrintEmptyLine()
@@ -158,25 +556,170 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	if len(composePaths) > 0 {
 		cfg.ComposePaths = composePaths
 	}
+	if pathsFile != "" {
+		if err := config.ApplyPathsFile(cfg, pathsFile); err != nil {
+			return err
+		}
+	}
 	if tagStrategy != "latest" {
 		cfg.ImageTagStrategy = tagStrategy
 	}
 	if len(excludeImages) > 0 {
 		cfg.ExcludeImages = excludeImages
 	}
+	if semverPattern != "" {
+		cfg.SemverPattern = semverPattern
+	}
+	if regexPattern != "" {
+		cfg.RegexPattern = regexPattern
+	}
+	if webhookURL != "" {
+		cfg.WebhookURL = webhookURL
+	}
+	if composeEnv != "" {
+		cfg.ComposeEnvFile = composeEnv
+	}
+	if apiTimeout > 0 {
+		cfg.RegistryAPITimeout = apiTimeout
+	}
+	if restartPolicy != "" {
+		cfg.RestartPolicy = restartPolicy
+	}
+	if maxParallel > 0 {
+		cfg.MaxParallel = maxParallel
+	}
+	if serialUpdate {
+		cfg.MaxParallel = 1
+	}
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+	if compactOutput {
+		cfg.CompactOutput = true
+	}
+	if sinceTag != "" {
+		cfg.SinceTag = sinceTag
+	}
+	if servicesOnly != "" {
+		cfg.ServicesOnly = servicesOnly
+	}
+	if gracePeriod > 0 {
+		cfg.GracePeriod = gracePeriod
+	}
+	if rollbackStrategy != "" {
+		cfg.RollbackStrategy = rollbackStrategy
+	}
+	cfg.RollbackOnFailure = rollbackOnFailure
+	cfg.FailFast = failFast
+	cfg.UpdateStrategy = updateStrategy
+	cfg.WaitForStable = waitForStable
+	if tagFormat != "" {
+		cfg.TagFormat = tagFormat
+	}
+	if pullPlatform != "" {
+		cfg.Platform = pullPlatform
+	}
+	if respectDependencies {
+		cfg.RespectDependencies = true
+	}
+	if backupBeforePull {
+		cfg.BackupBeforePull = true
+	}
+	if directPull {
+		cfg.UseDirectPull = true
+	}
+	if len(imageTagOverrides) > 0 {
+		overrides, err := parseImageTagOverrides(imageTagOverrides)
+		if err != nil {
+			return err
+		}
+		cfg.ImageTagOverrides = overrides
+	}
+	if composeArgs != "" {
+		args, err := splitShellArgs(composeArgs)
+		if err != nil {
+			return fmt.Errorf("无效的 --compose-args: %v", err)
+		}
+		cfg.ComposeArgs = args
+	}
+	if len(imageRegistryMap) > 0 {
+		registryMap, err := parseImageRegistryMap(imageRegistryMap)
+		if err != nil {
+			return err
+		}
+		cfg.ImageRegistryMap = registryMap
+	}
+	if cmd.Flags().Changed("only-outdated") {
+		cfg.SkipUpToDate = onlyOutdated
+	}
+	if logFile != "" {
+		cfg.LogFile = logFile
+	}
+	if composeVersion != "" {
+		cfg.ComposeVersion = composeVersion
+	}
+	if noRestart {
+		ui.PrintWarning("--no-restart 已弃用，请使用 --no-up")
+	}
+	cfg.NoUp = noUp || noRestart
+	cfg.ImageBuild = imageBuild
 	cfg.DryRun = dryRun
 
+	if err := config.Validate(cfg); err != nil {
+		return fmt.Errorf("参数验证失败: %v", err)
+	}
+
+	if cfg.LogFile != "" {
+		closeLog, err := openUpdateLog(cfg.LogFile, startTime)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+	}
+
+	// 提前检查标签格式，避免修改文件时才发现模板错误
+	if _, err := strategy.FormatTag(cfg.TagFormat, "1.0.0"); err != nil {
+		return err
+	}
+
+	// 提前检查输出模板，避免更新完成后才发现模板错误
+	if outputTemplate != "" {
+		if _, err := ui.LoadUpdateResultsTemplate(outputTemplate); err != nil {
+			return err
+		}
+	}
+
 	if len(cfg.ComposePaths) == 0 {
 		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
 	}
 
 	// 扫描 Compose 文件
 	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	scanner.SetSkipHidden(!includeHidden)
 	allComposeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
 	if err != nil {
 		return fmt.Errorf("扫描 Compose 文件失败: %v", err)
 	}
 
+	// 按标签过滤
+	if len(labelFilters) > 0 {
+		labels, err := parseLabelFilters(labelFilters)
+		if err != nil {
+			return err
+		}
+		allComposeFiles = compose.FilterByLabels(allComposeFiles, labels)
+		if len(allComposeFiles) == 0 {
+			ui.PrintEmptyLine()
+			ui.PrintWarning("没有 Compose 项目匹配标签过滤条件")
+			return nil
+		}
+	}
+
 	if len(allComposeFiles) == 0 {
 		ui.PrintEmptyLine()
 		ui.PrintWarning("未找到任何 Docker Compose 文件")
@@ -214,36 +757,143 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// 跳过状态异常的项目，其结果与更新结果一起输出
+	var unhealthyResults []*types.UpdateResult
+	if skipUnhealthy {
+		composeFiles, unhealthyResults = skipUnhealthyProjects(composeFiles)
+		if len(composeFiles) == 0 {
+			ui.PrintEmptyLine()
+			ui.PrintWarning("选择的项目都存在状态异常的服务，已全部跳过")
+			return nil
+		}
+	}
+
 	ui.PrintEmptyLine()
 	ui.PrintSuccess(fmt.Sprintf("✅ 将处理 %d 个 Compose 文件", len(composeFiles)))
 
+	// 拉取镜像前检查 Docker Hub 拉取限制
+	if !cfg.DryRun {
+		warnLowRateLimit(cfg)
+	}
+
+	// 估算需要下载的大小并检查 Docker 数据目录的剩余空间，干运行时只显示估算结果
+	if prePullCheck {
+		if err := checkDiskSpace(cfg, composeFiles); err != nil {
+			return err
+		}
+	}
+
 	// 显示开始更新的消息
 	ui.PrintEmptyLine()
 	ui.PrintInfo("🚀 开始更新镜像...")
 	ui.PrintEmptyLine()
 
 	// 创建更新器
-	updater := compose.NewUpdater(cfg)
+	updater, err := compose.NewUpdater(cfg)
+	if err != nil {
+		return fmt.Errorf("创建更新器失败: %v", err)
+	}
+	updater.SetVerbose(verbose)
+	if err := updater.LoadComposeEnvFile(cfg.ComposeEnvFile); err != nil {
+		return fmt.Errorf("加载 Compose 环境变量文件失败: %v", err)
+	}
+	if err := updater.AddComposeEnv(composeEnvVars); err != nil {
+		return err
+	}
 
-	// 创建多进度条
-	fileNames := make([]string, len(composeFiles))
-	for i, cf := range composeFiles {
-		fileNames[i] = filepath.Base(cf.FilePath)
+	// 更新镜像，batch 策略先拉取所有文件的镜像再依次重启，不显示逐个文件的进度条
+	var results []*types.UpdateResult
+	if cfg.UpdateStrategy == compose.UpdateStrategyBatch {
+		ui.PrintInfo(fmt.Sprintf("⬇️ 正在拉取 %d 个 Compose 文件的镜像，全部完成后依次重启服务...", len(composeFiles)))
+		results, err = updater.BatchUpdateImages(composeFiles)
+		if err != nil {
+			return fmt.Errorf("更新镜像失败: %v", err)
+		}
+	} else {
+		// 创建多进度条
+		fileNames := make([]string, len(composeFiles))
+		for i, cf := range composeFiles {
+			fileNames[i] = filepath.Base(cf.FilePath)
+		}
+		multiProgressBar := ui.NewMultiProgressBar(fileNames)
+
+		results, err = updater.UpdateImagesWithMultiProgress(composeFiles, multiProgressBar)
+		if err != nil {
+			return fmt.Errorf("更新镜像失败: %v", err)
+		}
+
+		// 完成所有进度条
+		multiProgressBar.Finish()
 	}
-	multiProgressBar := ui.NewMultiProgressBar(fileNames)
+	results = append(unhealthyResults, results...)
+	ui.PrintEmptyLine()
 
-	// 更新镜像
-	results, err := updater.UpdateImagesWithMultiProgress(composeFiles, multiProgressBar)
-	if err != nil {
-		return fmt.Errorf("更新镜像失败: %v", err)
+	// --fail-fast 中止时说明剩余文件未处理的原因
+	abortedFiles := 0
+	for _, result := range results {
+		if result.SkipReason == compose.FailFastSkipReason {
+			abortedFiles++
+		}
+	}
+	if abortedFiles > 0 {
+		ui.PrintError(fmt.Sprintf("因 --fail-fast 中止更新，剩余 %d 个 Compose 文件未处理", abortedFiles))
+		ui.PrintEmptyLine()
 	}
 
-	// 完成所有进度条
-	multiProgressBar.Finish()
-	ui.PrintEmptyLine()
+	// 显示结果，单行汇总模式下在清理镜像后统一输出
+	if !cfg.CompactOutput || outputTemplate != "" {
+		if err := displayUpdateResults(results, outputTemplate); err != nil {
+			return err
+		}
+		if outputTemplate == "" {
+			displayRegistryRewrites(updater.RewrittenImages())
+			displayUpdateErrors(results, ignoreErrors)
+		}
+	}
 
-	// 显示结果
-	displayUpdateResults(results)
+	// 检查更新后的服务运行状态，未重建容器时只提示如何使新镜像生效
+	if cfg.NoUp && !cfg.DryRun {
+		composeCmd := "docker-compose"
+		if updater.ComposeVersion() == "v2" {
+			composeCmd = "docker compose"
+		}
+		ui.PrintInfo(fmt.Sprintf("💡 已跳过重建容器，运行 %s up -d 使新镜像生效", composeCmd))
+	} else if !cfg.DryRun {
+		verifyProjectHealth(composeFiles)
+	}
+
+	// 保存更新报告
+	if saveReport != "" {
+		if err := writeUpdateReport(saveReport, appendReport, startTime, cfg.ComposePaths, results); err != nil {
+			ui.PrintWarning(fmt.Sprintf("保存更新报告失败: %v", err))
+		} else {
+			ui.PrintSuccess(fmt.Sprintf("更新报告已保存到: %s", saveReport))
+		}
+	}
+
+	// 发送 Webhook 通知
+	if cfg.WebhookURL != "" {
+		notifier := notify.NewWebhookNotifier(cfg.WebhookURL, webhookSecret)
+		if err := notifier.Send(notify.NewPayload(startTime, results)); err != nil {
+			ui.PrintWarning(fmt.Sprintf("发送 Webhook 通知失败: %v", err))
+		} else {
+			ui.PrintSuccess("Webhook 通知已发送")
+		}
+	}
+
+	// 单行汇总模式下静默清理镜像，回收空间显示在汇总中
+	if cfg.CompactOutput && outputTemplate == "" {
+		reclaimed := int64(-1)
+		if !dryRun {
+			if space, _, err := docker.NewClient().PruneUnusedImages(); err != nil {
+				ui.PrintWarning(fmt.Sprintf("清理镜像时出现警告: %v", err))
+			} else {
+				reclaimed = int64(space)
+			}
+		}
+		ui.PrintUpdateSummaryWithReclaimed(results, reclaimed)
+		return updateFailureError(cmd, results)
+	}
 
 	// 清理未使用的镜像
 	if !dryRun {
@@ -259,7 +909,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		ui.PrintEmptyLine()
 	}
 
-	return nil
+	return updateFailureError(cmd, results)
 }
 
 func runClean(cmd *cobra.Command, args []string) error {
@@ -267,43 +917,148 @@ func runClean(cmd *cobra.Command, args []string) error {
 	ui.PrintInfo("🧹 开始清理未使用的 Docker 镜像...")
 	ui.PrintEmptyLine()
 
+	// 加载配置
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	if cleanVolumes {
+		cfg.CleanVolumes = true
+	}
+	if cleanNetworks {
+		cfg.CleanNetworks = true
+	}
+
 	dockerClient := docker.NewClient()
 
 	if dryRun {
-		ui.PrintInfo("🔍 [干运行] 正在检查未使用的镜像...")
+		ui.PrintInfo("🔍 [干运行] 正在检查未使用的镜像、数据卷和网络...")
 		images, err := dockerClient.ListUnusedImages()
 		if err != nil {
 			return fmt.Errorf("获取未使用镜像失败: %v", err)
 		}
 
-		if len(images) == 0 {
+		volumes, err := dockerClient.ListUnusedVolumes()
+		if err != nil {
+			return fmt.Errorf("获取未使用数据卷失败: %v", err)
+		}
+
+		networks, err := dockerClient.ListUnusedNetworks()
+		if err != nil {
+			return fmt.Errorf("获取未使用网络失败: %v", err)
+		}
+
+		if len(images) == 0 && len(volumes) == 0 && len(networks) == 0 {
 			ui.PrintEmptyLine()
-			ui.PrintSuccess("✅ 没有发现未使用的镜像")
+			ui.PrintSuccess("✅ 没有发现未使用的镜像、数据卷或网络")
 			ui.PrintEmptyLine()
 			return nil
 		}
 
-		ui.PrintEmptyLine()
-		ui.PrintInfo(fmt.Sprintf("发现 %d 个未使用的镜像:", len(images)))
-		for _, img := range images {
-			ui.PrintItem(fmt.Sprintf("• %s (%s)", img.Repository+":"+img.Tag, formatSize(img.Size)))
+		ui.PrintSection(fmt.Sprintf("Unused Images (%d)", len(images)))
+		if len(images) > 0 {
+			var rows [][]string
+			for _, img := range images {
+				rows = append(rows, []string{img.Repository + ":" + img.Tag, shortID(img.ImageID), ui.FormatSize(img.Size)})
+			}
+			ui.PrintTable([]string{"镜像", "ID", "大小"}, rows)
+		}
+
+		ui.PrintSection(fmt.Sprintf("Unused Volumes (%d)", len(volumes)))
+		if len(volumes) > 0 {
+			var rows [][]string
+			for _, vol := range volumes {
+				size := "未知"
+				if vol.Size >= 0 {
+					size = ui.FormatSize(vol.Size)
+				}
+				rows = append(rows, []string{vol.Name, vol.Driver, size})
+			}
+			ui.PrintTable([]string{"数据卷", "驱动", "大小"}, rows)
+			if !cfg.CleanVolumes {
+				ui.PrintInfo("💡 使用 --volumes 参数或设置 clean_volumes: true 以清理数据卷")
+			}
+		}
+
+		ui.PrintSection(fmt.Sprintf("Unused Networks (%d)", len(networks)))
+		if len(networks) > 0 {
+			var rows [][]string
+			for _, network := range networks {
+				rows = append(rows, []string{network.Name, network.Driver})
+			}
+			ui.PrintTable([]string{"网络", "驱动"}, rows)
+			if !cfg.CleanNetworks {
+				ui.PrintInfo("💡 使用 --network 参数或设置 clean_networks: true 以清理网络")
+			}
 		}
 		ui.PrintEmptyLine()
 		return nil
 	}
 
-	err := dockerClient.CleanupUnusedImages()
+	err = dockerClient.CleanupUnusedImages()
 	if err != nil {
 		return fmt.Errorf("清理镜像失败: %v", err)
 	}
 
+	if cfg.CleanVolumes {
+		if err := dockerClient.CleanupUnusedVolumes(); err != nil {
+			return fmt.Errorf("清理数据卷失败: %v", err)
+		}
+	}
+
+	if cfg.CleanNetworks {
+		if err := dockerClient.CleanupUnusedNetworks(); err != nil {
+			return fmt.Errorf("清理网络失败: %v", err)
+		}
+	}
+
 	ui.PrintEmptyLine()
 	ui.PrintSuccess("✅ 镜像清理完成")
 	ui.PrintEmptyLine()
 	return nil
 }
 
+// formatEnvVarHelp 将环境变量名称格式化为两列，用于 config 命令的帮助信息
+func formatEnvVarHelp(names []string) string {
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n")
+	for i := 0; i < len(names); i += 2 {
+		if i+1 < len(names) {
+			builder.WriteString(fmt.Sprintf("\n  %-*s  %s", width, names[i], names[i+1]))
+		} else {
+			builder.WriteString(fmt.Sprintf("\n  %s", names[i]))
+		}
+	}
+	return builder.String()
+}
+
+// shortID returns the short form of a Docker object ID
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
 func runScan(cmd *cobra.Command, args []string) error {
+	if listTagsImage != "" {
+		return runListTags(listTagsImage, tagFilter)
+	}
+	if scanOutputYAML {
+		return runScanInventory()
+	}
+	if scanCountOnly {
+		return runScanCount()
+	}
+
 	ui.PrintEmptyLine()
 	ui.PrintInfo("🔍 扫描 Docker Compose 文件...")
 	ui.PrintEmptyLine()
@@ -315,8 +1070,8 @@ func runScan(cmd *cobra.Command, args []string) error {
 	}
 
 	// 如果命令行指定了路径，则覆盖配置文件中的路径
-	if len(composePaths) > 0 {
-		cfg.ComposePaths = composePaths
+	if err := applyScanOverrides(cfg); err != nil {
+		return err
 	}
 
 	if len(cfg.ComposePaths) == 0 {
@@ -324,137 +1079,2476 @@ func runScan(cmd *cobra.Command, args []string) error {
 	}
 
 	// 扫描文件
-	scanner := compose.NewScanner()
-	composeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
+	scanner := newScanScanner(cfg)
+	scanFiles := scanner.ScanComposeFiles
+	if scanChangedOnly {
+		scanFiles = scanner.ScanChangedFiles
+	}
+	composeFiles, err := scanFiles(cfg.ComposePaths)
 	if err != nil {
 		return fmt.Errorf("扫描失败: %v", err)
 	}
 
 	// 显示结果
-	if len(composeFiles) == 0 {
+	if len(composeFiles) == 0 && scanChangedOnly {
+		ui.PrintEmptyLine()
+		ui.PrintSuccess("上次成功更新后没有发生变化的 Compose 文件")
+		ui.PrintEmptyLine()
+		if !scanWatch {
+			return nil
+		}
+	} else if len(composeFiles) == 0 {
 		ui.PrintEmptyLine()
 		ui.PrintWarning("未找到任何 Docker Compose 文件")
 		ui.PrintEmptyLine()
-		return nil
-	}
+		if !scanWatch {
+			return nil
+		}
+	} else {
+		displayComposeList(composeFiles)
 
-	displayComposeList(composeFiles)
-	displayDetailedScanResults(composeFiles)
-	return nil
-}
+		var reachability map[string]error
+		if checkReachability {
+			if maxParallel > 0 {
+				cfg.MaxParallel = maxParallel
+			}
+			ui.PrintInfo("🌐 正在检查镜像可访问性...")
+			reachability = checkImageReachability(composeFiles, cfg.MaxParallel, cfg.RegistryAPITimeout)
+		}
 
-func runConfig(cmd *cobra.Command, args []string) error {
-	// 获取默认配置文件路径
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("获取用户目录失败: %v", err)
-	}
-	defaultPath := filepath.Join(home, ".config", "compman", "config.yml")
+		if scanGroupByImage {
+			displayImageGroups(scanner.GroupByImage(composeFiles), reachability)
+		} else {
+			displayDetailedScanResults(composeFiles, reachability)
+		}
+		if checkReachability {
+			displayReachabilitySummary(reachability)
+		}
 
-	if showPathOnly {
-		fmt.Println(defaultPath)
-		return nil
-	}
+		if scanTree {
+			displayServiceTrees(composeFiles)
+		}
 
-	ui.PrintEmptyLine()
-	ui.PrintInfo("📁 配置文件信息")
-	ui.PrintItem(fmt.Sprintf("默认配置文件路径: %s", defaultPath))
+		if lintCompose {
+			displayLintResults(composeFiles)
+		}
 
-	if cfgFile != "" {
-		ui.PrintItem(fmt.Sprintf("用户指定配置文件: %s", cfgFile))
-	}
+		if checkConflicts {
+			displayPortConflicts(compose.ValidatePortConflicts(composeFiles))
+		}
 
-	// 检查默认配置文件是否存在
-	if _, err := os.Stat(defaultPath); err == nil {
-		ui.PrintSuccess("✅ 默认配置文件存在")
-	} else {
-		ui.PrintWarning("⚠️  默认配置文件不存在，将在首次运行时创建")
-	}
+		var invalidImages []compose.InvalidImage
+		if validateImages {
+			invalidImages = compose.ValidateImages(composeFiles)
+			displayInvalidImages(invalidImages)
+		}
 
-	// 加载并显示配置内容
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return fmt.Errorf("加载配置失败: %v", err)
-	}
+		if scanStats {
+			displayScanStats(scanner.ComputeStats(composeFiles))
+		}
+
+		if scanAgeReport {
+			if maxParallel > 0 {
+				cfg.MaxParallel = maxParallel
+			}
+			if err := displayAgeReport(cfg, composeFiles); err != nil {
+				return err
+			}
+		}
+
+		if len(invalidImages) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("发现 %d 个无效的镜像名称", len(invalidImages))
+		}
+	}
+
+	if scanWatch {
+		return watchComposeFiles(scanner, cfg.ComposePaths)
+	}
+	return nil
+}
+
+// applyScanOverrides applies the --paths, --paths-file and --profile flags of the scan command to cfg
+func applyScanOverrides(cfg *types.Config) error {
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+	if pathsFile != "" {
+		if err := config.ApplyPathsFile(cfg, pathsFile); err != nil {
+			return err
+		}
+	}
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+	return nil
+}
+
+// newScanScanner creates the scanner used by the scan command from cfg and the scan flags
+func newScanScanner(cfg *types.Config) *compose.Scanner {
+	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	scanner.SetSkipHidden(!includeHidden)
+	scanner.SetRecursive(scanRecursive)
+	return scanner
+}
+
+// watchComposeFiles monitors the compose paths and prints changed files until interrupted
+// When the configuration file changes, the scanner and paths are rebuilt from the reloaded configuration
+func watchComposeFiles(scanner *compose.Scanner, paths []string) error {
+	watcher, composeFiles, err := compose.NewWatcher(scanner, paths)
+	if err != nil {
+		return fmt.Errorf("启动文件监控失败: %v", err)
+	}
+	defer watcher.Close()
+
+	// 受管理的项目 (文件路径 -> 项目名称)，用于判断容器事件是否属于扫描到的项目
+	var projectsMutex sync.Mutex
+	projects := make(map[string]string, len(composeFiles))
+	for _, cf := range composeFiles {
+		projects[cf.FilePath] = docker.NormalizeProjectName(compose.RuntimeProjectName(cf))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	stop := ctx.Done()
+
+	// 配置文件变化时热重载配置，并按新配置重新扫描
+	if configChanges, err := config.WatchConfigFile(ctx); err != nil {
+		ui.PrintWarning(fmt.Sprintf("无法监控配置文件，配置修改需重启后生效: %v", err))
+	} else {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				case <-configChanges:
+					if err := config.ReloadConfig(); err != nil {
+						ui.PrintTimestamp(color.RedString("⚠️  重新加载配置失败: %v", err))
+						continue
+					}
+					cfg := *config.GetConfig()
+					if err := applyScanOverrides(&cfg); err != nil {
+						ui.PrintTimestamp(color.RedString("⚠️  重新加载配置失败: %v", err))
+						continue
+					}
+					watcher.Reconfigure(newScanScanner(&cfg), cfg.ComposePaths)
+					ui.PrintTimestamp(color.CyanString("🔄 配置文件已变更，已重新加载配置"))
+				}
+			}
+		}()
+	}
+
+	// 同时订阅 Docker 事件，及时发现受管理项目中退出的容器
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+	if events, err := dockerClient.GetEventStream(); err != nil {
+		ui.PrintWarning(fmt.Sprintf("无法订阅 Docker 事件，仅监控文件变化: %v", err))
+	} else {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					projectsMutex.Lock()
+					printDockerEvent(event, projects)
+					projectsMutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	ui.PrintInfo("👀 正在监控 Compose 文件变化，按 Ctrl+C 退出...")
+	ui.PrintEmptyLine()
+
+	err = watcher.Watch(stop, func(changes []compose.FileChange) {
+		projectsMutex.Lock()
+		defer projectsMutex.Unlock()
+		for _, change := range changes {
+			if change.ComposeFile != nil {
+				projects[change.FilePath] = docker.NormalizeProjectName(compose.RuntimeProjectName(change.ComposeFile))
+			} else {
+				delete(projects, change.FilePath)
+			}
+
+			switch change.Type {
+			case compose.ChangeAdded:
+				ui.PrintTimestamp(fmt.Sprintf("%s %s (%d 个服务)", color.GreenString("➕ 新增"), change.FilePath, len(change.ComposeFile.Services)))
+			case compose.ChangeModified:
+				ui.PrintTimestamp(fmt.Sprintf("%s %s (%d 个服务)", color.YellowString("✏️  修改"), change.FilePath, len(change.ComposeFile.Services)))
+			case compose.ChangeRemoved:
+				ui.PrintTimestamp(fmt.Sprintf("%s %s", color.RedString("➖ 移除"), change.FilePath))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo("已停止监控")
+	return nil
+}
+
+// printDockerEvent prints container exits of the managed projects and image pulls seen in watch mode
+func printDockerEvent(event docker.ContainerEvent, projects map[string]string) {
+	switch {
+	case event.Type == "container" && event.Action == "die":
+		project := event.ProjectName()
+		managed := false
+		for _, name := range projects {
+			if name != "" && name == project {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			return
+		}
+		ui.PrintTimestamp(color.RedString("💥 项目 %s 的服务 %s 容器已退出 (退出码: %s)", project, event.ServiceName(), event.Attributes["exitCode"]))
+	case event.Type == "image" && event.Action == "pull":
+		ui.PrintTimestamp(fmt.Sprintf("%s %s", color.CyanString("⬇️  已拉取镜像"), event.Actor))
+	}
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	// 加载配置
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	// 合并命令行参数
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+	if tagStrategy != "latest" {
+		cfg.ImageTagStrategy = tagStrategy
+	}
+	if len(excludeImages) > 0 {
+		cfg.ExcludeImages = excludeImages
+	}
+	if semverPattern != "" {
+		cfg.SemverPattern = semverPattern
+	}
+	if regexPattern != "" {
+		cfg.RegexPattern = regexPattern
+	}
+	if apiTimeout > 0 {
+		cfg.RegistryAPITimeout = apiTimeout
+	}
+	if tagFormat != "" {
+		cfg.TagFormat = tagFormat
+	}
+	if pullPlatform != "" {
+		cfg.Platform = pullPlatform
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		return fmt.Errorf("参数验证失败: %v", err)
+	}
+	if _, err := strategy.FormatTag(cfg.TagFormat, "1.0.0"); err != nil {
+		return err
+	}
+
+	if len(cfg.ComposePaths) == 0 {
+		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
+	}
+
+	tagStrategyImpl, err := strategy.NewFromString(cfg.ImageTagStrategy, cfg)
+	if err != nil {
+		return err
+	}
+	semverStrategy, isSemver := tagStrategyImpl.(*strategy.SemverStrategy)
+	if showVersions > 0 && !isSemver {
+		ui.PrintWarning("--show-versions 仅适用于 semver 策略，已忽略")
+	}
+
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+
+	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	composeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
+	if err != nil {
+		return fmt.Errorf("扫描失败: %v", err)
+	}
+
+	if len(composeFiles) == 0 {
+		ui.PrintEmptyLine()
+		ui.PrintWarning("未找到任何 Docker Compose 文件")
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo(fmt.Sprintf("🔎 正在使用 %s 策略查询镜像版本...", cfg.ImageTagStrategy))
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	if err := imageManager.SetPlatform(cfg.Platform); err != nil {
+		return err
+	}
+
+	headers := []string{"项目名称", "服务", "当前镜像", "目标镜像", "下载大小"}
+	withVersions := showVersions > 0 && isSemver
+	if withVersions {
+		headers = append(headers, "可用版本")
+	}
+
+	var rows [][]string
+	var diffs []ui.Diff
+	updatable := 0
+	for _, cf := range composeFiles {
+		serviceNames := make([]string, 0, len(cf.Services))
+		for serviceName := range cf.Services {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			image := cf.Services[serviceName].Image
+			if image == "" || isExcludedImage(image, cfg.ExcludeImages) {
+				continue
+			}
+
+			repository, currentTag := splitImageTag(image)
+			target := color.RedString("查询失败")
+			downloadSize := "-"
+			latestTag, err := tagStrategyImpl.GetLatestTag(image)
+			if err == nil && isSemver {
+				latestTag, err = strategy.FormatTag(cfg.TagFormat, latestTag)
+			}
+			if err != nil {
+				ui.Debug(fmt.Sprintf("查询 %s 失败: %v", image, err), verbose)
+			} else if latestTag == currentTag {
+				target = "已是最新"
+			} else {
+				targetImage := repository + ":" + latestTag
+				target = color.GreenString("%s", targetImage)
+				updatable++
+				diffs = append(diffs, ui.Diff{Label: cf.ProjectName + "/" + serviceName, Old: image, New: targetImage})
+
+				// 下载大小仅供参考，查询失败时不影响结果
+				if size, err := imageManager.GetImageSize(targetImage); err != nil {
+					ui.Debug(err.Error(), verbose)
+				} else {
+					downloadSize = ui.FormatSize(size)
+				}
+			}
+
+			row := []string{cf.ProjectName, serviceName, image, target, downloadSize}
+			if withVersions {
+				versionList := "-"
+				if versions, err := semverStrategy.GetVersionList(image, showVersions); err == nil {
+					if formatted := semverStrategy.FormatVersionList(versions, currentTag); formatted != "" {
+						versionList = formatted
+					}
+				}
+				row = append(row, versionList)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 {
+		ui.PrintEmptyLine()
+		ui.PrintWarning("没有可检查的镜像服务")
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	ui.PrintTable(headers, rows)
+	if len(diffs) > 0 {
+		ui.PrintSubHeader("可更新的镜像")
+		ui.PrintDiffTable(diffs)
+		ui.PrintEmptyLine()
+	}
+	ui.PrintInfo(fmt.Sprintf("共 %d 个服务，其中 %s 个可更新", len(rows), color.GreenString("%d", updatable)))
+	ui.PrintEmptyLine()
+
+	return nil
+}
+
+// splitImageTag 将镜像拆分为仓库和标签，未指定标签时返回 latest
+func splitImageTag(image string) (string, string) {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
+
+// isExcludedImage 检查镜像是否匹配排除列表，与更新时的排除规则一致
+func isExcludedImage(image string, excludeImages []string) bool {
+	for _, pattern := range excludeImages {
+		if strings.Contains(image, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+	if len(cfg.ComposePaths) == 0 {
+		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
+	}
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+
+	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	scanner.SetSkipHidden(!includeHidden)
+	composeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
+	if err != nil {
+		return fmt.Errorf("扫描失败: %v", err)
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo(fmt.Sprintf("🔎 检查 %d 个 Compose 文件...", len(composeFiles)))
+
+	conflicts := compose.ValidatePortConflicts(composeFiles)
+	displayPortConflicts(conflicts)
+	dependencyIssues := compose.ValidateDependencies(composeFiles)
+	displayDependencyIssues(dependencyIssues)
+	unused := displayUnusedDeclarations(composeFiles, cfg)
+
+	var problems []string
+	if len(conflicts) > 0 {
+		problems = append(problems, fmt.Sprintf("%d 个端口冲突", len(conflicts)))
+	}
+	if len(dependencyIssues) > 0 {
+		problems = append(problems, fmt.Sprintf("%d 个依赖问题", len(dependencyIssues)))
+	}
+	if unused > 0 {
+		problems = append(problems, fmt.Sprintf("%d 个未使用的声明", unused))
+	}
+	if len(problems) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("发现 %s", strings.Join(problems, "、"))
+	}
+	return nil
+}
+
+// displayDependencyIssues prints invalid depends_on entries and dependency cycles
+func displayDependencyIssues(issues []compose.DependencyIssue) {
+	ui.PrintEmptyLine()
+	ui.PrintSection("🔗 服务依赖检查")
+
+	if len(issues) == 0 {
+		ui.PrintSuccess("✅ 服务依赖配置正确")
+		ui.PrintEmptyLine()
+		return
+	}
+
+	rows := make([][]string, 0, len(issues))
+	for _, issue := range issues {
+		service := issue.Service
+		if service == "" {
+			service = "-"
+		}
+		rows = append(rows, []string{displayPath(issue.File), service, issue.Reason})
+	}
+	ui.PrintTable([]string{"文件", "服务", "问题"}, rows)
+	ui.PrintEmptyLine()
+}
+
+// displayUnusedDeclarations lists networks and volumes that are declared but not used by any service
+// They are only hints unless --strict is set; returns the number of declarations that count as problems
+func displayUnusedDeclarations(composeFiles []*types.ComposeFile, cfg *types.Config) int {
+	parser := compose.NewParser()
+	parser.SetEncoding(cfg.ComposeEncoding)
+	parser.SetStrict(validateStrict)
+
+	var rows [][]string
+	problems := 0
+	for _, cf := range composeFiles {
+		warnings, err := parser.ValidateFile(cf.FilePath)
+		if errors.Is(err, compose.ErrUnusedDeclarations) {
+			problems += len(warnings)
+		} else if err != nil {
+			continue
+		}
+		for _, warning := range warnings {
+			rows = append(rows, []string{displayPath(cf.FilePath), warning.Message})
+		}
+	}
+	if len(rows) == 0 {
+		return problems
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintSection("🧹 未使用的声明")
+	ui.PrintTable([]string{"文件", "提示"}, rows)
+	ui.PrintEmptyLine()
+	return problems
+}
+
+// composeInstallPath doctor --fix 安装 docker-compose 的位置
+const composeInstallPath = "/usr/local/bin/docker-compose"
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ui.PrintEmptyLine()
+	ui.PrintSection("🩺 环境检查")
+
+	problems, fixed := 0, 0
+
+	// 配置文件
+	configPath := config.DefaultConfigPath()
+	if cfgFile != "" {
+		configPath = cfgFile
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		ui.PrintSuccess(fmt.Sprintf("配置文件: %s", configPath))
+	} else {
+		problems++
+		ui.PrintWarning(fmt.Sprintf("配置文件不存在: %s", configPath))
+		if doctorFix && confirmFix("生成默认配置文件?") {
+			if err := config.GenerateDefaultConfig(configPath); err != nil {
+				ui.PrintError(fmt.Sprintf("生成配置文件失败: %v", err))
+			} else {
+				ui.PrintSuccess(fmt.Sprintf("已生成默认配置文件: %s", configPath))
+				fixed++
+			}
+		}
+	}
+
+	// 不使用 LoadConfig，避免配置文件不存在时被自动创建
+	cfg, err := config.LoadConfigFile()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	docker.SetDefaultConfig(&cfg.DockerConfig)
+
+	// Compose 命令，auto 时 docker compose 插件可用即使用插件
+	dockerClient := docker.NewClient()
+	composeVersion := cfg.ComposeVersion
+	if composeVersion == "" || composeVersion == "auto" {
+		composeVersion = dockerClient.ComposeVersion()
+	}
+	if composeVersion == "v2" {
+		if available, _ := dockerClient.IsComposeV2Available(); available {
+			ui.PrintSuccess("Compose 命令: docker compose")
+		} else {
+			problems++
+			ui.PrintWarning("docker compose 插件不可用")
+			printManualFix(
+				"安装 Docker Compose 插件，如 apt install docker-compose-plugin",
+				"或在配置中设置 compose_version: v1 使用 docker-compose 命令",
+			)
+		}
+	} else if path, err := exec.LookPath("docker-compose"); err == nil {
+		ui.PrintSuccess(fmt.Sprintf("Compose 命令: %s", path))
+	} else {
+		problems++
+		ui.PrintWarning("未找到 docker-compose 命令")
+		if doctorFix && confirmFix(fmt.Sprintf("通过 curl 下载 docker-compose 到 %s?", composeInstallPath)) {
+			if err := installDockerCompose(composeInstallPath); err != nil {
+				ui.PrintError(fmt.Sprintf("安装 docker-compose 失败: %v", err))
+				printManualFix(
+					"以 root 身份重新运行 compman doctor --fix，或手动下载:",
+					fmt.Sprintf("curl -fSL %s -o %s && chmod +x %s", dockerComposeDownloadURL(), composeInstallPath, composeInstallPath),
+					"如果已安装 docker compose 插件，也可以使用 --compose-version v2",
+				)
+			} else {
+				ui.PrintSuccess(fmt.Sprintf("已安装 docker-compose: %s", composeInstallPath))
+				fixed++
+			}
+		} else if !doctorFix {
+			printManualFix("使用 --fix 自动下载，或使用 --compose-version v2 改用 docker compose 插件")
+		}
+	}
+
+	// Docker 连接
+	if version, err := dockerClient.GetVersion(); err == nil {
+		ui.PrintSuccess(fmt.Sprintf("Docker: %s", version))
+	} else {
+		problems++
+		ui.PrintWarning(fmt.Sprintf("无法连接 Docker: %v", err))
+		printManualFix(
+			"确认 Docker 已安装并正在运行，如 systemctl start docker",
+			"确认当前用户有权限访问 Docker，如 usermod -aG docker $USER 后重新登录",
+			"连接远程 Docker 时检查配置中的 docker_config.host 或 docker_config.ssh_host",
+		)
+	}
+	dockerClient.Close()
+
+	// Docker Hub 拉取速率限制，查询失败（如无法访问 Docker Hub）不视为问题
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	if status, err := imageManager.GetRateLimitStatus(); err != nil {
+		ui.PrintInfo(fmt.Sprintf("无法查询 Docker Hub 拉取限制: %v", err))
+	} else if rateLimitLow(status) {
+		problems++
+		ui.PrintWarning(fmt.Sprintf("Docker Hub 拉取限制: %s", formatRateLimitStatus(status)))
+		printManualFix(
+			"使用 docker login 登录 Docker Hub 以提高拉取限制",
+			"或等待限制重置后再更新镜像",
+		)
+	} else {
+		ui.PrintSuccess(fmt.Sprintf("Docker Hub 拉取限制: %s", formatRateLimitStatus(status)))
+	}
+
+	// Compose 文件路径
+	for _, path := range cfg.ComposePaths {
+		if _, err := os.Stat(path); err == nil {
+			ui.PrintSuccess(fmt.Sprintf("Compose 路径: %s", path))
+			continue
+		}
+
+		problems++
+		ui.PrintWarning(fmt.Sprintf("Compose 路径不存在: %s", path))
+		if ext := filepath.Ext(path); ext == ".yml" || ext == ".yaml" {
+			printManualFix(
+				"检查文件路径是否正确",
+				fmt.Sprintf("或使用 compman config remove-path %s 删除该路径", path),
+			)
+			continue
+		}
+		if doctorFix && confirmFix(fmt.Sprintf("创建目录 %s?", path)) {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				ui.PrintError(fmt.Sprintf("创建目录失败: %v", err))
+			} else {
+				ui.PrintSuccess(fmt.Sprintf("已创建目录: %s", path))
+				fixed++
+			}
+		}
+	}
+
+	ui.PrintEmptyLine()
+	remaining := problems - fixed
+	if remaining == 0 {
+		if problems == 0 {
+			ui.PrintSuccess("未发现问题")
+		} else {
+			ui.PrintSuccess(fmt.Sprintf("已修复全部 %d 个问题", fixed))
+		}
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	if !doctorFix {
+		ui.PrintInfo("使用 --fix 尝试自动修复")
+	}
+	ui.PrintEmptyLine()
+	cmd.SilenceUsage = true
+	return fmt.Errorf("还有 %d 个问题未解决", remaining)
+}
+
+// rateLimitWarnRatio 剩余拉取次数低于限制的该比例时发出警告
+const rateLimitWarnRatio = 0.2
+
+// rateLimitLow 判断 Docker Hub 剩余拉取次数是否低于警告阈值
+func rateLimitLow(status *docker.RateLimitStatus) bool {
+	return !status.Unlimited() && float64(status.Remaining) < float64(status.Limit)*rateLimitWarnRatio
+}
+
+// formatRateLimitStatus 格式化拉取速率限制，如 "剩余 12/100 次（每 6h0m0s）"
+func formatRateLimitStatus(status *docker.RateLimitStatus) string {
+	if status.Unlimited() {
+		return "不受限制"
+	}
+
+	text := fmt.Sprintf("剩余 %d/%d 次", status.Remaining, status.Limit)
+	if status.Window > 0 {
+		text += fmt.Sprintf("（每 %s）", status.Window)
+	}
+	if status.Reset > 0 {
+		text += fmt.Sprintf("，%s 后重置", status.Reset)
+	}
+	return text
+}
+
+// diskSpaceMargin is the factor applied to the estimated download size before comparing it with the free space
+const diskSpaceMargin = 1.1
+
+// checkDiskSpace estimates the download size of the target images chosen by the tag strategy
+// and returns an error when the Docker root directory has less than 110% of it available.
+// In dry-run mode the estimate is only displayed.
+func checkDiskSpace(cfg *types.Config, composeFiles []*types.ComposeFile) error {
+	ui.PrintEmptyLine()
+	ui.PrintInfo("💽 正在估算需要下载的镜像大小...")
+
+	estimate, counted, unknown, err := estimatePullSize(cfg, composeFiles)
+	if err != nil {
+		return err
+	}
+	required := int64(float64(estimate) * diskSpaceMargin)
+	summary := fmt.Sprintf("预计需要下载 %s (%d 个镜像)", ui.FormatSize(estimate), counted)
+	if unknown > 0 {
+		summary += fmt.Sprintf("，%d 个镜像无法获取大小未计入", unknown)
+	}
+	ui.PrintInfo(summary)
+
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+	info, err := dockerClient.GetSystemInfo()
+	if err != nil {
+		if cfg.DryRun {
+			ui.PrintWarning(fmt.Sprintf("无法检查磁盘空间: %v", err))
+			return nil
+		}
+		return fmt.Errorf("磁盘空间检查失败: %v", err)
+	}
+	free, err := docker.FreeDiskSpace(info.DockerRootDir)
+	if err != nil {
+		if cfg.DryRun {
+			ui.PrintWarning(fmt.Sprintf("无法检查磁盘空间: %v", err))
+			return nil
+		}
+		return fmt.Errorf("磁盘空间检查失败: %v", err)
+	}
+
+	ui.PrintInfo(fmt.Sprintf("Docker 数据目录 %s 剩余空间: %s", info.DockerRootDir, ui.FormatSize(free)))
+	if free >= required {
+		return nil
+	}
+	message := fmt.Sprintf("磁盘空间不足: Docker 数据目录 %s 剩余 %s，预计需要 %s (含 10%% 余量)",
+		info.DockerRootDir, ui.FormatSize(free), ui.FormatSize(required))
+	if cfg.DryRun {
+		ui.PrintWarning(message)
+		return nil
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// estimatePullSize sums the download size of the distinct target images of all services.
+// It returns the total, the number of images counted and the number whose size could not be queried.
+func estimatePullSize(cfg *types.Config, composeFiles []*types.ComposeFile) (int64, int, int, error) {
+	tagStrategyImpl, err := strategy.NewFromString(cfg.ImageTagStrategy, cfg)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	_, isSemver := tagStrategyImpl.(*strategy.SemverStrategy)
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	if err := imageManager.SetPlatform(cfg.Platform); err != nil {
+		return 0, 0, 0, err
+	}
+
+	targets := make(map[string]bool)
+	unknown := 0
+	for _, cf := range composeFiles {
+		for _, service := range cf.Services {
+			if service.Image == "" || isExcludedImage(service.Image, cfg.ExcludeImages) {
+				continue
+			}
+			latestTag, err := tagStrategyImpl.GetLatestTag(service.Image)
+			if err == nil && isSemver {
+				latestTag, err = strategy.FormatTag(cfg.TagFormat, latestTag)
+			}
+			if err != nil {
+				ui.Debug(fmt.Sprintf("查询 %s 失败: %v", service.Image, err), verbose)
+				unknown++
+				continue
+			}
+			repository, _ := splitImageTag(service.Image)
+			targets[repository+":"+latestTag] = true
+		}
+	}
+
+	var total int64
+	counted := 0
+	for image := range targets {
+		size, err := imageManager.GetImageSize(image)
+		if err != nil {
+			ui.Debug(err.Error(), verbose)
+			unknown++
+			continue
+		}
+		total += size
+		counted++
+	}
+
+	return total, counted, unknown, nil
+}
+
+// warnLowRateLimit 在 Docker Hub 剩余拉取次数较少时发出警告，查询失败时忽略
+func warnLowRateLimit(cfg *types.Config) {
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	status, err := imageManager.GetRateLimitStatus()
+	if err != nil || !rateLimitLow(status) {
+		return
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintWarning(fmt.Sprintf("Docker Hub 拉取次数即将用完: %s，拉取镜像可能因 429 错误失败", formatRateLimitStatus(status)))
+	ui.PrintInfo("💡 使用 docker login 登录 Docker Hub 可提高拉取限制")
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	fmt.Fprintf(ui.Output(), "compman %s (built on %s)\n", version, buildDate)
+	if !versionCheck {
+		return nil
+	}
+
+	if release.Disabled() {
+		ui.PrintInfo(fmt.Sprintf("已通过 %s 跳过版本检查", release.NoUpdateCheckEnv))
+		return nil
+	}
+
+	result, err := release.CheckLatest(version)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("检查新版本失败: %v", err)
+	}
+
+	if result.UpdateAvailable {
+		ui.PrintWarning(fmt.Sprintf("有可用的新版本: %s (当前版本: %s)", result.Latest, result.Current))
+		ui.PrintInfo("💡 升级: curl -fsSL https://raw.githubusercontent.com/QuentinHsu/compman/main/install-online.sh | bash")
+	} else {
+		ui.PrintSuccess(fmt.Sprintf("已是最新版本 (%s)", result.Current))
+	}
+	return nil
+}
+
+// confirmFix 确认是否执行一项修复，指定 --yes 时直接执行
+func confirmFix(message string) bool {
+	return doctorYes || ui.Confirm(message)
+}
+
+// printManualFix 输出无法自动修复的问题的手动处理方法
+func printManualFix(steps ...string) {
+	for _, step := range steps {
+		ui.PrintItem("👉 " + step)
+	}
+}
+
+// dockerComposeDownloadURL 返回当前系统对应的 docker-compose 独立二进制下载地址
+func dockerComposeDownloadURL() string {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	case "arm":
+		arch = "armv7"
+	}
+	return fmt.Sprintf("https://github.com/docker/compose/releases/latest/download/docker-compose-%s-%s", runtime.GOOS, arch)
+}
+
+// installDockerCompose 使用 curl 下载 docker-compose 独立二进制并设置可执行权限
+func installDockerCompose(target string) error {
+	curl := exec.Command("curl", "-fSL", "-o", target, dockerComposeDownloadURL())
+	curl.Stdout = os.Stdout
+	curl.Stderr = os.Stderr
+	if err := curl.Run(); err != nil {
+		return fmt.Errorf("下载失败: %v", err)
+	}
+	if err := os.Chmod(target, 0755); err != nil {
+		return fmt.Errorf("设置可执行权限失败: %v", err)
+	}
+	return nil
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	// 加载配置
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	// 如果命令行指定了路径，则覆盖配置文件中的路径
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+
+	if len(cfg.ComposePaths) == 0 {
+		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
+	}
+
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+
+	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	composeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
+	if err != nil {
+		return fmt.Errorf("扫描失败: %v", err)
+	}
+
+	if len(composeFiles) == 0 {
+		ui.PrintEmptyLine()
+		ui.PrintWarning("未找到任何 Docker Compose 文件")
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	ui.PrintEmptyLine()
+	ui.PrintSection("📊 Compose 项目状态")
+
+	headers := []string{"项目名称", "服务", "容器", "状态", "健康检查"}
+	if statusShowEnv {
+		headers = append(headers, "环境变量")
+	}
+	var rows [][]string
+
+	for _, cf := range composeFiles {
+		project, err := dockerClient.InspectComposeProject(compose.RuntimeProjectName(cf))
+		if err != nil {
+			return err
+		}
+
+		if len(project.Services) == 0 {
+			row := []string{cf.ProjectName, "-", "-", color.YellowString("未运行"), "-"}
+			if statusShowEnv {
+				row = append(row, "-")
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		for _, service := range project.Services {
+			row := []string{
+				cf.ProjectName,
+				service.ServiceName,
+				shortID(service.ContainerID),
+				formatContainerState(service.State),
+				formatHealthStatus(service.Health),
+			}
+			if statusShowEnv {
+				row = append(row, containerEnvCell(dockerClient, service.ContainerID))
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	ui.PrintTable(headers, rows)
+	return nil
+}
+
+// sensitiveEnvKeyPattern 匹配可能包含凭据的环境变量名，status --env 中隐藏其值
+var sensitiveEnvKeyPattern = regexp.MustCompile(`(?i)PASSWORD|SECRET|TOKEN|KEY`)
+
+// containerEnvCell 返回容器环境变量的 KEY=VALUE 列表，按变量名排序，敏感变量的值以 ****** 代替
+func containerEnvCell(dockerClient *docker.Client, containerID string) string {
+	env, err := dockerClient.GetContainerEnv(containerID)
+	if err != nil || len(env) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := env[key]
+		if sensitiveEnvKeyPattern.MatchString(key) {
+			value = "******"
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// diagnosticLogLines 诊断异常容器时显示的日志行数
+const diagnosticLogLines = 20
+
+// displayUpdateErrors 输出失败结果的错误详情，包括异常容器的日志摘录
+// asWarnings 为 true 时以警告形式输出（--ignore-errors）
+func displayUpdateErrors(results []*types.UpdateResult, asWarnings bool) {
+	for _, result := range results {
+		if !result.Success && result.Error != nil {
+			message := fmt.Sprintf("%s: %v", result.Service, result.Error)
+			if asWarnings {
+				ui.PrintWarning(message)
+			} else {
+				ui.PrintError(message)
+			}
+		}
+	}
+}
+
+// updateFailureError 在存在失败的更新结果时返回错误，使进程以非零退出码结束
+// 指定 --ignore-errors 时忽略失败，便于定时任务在个别项目失败时仍然成功结束
+func updateFailureError(cmd *cobra.Command, results []*types.UpdateResult) error {
+	if ignoreErrors {
+		return nil
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success && result.Error != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+
+	// 失败详情已在结果中输出，无需再显示命令用法
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d 个服务更新失败，使用 --ignore-errors 可忽略失败", failed)
+}
+
+// skipUnhealthyProjects 更新前检查项目状态，跳过存在健康检查失败或已退出服务的 Compose 文件
+// 返回需要更新的文件和被跳过文件中每个服务的跳过结果；无法获取状态的项目照常更新
+func skipUnhealthyProjects(composeFiles []*types.ComposeFile) ([]*types.ComposeFile, []*types.UpdateResult) {
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	var remaining []*types.ComposeFile
+	var skipped []*types.UpdateResult
+	for _, cf := range composeFiles {
+		project, err := dockerClient.InspectComposeProject(compose.RuntimeProjectName(cf))
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("检查项目 %s 的运行状态失败，继续更新: %v", cf.ProjectName, err))
+			remaining = append(remaining, cf)
+			continue
+		}
+
+		var unhealthy []string
+		for _, service := range project.Services {
+			if service.Health == "unhealthy" || service.State == "exited" {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s (%s)", service.ServiceName, formatServiceStatus(service)))
+			}
+		}
+		if len(unhealthy) == 0 {
+			remaining = append(remaining, cf)
+			continue
+		}
+
+		ui.PrintWarning(fmt.Sprintf("跳过项目 %s，以下服务状态异常: %s", cf.ProjectName, strings.Join(unhealthy, ", ")))
+		serviceNames := make([]string, 0, len(cf.Services))
+		for serviceName, service := range cf.Services {
+			if service.Image != "" {
+				serviceNames = append(serviceNames, serviceName)
+			}
+		}
+		sort.Strings(serviceNames)
+		for _, serviceName := range serviceNames {
+			skipped = append(skipped, &types.UpdateResult{
+				Service:    serviceName,
+				OldImage:   cf.Services[serviceName].Image,
+				NewImage:   cf.Services[serviceName].Image,
+				UpdatedAt:  time.Now(),
+				SkipReason: "服务状态异常",
+			})
+		}
+	}
+
+	return remaining, skipped
+}
+
+// formatServiceStatus 返回容器状态和健康检查状态，如 "running, unhealthy"
+func formatServiceStatus(service types.ServiceStatus) string {
+	if service.Health != "" {
+		return service.State + ", " + service.Health
+	}
+	return service.State
+}
+
+// verifyProjectHealth 检查更新后的项目中是否有未运行或健康检查失败的服务
+func verifyProjectHealth(composeFiles []*types.ComposeFile) {
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	allHealthy := true
+	for _, cf := range composeFiles {
+		project, err := dockerClient.InspectComposeProject(compose.RuntimeProjectName(cf))
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("检查项目 %s 的运行状态失败: %v", cf.ProjectName, err))
+			allHealthy = false
+			continue
+		}
+
+		unhealthy := project.UnhealthyServices()
+		if len(unhealthy) == 0 {
+			continue
+		}
+
+		allHealthy = false
+		for _, service := range unhealthy {
+			ui.PrintWarning(fmt.Sprintf("项目 %s 的服务 %s 状态异常 (%s)", cf.ProjectName, service.ServiceName, formatServiceStatus(service)))
+		}
+		if logs := dockerClient.DiagnoseComposeProject(compose.RuntimeProjectName(cf), diagnosticLogLines); logs != "" {
+			ui.PrintError(fmt.Sprintf("项目 %s 异常容器日志:%s", cf.ProjectName, logs))
+		}
+	}
+
+	if allHealthy {
+		ui.PrintSuccess("所有服务运行正常")
+	}
+	ui.PrintEmptyLine()
+}
+
+// formatContainerState 为容器状态添加颜色
+func formatContainerState(state string) string {
+	switch state {
+	case "running":
+		return color.GreenString(state)
+	case "restarting", "paused", "created":
+		return color.YellowString(state)
+	default:
+		return color.RedString(state)
+	}
+}
+
+// formatHealthStatus 为健康检查状态添加颜色
+func formatHealthStatus(health string) string {
+	switch health {
+	case "healthy":
+		return color.GreenString(health)
+	case "unhealthy":
+		return color.RedString(health)
+	case "starting":
+		return color.YellowString(health)
+	default:
+		return "-"
+	}
+}
+
+func runConfig(cmd *cobra.Command, args []string) error {
+	// 获取默认配置文件路径
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("获取用户目录失败: %v", err)
+	}
+	defaultPath := filepath.Join(home, ".config", "compman", "config.yml")
+
+	if showPathOnly {
+		fmt.Println(defaultPath)
+		return nil
+	}
+
+	// 导出的内容可直接重定向到文件，不输出其他提示信息
+	if exportFormat != "" {
+		export := config.ExportConfig
+		if showSecrets {
+			export = config.ExportConfigWithSecrets
+		}
+		if err := export(exportFormat, ui.Output()); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo("📁 配置文件信息")
+	ui.PrintItem(fmt.Sprintf("默认配置文件路径: %s", defaultPath))
+
+	if cfgFile != "" {
+		ui.PrintItem(fmt.Sprintf("用户指定配置文件: %s", cfgFile))
+	}
+
+	// 检查默认配置文件是否存在
+	if _, err := os.Stat(defaultPath); err == nil {
+		ui.PrintSuccess("✅ 默认配置文件存在")
+	} else {
+		ui.PrintWarning("⚠️  默认配置文件不存在，将在首次运行时创建")
+	}
+
+	// 加载并显示配置内容
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	if showDiff {
+		displayConfigDiff(cfg)
+		return nil
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo("⚙️  当前配置内容:")
+	ui.PrintItem(fmt.Sprintf("Compose文件路径: %v", cfg.ComposePaths))
+	ui.PrintItem(fmt.Sprintf("镜像标签策略: %s", cfg.ImageTagStrategy))
+	ui.PrintItem(fmt.Sprintf("环境: %s", cfg.Environment))
+	ui.PrintItem(fmt.Sprintf("备份启用: %t", cfg.BackupEnabled))
+	ui.PrintItem(fmt.Sprintf("超时时间: %s", cfg.Timeout))
+	ui.PrintEmptyLine()
+
+	// --verbose 时列出参与合并的配置文件和每个配置项的来源，便于排查配置未生效的原因
+	if verbose {
+		ui.PrintInfo("📄 配置文件 (按应用顺序):")
+		for _, file := range config.ListConfigFiles() {
+			ui.PrintItem(file)
+		}
+		ui.PrintEmptyLine()
+
+		if _, sources := config.GetEffectiveConfig(); len(sources) > 0 {
+			ui.PrintInfo("🔍 配置来源:")
+			for _, source := range sources {
+				ui.PrintItem(source)
+			}
+			ui.PrintEmptyLine()
+		}
+	}
+
+	return nil
+}
+
+func runConfigAddPath(cmd *cobra.Command, args []string) error {
+	path, err := normalizeComposePath(args[0])
+	if err != nil {
+		return err
+	}
+
+	if isGlobPattern(path) {
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Errorf("无效的 glob 模式 %s: %v", path, err)
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("路径不存在: %s", path)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("路径不是目录: %s", path)
+		}
+	}
+
+	cfg, err := config.LoadConfigFile()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	for _, existing := range cfg.ComposePaths {
+		if existing == path {
+			return fmt.Errorf("路径已存在于 compose_paths 中: %s", path)
+		}
+	}
+
+	cfg.ComposePaths = append(cfg.ComposePaths, path)
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
 
 	ui.PrintEmptyLine()
-	ui.PrintInfo("⚙️  当前配置内容:")
-	ui.PrintItem(fmt.Sprintf("Compose文件路径: %v", cfg.ComposePaths))
-	ui.PrintItem(fmt.Sprintf("镜像标签策略: %s", cfg.ImageTagStrategy))
-	ui.PrintItem(fmt.Sprintf("环境: %s", cfg.Environment))
-	ui.PrintItem(fmt.Sprintf("备份启用: %t", cfg.BackupEnabled))
-	ui.PrintItem(fmt.Sprintf("超时时间: %s", cfg.Timeout))
+	ui.PrintSuccess(fmt.Sprintf("已添加路径: %s", path))
+	displayComposePaths(cfg.ComposePaths)
+	return nil
+}
+
+func runConfigRemovePath(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfigFile()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	target := args[0]
+	absTarget, err := normalizeComposePath(target)
+	if err != nil {
+		return err
+	}
+
+	var remaining, removed []string
+	for _, existing := range cfg.ComposePaths {
+		matched := existing == target || existing == absTarget
+		if !matched && isGlobPattern(target) {
+			matched, _ = filepath.Match(absTarget, existing)
+		}
+
+		if matched {
+			removed = append(removed, existing)
+		} else {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	if len(removed) == 0 {
+		return fmt.Errorf("compose_paths 中没有找到路径: %s", target)
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("至少需要保留一个 compose 文件路径")
+	}
+
+	cfg.ComposePaths = remaining
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+
+	ui.PrintEmptyLine()
+	for _, path := range removed {
+		ui.PrintSuccess(fmt.Sprintf("已移除路径: %s", path))
+	}
+	displayComposePaths(cfg.ComposePaths)
+	return nil
+}
+
+// normalizeComposePath 将路径转换为绝对路径，glob 模式同样适用
+func normalizeComposePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("解析路径 %s 失败: %v", path, err)
+	}
+	return absPath, nil
+}
+
+// isGlobPattern 判断路径是否包含 glob 通配符
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func runPin(cmd *cobra.Command, args []string) error {
+	filePath, err := normalizeComposePath(args[0])
+	if err != nil {
+		return err
+	}
+	services := args[1:]
+
+	if pinClear && len(services) > 0 {
+		return fmt.Errorf("--clear 不能与服务名同时使用")
+	}
+
+	cfg, err := config.LoadConfigFile()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	if len(services) == 0 && !pinClear {
+		ui.PrintEmptyLine()
+		if pinned := cfg.PinnedServices[filePath]; len(pinned) > 0 {
+			ui.PrintInfo(fmt.Sprintf("📌 %s 固定的服务: %s", filePath, strings.Join(pinned, ", ")))
+		} else {
+			ui.PrintInfo(fmt.Sprintf("%s 没有固定的服务", filePath))
+		}
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	if pinClear {
+		if _, exists := cfg.PinnedServices[filePath]; !exists {
+			return fmt.Errorf("%s 没有固定的服务", filePath)
+		}
+		delete(cfg.PinnedServices, filePath)
+	} else {
+		parser := compose.NewParser()
+		parser.SetEncoding(cfg.ComposeEncoding)
+		cf, err := parser.ParseFile(filePath)
+		if err != nil {
+			return fmt.Errorf("解析 Compose 文件失败: %v", err)
+		}
+		for _, serviceName := range services {
+			if _, exists := cf.Services[serviceName]; !exists {
+				return fmt.Errorf("服务 %s 不存在于 %s", serviceName, filePath)
+			}
+		}
+
+		if cfg.PinnedServices == nil {
+			cfg.PinnedServices = make(map[string][]string)
+		}
+		cfg.PinnedServices[filePath] = services
+	}
+
+	if err := config.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+
+	ui.PrintEmptyLine()
+	if pinClear {
+		ui.PrintSuccess(fmt.Sprintf("已取消固定 %s 的服务", filePath))
+	} else {
+		ui.PrintSuccess(fmt.Sprintf("已固定 %s 的服务: %s", filePath, strings.Join(services, ", ")))
+	}
+	ui.PrintEmptyLine()
+	return nil
+}
+
+// displayComposePaths 以表格形式显示当前的 compose_paths
+func displayComposePaths(paths []string) {
+	ui.PrintEmptyLine()
+	ui.PrintInfo("📂 当前 compose_paths:")
+
+	rows := make([][]string, len(paths))
+	for i, path := range paths {
+		rows[i] = []string{fmt.Sprintf("%d", i+1), path}
+	}
+	ui.PrintTable([]string{"序号", "路径"}, rows)
+	ui.PrintEmptyLine()
+}
+
+// displayConfigDiff 以表格形式显示与默认配置不同的配置项
+func displayConfigDiff(cfg *types.Config) {
+	diff := config.DiffFromDefault(cfg)
+
+	ui.PrintEmptyLine()
+	if len(diff) == 0 {
+		ui.PrintSuccess("当前配置与默认配置一致")
+		ui.PrintEmptyLine()
+		return
+	}
+
+	ui.PrintInfo(fmt.Sprintf("⚙️  与默认配置不同的配置项 (%d):", len(diff)))
+
+	keys := make([]string, 0, len(diff))
+	for key := range diff {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, []string{key, color.YellowString("%v", diff[key])})
+	}
+	ui.PrintTable([]string{"配置项", "当前值"}, rows)
+}
+
+func displayUpdateResults(results []*types.UpdateResult, templatePath string) error {
+	// 未指定模板时使用默认模板，与README.md格式一致
+	tmpl, err := ui.LoadUpdateResultsTemplate(templatePath)
+	if err != nil {
+		return err
+	}
+
+	// 默认输出中先列出修改了镜像标签的服务，指定 --results-verbose 时列出所有服务的详细结果，
+	// 自定义模板自行决定输出内容
+	if templatePath == "" && resultsVerbose {
+		ui.PrintSubHeader("更新结果")
+		ui.PrintUpdateResultsTable(results)
+		ui.PrintEmptyLine()
+	} else if templatePath == "" {
+		if diffs := imageDiffs(results); len(diffs) > 0 {
+			ui.PrintSubHeader("镜像变更")
+			ui.PrintDiffTable(diffs)
+			ui.PrintEmptyLine()
+		}
+	}
+
+	return ui.RenderUpdateResults(ui.Output(), tmpl, results)
+}
+
+// imageDiffs 返回成功更新且镜像标签发生变化的服务
+func imageDiffs(results []*types.UpdateResult) []ui.Diff {
+	var diffs []ui.Diff
+	for _, result := range results {
+		if !result.Success || !result.Changed {
+			continue
+		}
+		// 新镜像可能带有 "(已更新)" 等说明
+		newImage, _, _ := strings.Cut(result.NewImage, " (")
+		if newImage == result.OldImage {
+			continue
+		}
+		diffs = append(diffs, ui.Diff{Label: result.Service, Old: result.OldImage, New: newImage})
+	}
+	return diffs
+}
+
+// openUpdateLog 以追加方式打开日志文件，之后的输出同时写入标准输出和去除颜色的日志文件
+// 返回的函数恢复标准输出并关闭日志文件
+func openUpdateLog(path string, startTime time.Time) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开日志文件失败: %v", err)
+	}
+
+	if _, err := fmt.Fprintf(file, "\n===== compman update %s =====\n", startTime.Format(time.RFC3339)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("写入日志文件失败: %v", err)
+	}
+
+	ui.SetOutput(io.MultiWriter(os.Stdout, ui.NewANSIStripWriter(file)))
+	return func() {
+		ui.SetOutput(os.Stdout)
+		file.Close()
+	}, nil
+}
+
+// parseLabelFilters parses key=value label filters into a map
+func parseLabelFilters(filters []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, filter := range filters {
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("无效的标签过滤条件: %s (正确格式: key=value)", filter)
+		}
+		labels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return labels, nil
+}
+
+// parseImageTagOverrides parses repeated --image-tag image=tag flags
+func parseImageTagOverrides(values []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, value := range values {
+		image, tag, found := strings.Cut(value, "=")
+		image, tag = strings.TrimSpace(image), strings.TrimSpace(tag)
+		if !found || image == "" || tag == "" || strings.ContainsAny(tag, " :/@") {
+			return nil, fmt.Errorf("无效的 --image-tag %s (正确格式: image=tag，如 nginx=1.25.4)", value)
+		}
+		if strings.Contains(image, "@") || strings.LastIndex(image, ":") > strings.LastIndex(image, "/") {
+			return nil, fmt.Errorf("无效的 --image-tag %s: 镜像名不能包含标签或摘要", value)
+		}
+		overrides[image] = tag
+	}
+	return overrides, nil
+}
+
+// parseImageRegistryMap parses repeated --image-registry-map old=new flags
+func parseImageRegistryMap(values []string) (map[string]string, error) {
+	registryMap := make(map[string]string)
+	for _, value := range values {
+		oldPrefix, newPrefix, found := strings.Cut(value, "=")
+		oldPrefix = strings.TrimSuffix(strings.TrimSpace(oldPrefix), "/")
+		newPrefix = strings.TrimSuffix(strings.TrimSpace(newPrefix), "/")
+		if !found || oldPrefix == "" || newPrefix == "" || strings.ContainsAny(value, " @") {
+			return nil, fmt.Errorf("无效的 --image-registry-map %s (正确格式: old=new，如 docker.io=registry.cn-hangzhou.aliyuncs.com)", value)
+		}
+		registryMap[oldPrefix] = newPrefix
+	}
+	return registryMap, nil
+}
+
+// displayRegistryRewrites lists the images rewritten by --image-registry-map
+func displayRegistryRewrites(rewritten map[string]string) {
+	if len(rewritten) == 0 {
+		return
+	}
+
+	oldImages := make([]string, 0, len(rewritten))
+	for oldImage := range rewritten {
+		oldImages = append(oldImages, oldImage)
+	}
+	sort.Strings(oldImages)
+
+	ui.PrintInfo(fmt.Sprintf("🔁 已替换 %d 个镜像的仓库前缀:", len(oldImages)))
+	for _, oldImage := range oldImages {
+		ui.PrintItem(fmt.Sprintf("%s → %s", oldImage, rewritten[oldImage]))
+	}
+	ui.PrintEmptyLine()
+}
+
+// splitShellArgs splits a command line into arguments following shell quoting rules:
+// single quotes are literal, double quotes allow backslash escapes and a backslash outside quotes escapes the next character
+func splitShellArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("引号未闭合: %s", line)
+	}
+	if escaped {
+		return nil, fmt.Errorf("末尾存在未转义的反斜杠: %s", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// writeUpdateReport serialises the update results with run metadata as JSON
+func writeUpdateReport(path string, appendMode bool, startTime time.Time, composePaths []string, results []*types.UpdateResult) error {
+	report := types.UpdateReport{
+		StartedAt:    startTime,
+		Duration:     time.Since(startTime).String(),
+		ComposePaths: composePaths,
+		Results:      make([]types.UpdateReportEntry, 0, len(results)),
+	}
+
+	for _, result := range results {
+		report.Results = append(report.Results, result.ReportEntry())
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("打开报告文件失败: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("写入报告失败: %v", err)
+	}
+
+	return nil
+}
+
+// reachability 为镜像可访问性检查结果，为 nil 时不显示检查标记
+func displayDetailedScanResults(composeFiles []*types.ComposeFile, reachability map[string]error) {
+	ui.PrintSection("📋 详细信息")
+
+	parser := compose.NewParser()
+	dockerClient := docker.NewClient()
+	dockerAvailable := dockerClient.Connect() == nil
+	defer dockerClient.Close()
+
+	for i, cf := range composeFiles {
+		relPath, _ := filepath.Rel(".", cf.FilePath)
+
+		ui.PrintSubHeader(fmt.Sprintf("%d. %s (%s)", i+1, cf.ProjectName, relPath))
+
+		if len(cf.Services) == 0 {
+			ui.PrintWarning("  无服务定义")
+			ui.PrintEmptyLine()
+			continue
+		}
+
+		for serviceName, service := range cf.Services {
+			if service.Image != "" {
+				ui.PrintItem(fmt.Sprintf("  • %s: %s%s", serviceName, service.Image, formatReachability(reachability, service.Image)))
+				if verbose && dockerAvailable {
+					displayImageLabels(dockerClient, service.Image)
+				}
+			} else if service.Build != nil {
+				ui.PrintItem(fmt.Sprintf("  • %s: [构建镜像] %s", serviceName, service.Build.Context))
+			} else {
+				ui.PrintItem(fmt.Sprintf("  • %s: [未定义镜像]", serviceName))
+			}
+		}
+
+		volumes := parser.GetNamedVolumes(cf)
+		if len(volumes) > 0 {
+			ui.PrintItem("  💾 数据卷:")
+			for _, volumeName := range volumes {
+				ui.PrintItem(fmt.Sprintf("    • %s", describeVolume(dockerClient, dockerAvailable, compose.RuntimeProjectName(cf), volumeName)))
+			}
+		}
+		ui.PrintEmptyLine()
+	}
+}
+
+// displayImageGroups prints each image repository with the projects and services using it
+func displayImageGroups(usages []compose.ImageUsage, reachability map[string]error) {
+	ui.PrintSection("📦 按镜像分组")
+
+	if len(usages) == 0 {
+		ui.PrintWarning("  没有使用镜像的服务")
+		ui.PrintEmptyLine()
+		return
+	}
+
+	for _, usage := range usages {
+		ui.PrintSubHeader(fmt.Sprintf("%s (%d 个项目)", usage.Repository, usage.ProjectCount))
+		for _, entry := range usage.Entries {
+			ui.PrintItem(fmt.Sprintf("  • %s/%s: %s (%s)%s", entry.ProjectName, entry.Service, entry.Tag, displayPath(entry.FilePath), formatReachability(reachability, entry.Image)))
+		}
+		ui.PrintEmptyLine()
+	}
+}
+
+// runScanCount 只按文件名统计 Compose 文件数量并输出到标准输出，不解析 YAML，适用于 CI 脚本
+func runScanCount() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+	if pathsFile != "" {
+		if err := config.ApplyPathsFile(cfg, pathsFile); err != nil {
+			return err
+		}
+	}
+	if len(cfg.ComposePaths) == 0 {
+		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
+	}
+
+	scanner := compose.NewScanner()
+	scanner.SetSkipHidden(!includeHidden)
+	scanner.SetRecursive(scanRecursive)
+	count, err := scanner.CountComposeFiles(cfg.ComposePaths)
+	if err != nil {
+		return fmt.Errorf("扫描失败: %v", err)
+	}
+
+	fmt.Println(count)
+	return nil
+}
+
+// runScanInventory 扫描 Compose 文件并将服务清单以 YAML 输出到标准输出，不输出其他提示信息
+func runScanInventory() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	if len(composePaths) > 0 {
+		cfg.ComposePaths = composePaths
+	}
+	if pathsFile != "" {
+		if err := config.ApplyPathsFile(cfg, pathsFile); err != nil {
+			return err
+		}
+	}
+	if len(composeProfiles) > 0 {
+		cfg.ComposeProfiles = composeProfiles
+	}
+	if len(cfg.ComposePaths) == 0 {
+		return fmt.Errorf("未配置 Compose 文件路径，请在配置文件中设置 compose_paths 或使用 --paths 参数")
+	}
+
+	scanner := compose.NewScanner()
+	scanner.SetProjectNameOverrides(cfg.ProjectNameOverride)
+	scanner.SetEncoding(cfg.ComposeEncoding)
+	scanner.SetUseComposeLibrary(cfg.UseComposeLibrary)
+	scanner.SetVerbose(verbose)
+	scanner.SetProfiles(cfg.ComposeProfiles)
+	scanner.SetSkipHidden(!includeHidden)
+	scanner.SetRecursive(scanRecursive)
+	composeFiles, err := scanner.ScanComposeFiles(cfg.ComposePaths)
+	if err != nil {
+		return fmt.Errorf("扫描失败: %v", err)
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(scanner.BuildInventory(composeFiles)); err != nil {
+		return fmt.Errorf("输出 YAML 失败: %v", err)
+	}
+	return encoder.Close()
+}
+
+// runListTags 列出镜像仓库中匹配过滤模式的标签
+func runListTags(image, pattern string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+
+	ui.PrintEmptyLine()
+	ui.PrintInfo(fmt.Sprintf("🏷️  正在获取 %s 的标签...", image))
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	tags, err := imageManager.ListTagsMatchingPattern(image, pattern)
+	if err != nil {
+		return fmt.Errorf("获取标签失败: %v", err)
+	}
+
 	ui.PrintEmptyLine()
+	if len(tags) == 0 {
+		ui.PrintWarning("没有匹配的标签")
+		ui.PrintEmptyLine()
+		return nil
+	}
 
+	for _, tag := range tags {
+		ui.PrintItem(fmt.Sprintf("  • %s", tag))
+	}
+	ui.PrintEmptyLine()
+	ui.PrintInfo(fmt.Sprintf("共 %d 个标签", len(tags)))
+	ui.PrintEmptyLine()
 	return nil
 }
 
-func displayUpdateResults(results []*types.UpdateResult) {
-	successCount := 0
-	failureCount := 0
-	skippedCount := 0
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := strings.TrimSpace(args[0])
+	if query == "" {
+		return fmt.Errorf("搜索关键字不能为空")
+	}
+	if searchLimit <= 0 {
+		return fmt.Errorf("--limit 必须大于 0")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
 
 	ui.PrintEmptyLine()
-	ui.PrintSuccess("✅ 更新完成！")
+	ui.PrintInfo(fmt.Sprintf("🔍 正在 Docker Hub 中搜索 %s...", query))
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	results, err := imageManager.SearchDockerHub(query, searchLimit)
+	if err != nil {
+		return fmt.Errorf("搜索镜像失败: %v", err)
+	}
+
 	ui.PrintEmptyLine()
+	if len(results) == 0 {
+		ui.PrintWarning("没有找到匹配的镜像")
+		ui.PrintEmptyLine()
+		return nil
+	}
 
-	for _, result := range results {
-		if result.Success {
-			successCount++
-		} else if result.Error != nil {
-			failureCount++
-		} else {
-			skippedCount++
+	rows := make([][]string, len(results))
+	for i, result := range results {
+		official := ""
+		if result.Official {
+			official = "✓"
+		}
+		rows[i] = []string{
+			fmt.Sprintf("%d", i+1),
+			result.Name,
+			ui.TruncateString(result.Description, 50),
+			fmt.Sprintf("%d", result.Stars),
+			formatCount(result.Pulls),
+			official,
 		}
 	}
+	ui.PrintTable([]string{"序号", "镜像", "描述", "星标", "拉取次数", "官方"}, rows)
+	ui.PrintEmptyLine()
+
+	choice, err := ui.Input("输入序号将镜像添加到 Compose 文件 (直接回车跳过)", "")
+	if err != nil || choice == "" {
+		return nil
+	}
+	index, err := strconv.Atoi(choice)
+	if err != nil || index < 1 || index > len(results) {
+		return fmt.Errorf("无效的序号: %s", choice)
+	}
+
+	return addImageToCompose(cfg, results[index-1].Name)
+}
+
+// addImageToCompose prompts for a compose file, service name and tag, then adds
+// the image as a new service. Existing services are never overwritten.
+func addImageToCompose(cfg *types.Config, image string) error {
+	input, err := ui.Input("Compose 文件路径", "")
+	if err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("Compose 文件路径不能为空")
+	}
+	filePath, err := normalizeComposePath(input)
+	if err != nil {
+		return err
+	}
+
+	parser := compose.NewParser()
+	parser.SetEncoding(cfg.ComposeEncoding)
+	cf, err := parser.ParseFile(filePath)
+	if err != nil {
+		return fmt.Errorf("解析 Compose 文件失败: %v", err)
+	}
+
+	defaultName := image[strings.LastIndex(image, "/")+1:]
+	serviceName, err := ui.Input("服务名称", defaultName)
+	if err != nil {
+		return err
+	}
+	if _, exists := cf.Services[serviceName]; exists {
+		return fmt.Errorf("服务 %s 已存在于 %s", serviceName, filePath)
+	}
+
+	tag, err := ui.Input("镜像标签", "latest")
+	if err != nil {
+		return err
+	}
+	if tag == "" || strings.ContainsAny(tag, " :/@") {
+		return fmt.Errorf("无效的镜像标签: %q", tag)
+	}
+
+	if cf.Services == nil {
+		cf.Services = make(map[string]types.Service)
+	}
+	cf.Services[serviceName] = types.Service{Image: image + ":" + tag}
+
+	if dryRun {
+		ui.PrintEmptyLine()
+		ui.PrintInfo(fmt.Sprintf("[干运行] 将在 %s 中添加服务 %s (%s:%s)", filePath, serviceName, image, tag))
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	if err := parser.WriteFileAtomic(cf, filePath); err != nil {
+		return fmt.Errorf("写入 Compose 文件失败: %v", err)
+	}
 
-	// 显示统计信息，与README.md格式一致
-	ui.PrintInfo(fmt.Sprintf("- 成功更新: %s 个镜像", color.GreenString("%d", successCount)))
-	ui.PrintInfo(fmt.Sprintf("- 跳过: %s 个镜像", color.YellowString("%d", skippedCount)))
-	ui.PrintInfo(fmt.Sprintf("- 失败: %s 个镜像", color.RedString("%d", failureCount)))
 	ui.PrintEmptyLine()
+	ui.PrintSuccess(fmt.Sprintf("已在 %s 中添加服务 %s (%s:%s)", filePath, serviceName, image, tag))
+	ui.PrintEmptyLine()
+	return nil
 }
 
-func displayDetailedScanResults(composeFiles []*types.ComposeFile) {
-	ui.PrintSection("📋 详细信息")
+// formatCount formats large counts with K/M/B suffixes, e.g. 1.2M
+func formatCount(n int) string {
+	switch {
+	case n >= 1000000000:
+		return fmt.Sprintf("%.1fB", float64(n)/1000000000)
+	case n >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fK", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
 
-	for i, cf := range composeFiles {
-		dir := filepath.Dir(cf.FilePath)
-		projectName := filepath.Base(dir)
-		relPath, _ := filepath.Rel(".", cf.FilePath)
+// imageAge is a row of the scan --age-report table
+type imageAge struct {
+	project     string
+	service     string
+	image       string
+	latestImage string
+	current     time.Time
+	latest      time.Time
+	gapDays     int
+	err         error
+}
 
-		ui.PrintSubHeader(fmt.Sprintf("%d. %s (%s)", i+1, projectName, relPath))
+// ageWarningDays and ageCriticalDays are the gaps (in days) highlighted in yellow and red
+const (
+	ageWarningDays  = 90
+	ageCriticalDays = 180
+)
 
-		if len(cf.Services) == 0 {
-			ui.PrintWarning("  无服务定义")
-			ui.PrintEmptyLine()
+// displayAgeReport queries the registry for the build time of each service's current image
+// and of the latest tag chosen by the configured strategy, then prints the gap sorted descending
+func displayAgeReport(cfg *types.Config, composeFiles []*types.ComposeFile) error {
+	tagStrategyImpl, err := strategy.NewFromString(cfg.ImageTagStrategy, cfg)
+	if err != nil {
+		return err
+	}
+	_, isSemver := tagStrategyImpl.(*strategy.SemverStrategy)
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), cfg.RegistryAPITimeout)
+	if err := imageManager.SetPlatform(cfg.Platform); err != nil {
+		return err
+	}
+
+	ui.PrintInfo(fmt.Sprintf("📅 正在使用 %s 策略查询镜像构建时间...", cfg.ImageTagStrategy))
+
+	// 按镜像查询，多个服务使用同一镜像时只请求一次
+	var ages []*imageAge
+	byImage := make(map[string][]*imageAge)
+	for _, cf := range composeFiles {
+		for serviceName, service := range cf.Services {
+			if service.Image == "" || isExcludedImage(service.Image, cfg.ExcludeImages) {
+				continue
+			}
+			age := &imageAge{project: cf.ProjectName, service: serviceName, image: service.Image}
+			ages = append(ages, age)
+			byImage[service.Image] = append(byImage[service.Image], age)
+		}
+	}
+	if len(ages) == 0 {
+		ui.PrintWarning("没有可检查的镜像服务")
+		ui.PrintEmptyLine()
+		return nil
+	}
+
+	parallel := cfg.MaxParallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+	for image, entries := range byImage {
+		wg.Add(1)
+		go func(image string, entries []*imageAge) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result := imageAge{}
+			result.current, result.err = imageManager.GetImageCreated(image)
+			if result.err == nil {
+				var latestTag string
+				latestTag, result.err = tagStrategyImpl.GetLatestTag(image)
+				if result.err == nil && isSemver {
+					latestTag, result.err = strategy.FormatTag(cfg.TagFormat, latestTag)
+				}
+				if result.err == nil {
+					repository, _ := splitImageTag(image)
+					result.latestImage = repository + ":" + latestTag
+					result.latest, result.err = imageManager.GetImageCreated(result.latestImage)
+				}
+			}
+			if result.err == nil && result.latest.After(result.current) {
+				result.gapDays = int(result.latest.Sub(result.current).Hours() / 24)
+			}
+
+			// 每个条目只由处理该镜像的 goroutine 写入
+			for _, entry := range entries {
+				entry.latestImage = result.latestImage
+				entry.current = result.current
+				entry.latest = result.latest
+				entry.gapDays = result.gapDays
+				entry.err = result.err
+			}
+		}(image, entries)
+	}
+	wg.Wait()
+
+	// 按落后天数降序排列，查询失败的服务排在最后
+	sort.SliceStable(ages, func(i, j int) bool {
+		if (ages[i].err == nil) != (ages[j].err == nil) {
+			return ages[i].err == nil
+		}
+		if ages[i].gapDays != ages[j].gapDays {
+			return ages[i].gapDays > ages[j].gapDays
+		}
+		if ages[i].project != ages[j].project {
+			return ages[i].project < ages[j].project
+		}
+		return ages[i].service < ages[j].service
+	})
+
+	rows := make([][]string, 0, len(ages))
+	failed := 0
+	behind := 0
+	for _, age := range ages {
+		if age.err != nil {
+			failed++
+			ui.Debug(age.err.Error(), verbose)
+			rows = append(rows, []string{age.project, age.service, age.image, "-", "-", "-", color.RedString("查询失败")})
 			continue
 		}
 
-		for serviceName, service := range cf.Services {
+		gap := fmt.Sprintf("%d", age.gapDays)
+		switch {
+		case age.gapDays > ageCriticalDays:
+			gap = color.RedString("%s", gap)
+		case age.gapDays > ageWarningDays:
+			gap = color.YellowString("%s", gap)
+		}
+		if age.gapDays > ageWarningDays {
+			behind++
+		}
+		rows = append(rows, []string{
+			age.project,
+			age.service,
+			age.image,
+			age.current.Format("2006-01-02"),
+			age.latestImage,
+			age.latest.Format("2006-01-02"),
+			gap,
+		})
+	}
+
+	ui.PrintSection("📅 镜像年龄报告")
+	ui.PrintTable([]string{"项目名称", "服务", "当前镜像", "当前镜像构建日期", "最新镜像", "最新镜像构建日期", "落后天数"}, rows)
+	ui.PrintInfo(fmt.Sprintf("共 %d 个服务，%s 个落后超过 %d 天，%s 个查询失败",
+		len(ages), color.YellowString("%d", behind), ageWarningDays, color.RedString("%d", failed)))
+	ui.PrintEmptyLine()
+	return nil
+}
+
+// checkImageReachability 并发检查所有不重复的镜像是否可以从镜像仓库访问
+// 返回 镜像 → 检查错误 的映射，可访问的镜像对应 nil
+func checkImageReachability(composeFiles []*types.ComposeFile, parallel int, timeout time.Duration) map[string]error {
+	imageSet := make(map[string]bool)
+	for _, cf := range composeFiles {
+		for _, service := range cf.Services {
 			if service.Image != "" {
-				ui.PrintItem(fmt.Sprintf("  • %s: %s", serviceName, service.Image))
-			} else if service.Build != nil {
-				ui.PrintItem(fmt.Sprintf("  • %s: [构建镜像] %s", serviceName, service.Build.Context))
-			} else {
-				ui.PrintItem(fmt.Sprintf("  • %s: [未定义镜像]", serviceName))
+				imageSet[service.Image] = true
+			}
+		}
+	}
+
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), timeout)
+	results := make(map[string]error, len(imageSet))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+
+	for image := range imageSet {
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			exists, err := imageManager.ValidateImageExists(image)
+			if err == nil && !exists {
+				err = fmt.Errorf("镜像仓库中没有可用的标签")
+			}
+
+			mutex.Lock()
+			results[image] = err
+			mutex.Unlock()
+		}(image)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// formatReachability 返回镜像可访问性标记，未检查时为空
+func formatReachability(reachability map[string]error, image string) string {
+	if reachability == nil {
+		return ""
+	}
+	err, checked := reachability[image]
+	if !checked {
+		return ""
+	}
+	if err != nil {
+		return " " + color.RedString("✗")
+	}
+	return " " + color.GreenString("✓")
+}
+
+// displayServiceTrees prints the depends_on hierarchy of each compose file as a tree
+func displayServiceTrees(composeFiles []*types.ComposeFile) {
+	ui.PrintSection("🌳 服务依赖关系")
+	for _, cf := range composeFiles {
+		tree, err := buildServiceTree(cf)
+		if err != nil {
+			ui.PrintWarning(fmt.Sprintf("%s: %v", cf.FilePath, err))
+			ui.PrintEmptyLine()
+			continue
+		}
+		ui.PrintTree(tree)
+		ui.PrintEmptyLine()
+	}
+}
+
+// buildServiceTree builds a tree rooted at the project from the dependency groups of the file.
+// Services without dependencies hang off the root; every other service is expanded once under its
+// first dependency, and each further dependency only gets a reference leaf naming where it is expanded.
+func buildServiceTree(cf *types.ComposeFile) (*ui.TreeNode, error) {
+	graph, err := compose.BuildDependencyGraph(cf)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &ui.TreeNode{Label: fmt.Sprintf("%s (%s)", cf.ProjectName, cf.FilePath)}
+	nodes := make(map[string]*ui.TreeNode, len(cf.Services))
+	for _, group := range graph.IndependentGroups() {
+		for _, serviceName := range group {
+			service := cf.Services[serviceName]
+
+			// 依赖的服务位于之前的层级，已经创建了节点
+			var parents []*ui.TreeNode
+			var parentNames []string
+			dependencies, _ := compose.ParseDependsOn(service.DependsOn)
+			for _, dependency := range dependencies {
+				if parent, exists := nodes[dependency.Service]; exists {
+					parents = append(parents, parent)
+					parentNames = append(parentNames, dependency.Service)
+				}
+			}
+			if len(parents) == 0 {
+				parents = []*ui.TreeNode{root}
+			}
+
+			// 只在第一个依赖下展开服务，其余依赖下添加指向展开位置的引用节点，避免子树重复展开
+			node := serviceTreeNode(serviceName, service)
+			parents[0].Children = append(parents[0].Children, node)
+			nodes[serviceName] = node
+			for _, parent := range parents[1:] {
+				label := fmt.Sprintf("%s (见 %s 下)", serviceName, parentNames[0])
+				parent.Children = append(parent.Children, &ui.TreeNode{Label: label})
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// serviceTreeNode creates the node of a service with its image and volumes as children
+func serviceTreeNode(serviceName string, service types.Service) *ui.TreeNode {
+	node := &ui.TreeNode{Label: serviceName, Type: ui.TreeNodeService}
+	if service.Image != "" {
+		node.Children = append(node.Children, &ui.TreeNode{Label: service.Image, Type: ui.TreeNodeImage})
+	}
+	for _, volume := range service.Volumes {
+		node.Children = append(node.Children, &ui.TreeNode{Label: volume, Type: ui.TreeNodeVolume})
+	}
+	return node
+}
+
+// displayReachabilitySummary 显示镜像可访问性检查汇总
+func displayReachabilitySummary(reachability map[string]error) {
+	var unreachable []string
+	for image, err := range reachability {
+		if err != nil {
+			unreachable = append(unreachable, image)
+		}
+	}
+	sort.Strings(unreachable)
+
+	ui.PrintSection("🌐 镜像可访问性")
+	ui.PrintInfo(fmt.Sprintf("共检查 %d 个镜像，%s 个可访问，%s 个不可访问",
+		len(reachability),
+		color.GreenString("%d", len(reachability)-len(unreachable)),
+		color.RedString("%d", len(unreachable))))
+
+	for _, image := range unreachable {
+		ui.PrintItem(fmt.Sprintf("  %s %s: %v", color.RedString("✗"), image, reachability[image]))
+	}
+	ui.PrintEmptyLine()
+}
+
+// displayScanStats prints aggregate statistics across all compose files
+func displayScanStats(stats *types.ScanStats) {
+	ui.PrintSection("📊 统计汇总")
+
+	ui.PrintItem(fmt.Sprintf("Compose 文件: %d", stats.TotalFiles))
+	ui.PrintItem(fmt.Sprintf("服务总数: %d (平均每个文件 %.1f 个)", stats.TotalServices, stats.AvgServicesPerFile))
+	if stats.BuildOnlyServices > 0 {
+		ui.PrintItem(fmt.Sprintf("仅构建的服务: %d", stats.BuildOnlyServices))
+	}
+	ui.PrintItem(fmt.Sprintf("不重复镜像: %d", stats.UniqueImages))
+	ui.PrintItem(fmt.Sprintf("  • 使用 latest 标签: %s", color.YellowString("%d", stats.LatestTagImages)))
+	ui.PrintItem(fmt.Sprintf("  • 使用语义版本标签: %s", color.GreenString("%d", stats.SemverTagImages)))
+	ui.PrintItem(fmt.Sprintf("  • 官方镜像: %d", stats.OfficialImages))
+	ui.PrintItem(fmt.Sprintf("  • 第三方镜像: %d", stats.ThirdPartyImages))
+
+	if len(stats.ByRegistry) > 0 {
+		ui.PrintEmptyLine()
+		ui.PrintSubHeader("镜像仓库")
+		registries := make([]string, 0, len(stats.ByRegistry))
+		for registry := range stats.ByRegistry {
+			registries = append(registries, registry)
+		}
+		// 按镜像数量降序，数量相同时按仓库地址排序
+		sort.Slice(registries, func(i, j int) bool {
+			ci, cj := stats.ByRegistry[registries[i]], stats.ByRegistry[registries[j]]
+			if ci != cj {
+				return ci > cj
 			}
+			return registries[i] < registries[j]
+		})
+
+		table := ui.NewTable().
+			Column("镜像仓库", ui.Left).
+			Column("镜像数量", ui.Right)
+		for _, registry := range registries {
+			table.AddRow(registry, fmt.Sprintf("%d", stats.ByRegistry[registry]))
 		}
+		table.Print()
+	}
+
+	if stats.LatestTagImages > 0 {
+		ui.PrintEmptyLine()
+		ui.PrintWarning(fmt.Sprintf("%d 个镜像使用 latest 标签，更新结果不可预测，建议固定版本", stats.LatestTagImages))
+	}
+	ui.PrintEmptyLine()
+}
+
+// displayPortConflicts prints host port conflicts between services
+func displayPortConflicts(conflicts []compose.PortConflict) {
+	ui.PrintEmptyLine()
+	ui.PrintSection("🔌 端口冲突检查")
+
+	if len(conflicts) == 0 {
+		ui.PrintSuccess("✅ 未发现宿主机端口冲突")
+		ui.PrintEmptyLine()
+		return
+	}
+
+	rows := make([][]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		rows = append(rows, []string{
+			conflict.Port,
+			fmt.Sprintf("%s (%s)", conflict.Service1, displayPath(conflict.File1)),
+			fmt.Sprintf("%s (%s)", conflict.Service2, displayPath(conflict.File2)),
+		})
+	}
+	ui.PrintTable([]string{"端口", "服务", "冲突服务"}, rows)
+	ui.PrintWarning(fmt.Sprintf("⚠️  发现 %d 个端口冲突，后启动的项目将无法绑定这些端口", len(conflicts)))
+	ui.PrintEmptyLine()
+}
+
+// displayInvalidImages prints services whose image names are not valid image references
+func displayInvalidImages(invalid []compose.InvalidImage) {
+	ui.PrintEmptyLine()
+	ui.PrintSection("🏷️  镜像名称检查")
+
+	if len(invalid) == 0 {
+		ui.PrintSuccess("✅ 所有镜像名称格式正确")
 		ui.PrintEmptyLine()
+		return
+	}
+
+	rows := make([][]string, 0, len(invalid))
+	for _, image := range invalid {
+		rows = append(rows, []string{displayPath(image.File), image.Service, fmt.Sprintf("%q", image.Image), image.Reason})
+	}
+	ui.PrintTable([]string{"文件", "服务", "镜像", "原因"}, rows)
+	ui.PrintEmptyLine()
+}
+
+// displayPath returns the path relative to the working directory when possible
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if relPath, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(relPath, "..") {
+		return relPath
+	}
+	return path
+}
+
+// displayLintResults prints style and best-practice issues for each compose file
+func displayLintResults(composeFiles []*types.ComposeFile) {
+	ui.PrintSection("🔎 规范检查")
+
+	linter := compose.NewLinter()
+	totalIssues := 0
+
+	for _, cf := range composeFiles {
+		issues := linter.Lint(cf)
+		if len(issues) == 0 {
+			continue
+		}
+		totalIssues += len(issues)
+
+		relPath, err := filepath.Rel(".", cf.FilePath)
+		if err != nil {
+			relPath = cf.FilePath
+		}
+		ui.PrintSubHeader(fmt.Sprintf("%s (%d 个问题)", relPath, len(issues)))
+		for _, issue := range issues {
+			if issue.Severity == compose.LintWarning {
+				ui.PrintItem(fmt.Sprintf("  %s [%s] %s", color.YellowString("warning"), issue.Service, issue.Message))
+			} else {
+				ui.PrintItem(fmt.Sprintf("  %s [%s] %s", color.CyanString("info"), issue.Service, issue.Message))
+			}
+		}
+	}
+
+	ui.PrintEmptyLine()
+	if totalIssues == 0 {
+		ui.PrintSuccess("未发现规范问题")
+	} else {
+		ui.PrintWarning(fmt.Sprintf("共发现 %d 个规范问题", totalIssues))
+	}
+	ui.PrintEmptyLine()
+}
+
+// ociImageLabels 详细模式下显示的 OCI 标准镜像标签及其说明
+var ociImageLabels = []struct {
+	key   string
+	title string
+}{
+	{"org.opencontainers.image.source", "源码"},
+	{"org.opencontainers.image.description", "描述"},
+	{"org.opencontainers.image.vendor", "提供方"},
+}
+
+// displayImageLabels 显示本地镜像的 OCI 标准标签，镜像不在本地时不显示
+func displayImageLabels(dockerClient *docker.Client, image string) {
+	labels, err := dockerClient.GetImageLabels(image)
+	if err != nil {
+		return
+	}
+
+	for _, label := range ociImageLabels {
+		if value := labels[label.key]; value != "" {
+			ui.PrintItem(fmt.Sprintf("      %s: %s", label.title, value))
+		}
 	}
 }
 
-func formatSize(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// describeVolume returns a short status line for a named volume referenced by a compose project
+func describeVolume(dockerClient *docker.Client, dockerAvailable bool, projectName, volumeName string) string {
+	if !dockerAvailable {
+		return fmt.Sprintf("%s (无法连接 Docker)", volumeName)
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+
+	// Docker Compose 默认以 "<项目名>_<卷名>" 创建数据卷
+	candidates := []string{strings.ToLower(projectName) + "_" + volumeName, volumeName}
+	for _, candidate := range candidates {
+		info, err := dockerClient.GetVolumeInfo(candidate)
+		if info == nil {
+			continue
+		}
+
+		// 数据卷存在但检查使用状态失败时，使用状态和大小都无法确定
+		if err != nil {
+			return fmt.Sprintf("%s → %s (%s, 大小未知)", volumeName, info.Name, color.YellowString("使用状态未知"))
+		}
+
+		status := color.YellowString("未使用")
+		if info.InUse {
+			status = color.GreenString("使用中")
+		}
+		if info.Size >= 0 {
+			return fmt.Sprintf("%s → %s (%s, %s)", volumeName, info.Name, status, ui.FormatSize(info.Size))
+		}
+		return fmt.Sprintf("%s → %s (%s)", volumeName, info.Name, status)
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+
+	return fmt.Sprintf("%s (%s)", volumeName, color.RedString("不存在"))
 }
 
 func main() {
@@ -470,23 +3564,31 @@ func main() {
 	}
 }
 
+// 文件列表中端口列的显示条件和最大长度
+const (
+	portsColumnMinWidth  = 120
+	portsColumnMaxLength = 30
+)
+
 // displayComposeList shows all found compose files with numbering
 func displayComposeList(composeFiles []*types.ComposeFile) {
 	ui.PrintEmptyLine()
 	ui.PrintSection("🔍 发现的 Docker Compose 文件")
 
-	headers := []string{"序号", "项目名称", "文件路径", "服务数量", "镜像服务"}
-	var rows [][]string
+	table := ui.NewTable().
+		Column("序号", ui.Right).
+		Column("项目名称", ui.Left).
+		Column("文件路径", ui.Left).
+		Column("服务数量", ui.Right).
+		Column("镜像服务", ui.Left)
+
+	// 端口列较宽，窄终端下不显示以免挤压其他列
+	withPorts := showPorts && ui.TerminalWidth() >= portsColumnMinWidth
+	if withPorts {
+		table.Column("端口", ui.Left)
+	}
 
 	for i, cf := range composeFiles {
-		// 提取项目名称（文件所在目录名）
-		dir := filepath.Dir(cf.FilePath)
-		projectName := filepath.Base(dir)
-		if projectName == "." || projectName == "/" {
-			projectName = filepath.Base(cf.FilePath)
-			projectName = strings.TrimSuffix(projectName, filepath.Ext(projectName))
-		}
-
 		// 统计有镜像的服务
 		imageServices := []string{}
 		for serviceName, service := range cf.Services {
@@ -498,16 +3600,23 @@ func displayComposeList(composeFiles []*types.ComposeFile) {
 		// 相对路径显示
 		relPath, _ := filepath.Rel(".", cf.FilePath)
 
-		rows = append(rows, []string{
+		row := []string{
 			fmt.Sprintf("%d", i+1),
-			projectName,
+			cf.ProjectName,
 			relPath,
 			fmt.Sprintf("%d", len(cf.Services)),
 			strings.Join(imageServices, ", "),
-		})
+		}
+		if withPorts {
+			row = append(row, ui.TruncateString(strings.Join(compose.HostPorts(cf), ", "), portsColumnMaxLength))
+		}
+		table.AddRow(row...)
 	}
 
-	ui.PrintTable(headers, rows)
+	table.Print()
+	if showPorts && !withPorts {
+		ui.PrintInfo(fmt.Sprintf("💡 终端宽度小于 %d，未显示端口列", portsColumnMinWidth))
+	}
 	ui.PrintEmptyLine()
 	ui.PrintInfo("💡 使用方法:")
 	ui.PrintItem("• 运行 'compman update' 进入交互模式")
@@ -592,8 +3701,6 @@ func parseIndex(indexStr string, maxCount int) (int, error) {
 
 // interactiveSelectCompose provides interactive selection of compose files
 func interactiveSelectCompose(allFiles []*types.ComposeFile) ([]*types.ComposeFile, error) {
-	reader := bufio.NewReader(os.Stdin)
-
 	for {
 		ui.PrintEmptyLine()
 		ui.PrintInfo("🎯 请选择要更新的 Compose 文件:")
@@ -602,12 +3709,10 @@ func interactiveSelectCompose(allFiles []*types.ComposeFile) ([]*types.ComposeFi
 		ui.PrintItem("• 输入 'q' 退出")
 		ui.PrintEmptyLine()
 
-		fmt.Print("请输入选择: ")
-		input, err := reader.ReadString('\n')
+		input, err := ui.Input("请输入选择", "")
 		if err != nil {
 			return nil, fmt.Errorf("读取输入失败: %v", err)
 		}
-		input = strings.TrimSpace(input)
 
 		if input == "" {
 			ui.PrintEmptyLine()
@@ -638,14 +3743,13 @@ func interactiveSelectCompose(allFiles []*types.ComposeFile) ([]*types.ComposeFi
 
 				// 显示选中的文件
 				for i, cf := range selectedFiles {
-					dir := filepath.Dir(cf.FilePath)
-					projectName := filepath.Base(dir)
 					relPath, _ := filepath.Rel(".", cf.FilePath)
-					ui.PrintItem(fmt.Sprintf("%d. %s (%s)", i+1, projectName, relPath))
+					ui.PrintItem(fmt.Sprintf("%d. %s (%s)", i+1, cf.ProjectName, relPath))
 				}
 
 				ui.PrintEmptyLine()
-				if ui.Confirm("确认更新以上文件?") {
+				// 刚刚完成选择，直接回车即确认
+				if ui.ConfirmWithDefault("确认更新以上文件?", true) {
 					return selectedFiles, nil
 				} else {
 					ui.PrintEmptyLine()
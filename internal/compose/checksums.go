@@ -0,0 +1,122 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"compman/pkg/types"
+)
+
+// fileChecksum 返回文件原始内容的 SHA-256 十六进制摘要
+func fileChecksum(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checksumsPath 返回校验和文件路径，优先使用 XDG_DATA_HOME，默认为 ~/.local/share/compman/checksums.json
+func checksumsPath() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "compman", "checksums.json"), nil
+}
+
+// LoadChecksums 读取上次成功更新时记录的校验和 (文件绝对路径 -> 校验和)，文件不存在时返回空映射
+func LoadChecksums() (map[string]string, error) {
+	path, err := checksumsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checksums, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取校验和文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		return nil, fmt.Errorf("解析校验和文件失败: %v", err)
+	}
+	return checksums, nil
+}
+
+// saveChecksums 保存校验和
+func saveChecksums(checksums map[string]string) error {
+	path, err := checksumsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RecordChecksums 按文件当前内容更新记录的校验和，用于更新成功后标记文件为未变化
+func RecordChecksums(filePaths []string) error {
+	if len(filePaths) == 0 {
+		return nil
+	}
+
+	checksums, err := LoadChecksums()
+	if err != nil {
+		return err
+	}
+	for _, filePath := range filePaths {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return err
+		}
+		checksum, err := fileChecksum(absPath)
+		if err != nil {
+			return err
+		}
+		checksums[absPath] = checksum
+	}
+	return saveChecksums(checksums)
+}
+
+// ScanChangedFiles 扫描指定路径，只返回内容与上次成功更新时记录的校验和不同的文件，未记录过的文件视为已变化
+func (s *Scanner) ScanChangedFiles(paths []string) ([]*types.ComposeFile, error) {
+	composeFiles, err := s.ScanComposeFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := LoadChecksums()
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []*types.ComposeFile
+	for _, composeFile := range composeFiles {
+		absPath, err := filepath.Abs(composeFile.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		if checksums[absPath] != composeFile.Checksum {
+			changed = append(changed, composeFile)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,120 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ExtractComments 逐行扫描文件，提取其中的 YAML 注释
+// 返回 行号(从 1 开始) → 注释 的映射；整行注释保留原始缩进，行尾注释只保留 # 及其后的内容
+// | 和 > 块标量中以 # 开头的行是字符串内容而不是注释，不会被提取；文件无法读取时返回空映射
+func (p *Parser) ExtractComments(filePath string) map[string]string {
+	comments := make(map[string]string)
+
+	content, err := p.readFile(filePath)
+	if err != nil {
+		return comments
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	inBlock := blockScalarLines(lines)
+	for i, line := range lines {
+		lineNumber := i + 1
+		if inBlock[i] {
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			comments[strconv.Itoa(lineNumber)] = strings.TrimRight(line, " \t")
+			continue
+		}
+
+		if idx := findInlineComment(line); idx >= 0 {
+			comments[strconv.Itoa(lineNumber)] = strings.TrimSpace(line[idx:])
+		}
+	}
+
+	return comments
+}
+
+// blockScalarLines 标记属于 | 或 > 块标量内容的行，这些行即使以 # 开头也是字符串的一部分
+// 块标量从以 | 或 > (可带 +、- 和缩进指示符) 结尾的键或列表项开始，到缩进不大于该行的非空行结束
+func blockScalarLines(lines []string) []bool {
+	inBlock := make([]bool, len(lines))
+	blockIndent := -1
+	for i, line := range lines {
+		if blockIndent >= 0 {
+			if strings.TrimSpace(line) == "" || len(leadingWhitespace(line)) > blockIndent {
+				inBlock[i] = true
+				continue
+			}
+			blockIndent = -1
+		}
+
+		if startsBlockScalar(stripComment(line)) {
+			blockIndent = len(leadingWhitespace(line))
+		}
+	}
+	return inBlock
+}
+
+// startsBlockScalar 判断去掉注释后的行是否以块标量指示符结尾，如 "command: |"、"- >-"
+func startsBlockScalar(content string) bool {
+	value := content
+	if strings.HasPrefix(value, "- ") {
+		value = strings.TrimSpace(value[2:])
+	}
+	if idx := strings.Index(value, ": "); idx > 0 {
+		value = strings.TrimSpace(value[idx+1:])
+	}
+
+	if value == "" || (value[0] != '|' && value[0] != '>') {
+		return false
+	}
+	return strings.Trim(value[1:], "+-0123456789") == ""
+}
+
+// stripComment 去掉行尾注释和首尾空白
+func stripComment(line string) string {
+	if idx := findInlineComment(line); idx >= 0 {
+		line = line[:idx]
+	}
+	return strings.TrimSpace(line)
+}
+
+// findInlineComment 返回行内注释 # 的位置，忽略引号中的 #，不存在时返回 -1
+func findInlineComment(line string) int {
+	inSingle, inDouble := false, false
+	for i, r := range line {
+		switch r {
+		case '\'':
+			if !inDouble {
+				inSingle = !inSingle
+			}
+		case '"':
+			if !inSingle {
+				inDouble = !inDouble
+			}
+		case '#':
+			if inSingle || inDouble {
+				continue
+			}
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// leadingWhitespace 返回行首的缩进
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,91 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileKeepsHashLinesInBlockScalars(t *testing.T) {
+	const original = `services:
+  app:
+    # 应用服务
+    image: alpine:3.18 # 固定版本
+    command: |
+      # setup step
+      echo hi
+    entrypoint:
+      - sh
+      - -c
+      - >-
+        # not a comment
+        run
+`
+	tests := []struct {
+		line  string
+		count int
+	}{
+		{line: "# 应用服务", count: 1},
+		{line: "# 固定版本", count: 1},
+		{line: "# setup step", count: 1},
+		{line: "# not a comment", count: 1},
+	}
+
+	filePath := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(filePath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser()
+	before, err := parser.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if err := parser.WriteFile(before, filePath); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	written, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if got := strings.Count(string(written), tt.line); got != tt.count {
+			t.Errorf("%q 出现 %d 次, want %d\n%s", tt.line, got, tt.count, written)
+		}
+	}
+
+	after, err := parser.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("重新解析写入的文件失败: %v", err)
+	}
+	if !reflect.DeepEqual(after.Services["app"].Command, before.Services["app"].Command) {
+		t.Errorf("command = %#v, want %#v", after.Services["app"].Command, before.Services["app"].Command)
+	}
+	if !reflect.DeepEqual(after.Services["app"].Other, before.Services["app"].Other) {
+		t.Errorf("entrypoint = %#v, want %#v", after.Services["app"].Other, before.Services["app"].Other)
+	}
+}
+
+func TestExtractCommentsSkipsBlockScalars(t *testing.T) {
+	const content = `services:
+  app:
+    command: |
+      # setup step
+      echo hi # not a comment either
+    # 真正的注释
+    image: alpine
+`
+	filePath := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewParser().ExtractComments(filePath)
+	want := map[string]string{"6": "    # 真正的注释"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractComments() = %#v, want %#v", got, want)
+	}
+}
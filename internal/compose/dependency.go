@@ -0,0 +1,105 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"compman/pkg/types"
+)
+
+// DependencyGraph 描述 Compose 文件中服务之间的 depends_on 依赖关系
+type DependencyGraph struct {
+	dependants map[string][]string // 服务 -> 依赖它的服务
+	groups     [][]string          // 按依赖层级划分的服务组
+}
+
+// BuildDependencyGraph 根据服务的 depends_on 构建依赖图，并使用 Kahn 算法进行拓扑排序
+// 指向文件中不存在的服务（如未启用的 profile）的依赖会被忽略；存在循环依赖时返回错误
+func BuildDependencyGraph(cf *types.ComposeFile) (*DependencyGraph, error) {
+	graph := &DependencyGraph{
+		dependants: make(map[string][]string),
+	}
+
+	inDegree := make(map[string]int, len(cf.Services))
+	for serviceName := range cf.Services {
+		inDegree[serviceName] = 0
+	}
+
+	for serviceName, service := range cf.Services {
+		dependencies, err := ParseDependsOn(service.DependsOn)
+		if err != nil {
+			return nil, fmt.Errorf("服务 %s 的 depends_on 无效: %v", serviceName, err)
+		}
+
+		seen := make(map[string]bool)
+		for _, d := range dependencies {
+			dependency := d.Service
+			if _, exists := cf.Services[dependency]; !exists || seen[dependency] {
+				continue
+			}
+			seen[dependency] = true
+
+			graph.dependants[dependency] = append(graph.dependants[dependency], serviceName)
+			inDegree[serviceName]++
+		}
+	}
+
+	// 每一轮取出所有入度为 0 的服务作为一个层级，同一层级的服务互不依赖
+	var current []string
+	for serviceName, degree := range inDegree {
+		if degree == 0 {
+			current = append(current, serviceName)
+		}
+	}
+
+	processed := 0
+	for len(current) > 0 {
+		sort.Strings(current)
+		graph.groups = append(graph.groups, current)
+		processed += len(current)
+
+		var next []string
+		for _, serviceName := range current {
+			for _, dependant := range graph.dependants[serviceName] {
+				inDegree[dependant]--
+				if inDegree[dependant] == 0 {
+					next = append(next, dependant)
+				}
+			}
+		}
+		current = next
+	}
+
+	if processed < len(cf.Services) {
+		var cyclic []string
+		for serviceName, degree := range inDegree {
+			if degree > 0 {
+				cyclic = append(cyclic, serviceName)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("服务之间存在循环依赖，无法排序的服务: %s", strings.Join(cyclic, ", "))
+	}
+
+	return graph, nil
+}
+
+// TopologicalOrder 返回拓扑排序后的服务列表，被依赖的服务排在前面
+func (g *DependencyGraph) TopologicalOrder() []string {
+	var order []string
+	for _, group := range g.groups {
+		order = append(order, group...)
+	}
+	return order
+}
+
+// IndependentGroups 返回按依赖层级划分的服务组
+// 每组中的服务互不依赖，可以并行拉取；组按顺序排列，前面的组不依赖后面的组
+func (g *DependencyGraph) IndependentGroups() [][]string {
+	groups := make([][]string, len(g.groups))
+	for i, group := range g.groups {
+		groups[i] = append([]string(nil), group...)
+	}
+	return groups
+}
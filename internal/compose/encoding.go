@@ -0,0 +1,61 @@
+package compose
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// SupportedEncodings 支持的 Compose 文件编码
+var SupportedEncodings = []string{"utf-8", "cp1252", "latin-1"}
+
+// utf8BOM UTF-8 字节顺序标记，部分 Windows 编辑器会在文件开头写入
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// lookupEncoding 返回编码名称对应的字符映射，UTF-8 返回 nil
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return nil, nil
+	case "cp1252", "windows-1252":
+		return charmap.Windows1252, nil
+	case "latin-1", "latin1", "iso-8859-1":
+		return charmap.ISO8859_1, nil
+	default:
+		return nil, fmt.Errorf("不支持的文件编码: %s (支持: %s)", name, strings.Join(SupportedEncodings, ", "))
+	}
+}
+
+// decodeContent 将指定编码的文件内容转换为 UTF-8，并去除开头的 UTF-8 BOM
+func decodeContent(content []byte, name string) ([]byte, error) {
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return bytes.TrimPrefix(content, utf8BOM), nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("按 %s 编码转换文件内容失败: %v", name, err)
+	}
+	return decoded, nil
+}
+
+// encodeContent 将 UTF-8 内容转换回指定编码，写回文件时保持原有编码
+func encodeContent(content []byte, name string) ([]byte, error) {
+	enc, err := lookupEncoding(name)
+	if err != nil || enc == nil {
+		return content, err
+	}
+
+	encoded, err := enc.NewEncoder().Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("内容包含 %s 编码无法表示的字符: %v", name, err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,138 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"compman/pkg/types"
+)
+
+// LintSeverity 表示检查问题的严重程度
+type LintSeverity string
+
+const (
+	// LintWarning 可能导致问题的配置
+	LintWarning LintSeverity = "warning"
+	// LintInfo 最佳实践建议
+	LintInfo LintSeverity = "info"
+)
+
+// LintIssue 表示一条风格或最佳实践问题
+type LintIssue struct {
+	Severity LintSeverity
+	Service  string
+	Message  string
+}
+
+// Linter 负责检查 Compose 文件的风格和最佳实践，与 ValidateFile 的语法检查相互独立
+type Linter struct{}
+
+// NewLinter 创建一个新的检查器
+func NewLinter() *Linter {
+	return &Linter{}
+}
+
+// Lint 检查 Compose 文件并返回发现的问题，按服务名排序
+func (l *Linter) Lint(cf *types.ComposeFile) []LintIssue {
+	var issues []LintIssue
+
+	serviceNames := make([]string, 0, len(cf.Services))
+	for serviceName := range cf.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	for _, serviceName := range serviceNames {
+		service := cf.Services[serviceName]
+		issues = append(issues, l.lintService(serviceName, service)...)
+	}
+
+	return issues
+}
+
+// lintService 检查单个服务
+func (l *Linter) lintService(name string, service types.Service) []LintIssue {
+	var issues []LintIssue
+
+	// 检查重启策略
+	if service.Restart == "" {
+		issues = append(issues, LintIssue{
+			Severity: LintWarning,
+			Service:  name,
+			Message:  "未设置 restart 策略，容器退出或主机重启后不会自动恢复",
+		})
+	}
+
+	// 检查镜像标签是否固定
+	if service.Image != "" && l.usesLatestTag(service.Image) {
+		issues = append(issues, LintIssue{
+			Severity: LintWarning,
+			Service:  name,
+			Message:  fmt.Sprintf("镜像 %s 未固定版本标签，建议使用明确的版本号", service.Image),
+		})
+	}
+
+	// 检查健康检查
+	if _, ok := service.Other["healthcheck"]; !ok {
+		issues = append(issues, LintIssue{
+			Severity: LintInfo,
+			Service:  name,
+			Message:  "未配置 healthcheck",
+		})
+	}
+
+	// 检查端口绑定
+	for _, port := range service.Ports {
+		if l.bindsAllInterfaces(port) {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Service:  name,
+				Message:  fmt.Sprintf("端口 %s 绑定到所有网络接口，如仅本机访问建议使用 127.0.0.1", formatPortEntry(port)),
+			})
+		}
+	}
+
+	// 检查环境变量格式
+	if _, ok := service.Environment.([]interface{}); ok {
+		issues = append(issues, LintIssue{
+			Severity: LintInfo,
+			Service:  name,
+			Message:  "environment 使用列表格式，建议改用 map 格式以提高可读性",
+		})
+	}
+
+	return issues
+}
+
+// usesLatestTag 检查镜像是否使用 latest 标签
+func (l *Linter) usesLatestTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return false // 使用摘要固定
+	}
+
+	lastSlash := strings.LastIndex(image, "/")
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon <= lastSlash {
+		return true // 未指定标签，默认为 latest
+	}
+
+	return image[lastColon+1:] == "latest"
+}
+
+// bindsAllInterfaces 检查端口映射是否绑定到所有网络接口（未指定主机地址或使用 0.0.0.0）
+func (l *Linter) bindsAllInterfaces(port interface{}) bool {
+	bindings, err := parsePortEntry(port)
+	if err != nil || len(bindings) == 0 {
+		return false
+	}
+	return isWildcardIP(bindings[0].hostIP)
+}
+
+// formatPortEntry 返回端口定义的可读形式，长格式显示为 published:target
+func formatPortEntry(port interface{}) string {
+	if long, ok := port.(map[string]interface{}); ok {
+		return fmt.Sprintf("%v:%v", long["published"], long["target"])
+	}
+	return fmt.Sprint(port)
+}
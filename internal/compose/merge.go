@@ -0,0 +1,352 @@
+package compose
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"compman/pkg/types"
+)
+
+// MergeComposeFiles 按 Docker Compose 的合并规则将 override 合并到 base，返回新的 ComposeFile，不修改参数
+// 标量字段 (image、restart、command 等) 由 override 替换；ports、extra_hosts、networks、profiles 等序列拼接并去重，
+// volumes 按容器内路径合并；labels、environment、build.args、depends_on 等映射按键合并，override 优先
+// 结果的 FilePath 和 ProjectName 沿用 base，base 未设置项目名称时使用 override 的
+func (p *Parser) MergeComposeFiles(base, override *types.ComposeFile) *types.ComposeFile {
+	if base == nil {
+		base = &types.ComposeFile{}
+	}
+	merged := *base
+	merged.Services = make(map[string]types.Service, len(base.Services))
+	for name, service := range base.Services {
+		merged.Services[name] = mergeService(types.Service{}, service)
+	}
+	merged.Networks = mergeInterfaceMaps(base.Networks, nil)
+	merged.Volumes = mergeInterfaceMaps(base.Volumes, nil)
+	merged.XLabels = mergeStringMaps(base.XLabels, nil)
+	if override == nil {
+		return &merged
+	}
+
+	if override.Version != "" {
+		merged.Version = override.Version
+	}
+	for name, service := range override.Services {
+		merged.Services[name] = mergeService(merged.Services[name], service)
+	}
+	merged.Networks = mergeInterfaceMaps(merged.Networks, override.Networks)
+	merged.Volumes = mergeInterfaceMaps(merged.Volumes, override.Volumes)
+	merged.XLabels = mergeStringMaps(merged.XLabels, override.XLabels)
+	if merged.ProjectName == "" {
+		merged.ProjectName = override.ProjectName
+	}
+
+	return &merged
+}
+
+// mergeService 合并单个服务的配置
+func mergeService(base, override types.Service) types.Service {
+	merged := types.Service{
+		Image:       firstNonEmpty(override.Image, base.Image),
+		Build:       mergeBuildConfig(base.Build, override.Build),
+		Environment: mergeEnvironment(base.Environment, override.Environment),
+		Ports:       appendUnique(base.Ports, override.Ports),
+		Volumes:     mergeVolumes(base.Volumes, override.Volumes),
+		DependsOn:   mergeDependsOn(base.DependsOn, override.DependsOn),
+		Networks:    appendUniqueStrings(base.Networks, override.Networks),
+		Restart:     firstNonEmpty(override.Restart, base.Restart),
+		ExtraHosts:  appendUniqueStrings(base.ExtraHosts, override.ExtraHosts),
+		Command:     base.Command,
+		Labels:      mergeStringMaps(base.Labels, override.Labels),
+		Profiles:    appendUniqueStrings(base.Profiles, override.Profiles),
+		Other:       mergeInterfaceMaps(base.Other, override.Other),
+	}
+	// command 整体替换，不拼接参数
+	if override.Command != nil {
+		merged.Command = override.Command
+	}
+	return merged
+}
+
+// mergeBuildConfig 合并构建配置，args 按键合并，其余字段由 override 替换
+func mergeBuildConfig(base, override *types.BuildConfig) *types.BuildConfig {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := &types.BuildConfig{}
+	if base != nil {
+		*merged = *base
+	} else {
+		base = &types.BuildConfig{}
+	}
+	if override == nil {
+		override = &types.BuildConfig{}
+	}
+
+	merged.Context = firstNonEmpty(override.Context, base.Context)
+	merged.Dockerfile = firstNonEmpty(override.Dockerfile, base.Dockerfile)
+	merged.Target = firstNonEmpty(override.Target, base.Target)
+	merged.Args = mergeStringMaps(base.Args, override.Args)
+	return merged
+}
+
+// mergeEnvironment 按变量名合并环境变量，override 优先
+// 两者都是列表形式时结果为 KEY=VALUE 列表 (保持 base 中的顺序，新增变量追加在后)，否则为映射
+func mergeEnvironment(base, override interface{}) interface{} {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	baseKeys, baseValues, baseIsList := environmentEntries(base)
+	overrideKeys, overrideValues, overrideIsList := environmentEntries(override)
+
+	keys := baseKeys
+	values := baseValues
+	for _, key := range overrideKeys {
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = overrideValues[key]
+	}
+
+	if (base == nil || baseIsList) && (override == nil || overrideIsList) {
+		list := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			if value := values[key]; value != nil {
+				list = append(list, key+"="+*value)
+			} else {
+				list = append(list, key)
+			}
+		}
+		return list
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value := values[key]; value != nil {
+			result[key] = *value
+		} else {
+			result[key] = nil
+		}
+	}
+	return result
+}
+
+// environmentEntries 将列表或映射形式的环境变量转换为有序的键和值，未赋值的变量对应 nil
+func environmentEntries(env interface{}) ([]string, map[string]*string, bool) {
+	var keys []string
+	values := make(map[string]*string)
+
+	set := func(key string, value *string) {
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+
+	switch e := env.(type) {
+	case []interface{}:
+		for _, item := range e {
+			key, value, hasValue := strings.Cut(fmt.Sprint(item), "=")
+			if hasValue {
+				set(key, &value)
+			} else {
+				set(key, nil)
+			}
+		}
+		return keys, values, true
+	case []string:
+		for _, item := range e {
+			key, value, hasValue := strings.Cut(item, "=")
+			if hasValue {
+				set(key, &value)
+			} else {
+				set(key, nil)
+			}
+		}
+		return keys, values, true
+	case map[string]interface{}:
+		names := make([]string, 0, len(e))
+		for key := range e {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			if e[key] == nil {
+				set(key, nil)
+			} else {
+				value := fmt.Sprint(e[key])
+				set(key, &value)
+			}
+		}
+	case map[string]string:
+		names := make([]string, 0, len(e))
+		for key := range e {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		for _, key := range names {
+			value := e[key]
+			set(key, &value)
+		}
+	}
+	return keys, values, false
+}
+
+// mergeVolumes 按容器内路径合并数据卷，override 中挂载到相同路径的条目替换 base 中的条目
+func mergeVolumes(base, override []string) []string {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := make([]string, 0, len(base)+len(override))
+	index := make(map[string]int, len(base)+len(override))
+	for _, volume := range append(append([]string{}, base...), override...) {
+		target := volumeTarget(volume)
+		if i, exists := index[target]; exists {
+			merged[i] = volume
+			continue
+		}
+		index[target] = len(merged)
+		merged = append(merged, volume)
+	}
+	return merged
+}
+
+// volumeTarget 返回短格式数据卷的容器内路径，匿名卷本身就是容器内路径
+func volumeTarget(volume string) string {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 {
+		return volume
+	}
+	return parts[1]
+}
+
+// mergeDependsOn 合并服务依赖，两者都是列表形式时结果为去重后的列表，否则为 服务名 -> {condition} 的映射
+// 列表中的依赖转换为映射时使用 service_started 条件
+func mergeDependsOn(base, override interface{}) interface{} {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	baseList, baseIsList := dependsOnList(base)
+	overrideList, overrideIsList := dependsOnList(override)
+	if (base == nil || baseIsList) && (override == nil || overrideIsList) {
+		return appendUnique(baseList, overrideList)
+	}
+
+	merged := make(map[string]interface{})
+	for _, deps := range []interface{}{base, override} {
+		if list, isList := dependsOnList(deps); isList {
+			for _, name := range list {
+				if _, exists := merged[fmt.Sprint(name)]; !exists {
+					merged[fmt.Sprint(name)] = map[string]interface{}{"condition": "service_started"}
+				}
+			}
+			continue
+		}
+		if d, ok := deps.(map[string]interface{}); ok {
+			for name, dependency := range d {
+				merged[name] = dependency
+			}
+		}
+	}
+	return merged
+}
+
+// dependsOnList 返回列表形式的服务依赖，映射形式时第二个返回值为 false
+func dependsOnList(deps interface{}) ([]interface{}, bool) {
+	switch d := deps.(type) {
+	case []interface{}:
+		return d, true
+	case []string:
+		list := make([]interface{}, len(d))
+		for i, name := range d {
+			list[i] = name
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// appendUnique 拼接两个序列并去除重复的条目，保持首次出现的顺序
+func appendUnique(base, override []interface{}) []interface{} {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := make([]interface{}, 0, len(base)+len(override))
+	for _, item := range append(append([]interface{}{}, base...), override...) {
+		duplicate := false
+		for _, existing := range merged {
+			if reflect.DeepEqual(existing, item) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// appendUniqueStrings 拼接两个字符串序列并去除重复的条目，保持首次出现的顺序
+func appendUniqueStrings(base, override []string) []string {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := make([]string, 0, len(base)+len(override))
+	seen := make(map[string]bool, len(base)+len(override))
+	for _, item := range append(append([]string{}, base...), override...) {
+		if !seen[item] {
+			seen[item] = true
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// mergeStringMaps 按键合并两个映射，override 优先
+func mergeStringMaps(base, override map[string]string) map[string]string {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := make(map[string]string, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
+
+// mergeInterfaceMaps 按键合并两个映射，override 中的值整体替换 base 中的值
+func mergeInterfaceMaps(base, override map[string]interface{}) map[string]interface{} {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
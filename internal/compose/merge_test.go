@@ -0,0 +1,406 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+
+	"compman/pkg/types"
+)
+
+// mergeServices 合并只包含一个 app 服务的两个文件，返回合并后的 app 服务
+func mergeServices(t *testing.T, base, override types.Service) types.Service {
+	t.Helper()
+	merged := NewParser().MergeComposeFiles(
+		&types.ComposeFile{Services: map[string]types.Service{"app": base}},
+		&types.ComposeFile{Services: map[string]types.Service{"app": override}},
+	)
+	return merged.Services["app"]
+}
+
+func TestMergeComposeFilesScalars(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     types.Service
+		override types.Service
+		want     types.Service
+	}{
+		{
+			name:     "override 替换镜像和重启策略",
+			base:     types.Service{Image: "nginx:1.24", Restart: "always"},
+			override: types.Service{Image: "nginx:1.25", Restart: "unless-stopped"},
+			want:     types.Service{Image: "nginx:1.25", Restart: "unless-stopped"},
+		},
+		{
+			name:     "override 未设置时保留 base",
+			base:     types.Service{Image: "nginx:1.24", Restart: "always"},
+			override: types.Service{},
+			want:     types.Service{Image: "nginx:1.24", Restart: "always"},
+		},
+		{
+			name:     "command 整体替换而不拼接",
+			base:     types.Service{Command: []interface{}{"nginx", "-g", "daemon off;"}},
+			override: types.Service{Command: "nginx-debug"},
+			want:     types.Service{Command: "nginx-debug"},
+		},
+		{
+			name:     "override 未设置 command 时保留 base",
+			base:     types.Service{Command: "serve"},
+			override: types.Service{Image: "app:2"},
+			want:     types.Service{Image: "app:2", Command: "serve"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, tt.base, tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("合并结果 = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     types.Service
+		override types.Service
+		want     types.Service
+	}{
+		{
+			name:     "ports 拼接并去重",
+			base:     types.Service{Ports: []interface{}{"80:80", "443:443"}},
+			override: types.Service{Ports: []interface{}{"443:443", "8080:8080"}},
+			want:     types.Service{Ports: []interface{}{"80:80", "443:443", "8080:8080"}},
+		},
+		{
+			name: "长格式 ports 按内容去重",
+			base: types.Service{Ports: []interface{}{map[string]interface{}{"target": 80, "published": "8080"}}},
+			override: types.Service{Ports: []interface{}{
+				map[string]interface{}{"target": 80, "published": "8080"},
+				map[string]interface{}{"target": 443},
+			}},
+			want: types.Service{Ports: []interface{}{
+				map[string]interface{}{"target": 80, "published": "8080"},
+				map[string]interface{}{"target": 443},
+			}},
+		},
+		{
+			name:     "networks 拼接并去重",
+			base:     types.Service{Networks: []string{"frontend", "backend"}},
+			override: types.Service{Networks: []string{"backend", "monitoring"}},
+			want:     types.Service{Networks: []string{"frontend", "backend", "monitoring"}},
+		},
+		{
+			name:     "profiles 拼接并去重",
+			base:     types.Service{Profiles: []string{"dev"}},
+			override: types.Service{Profiles: []string{"debug", "dev"}},
+			want:     types.Service{Profiles: []string{"dev", "debug"}},
+		},
+		{
+			name:     "只有 override 设置序列",
+			base:     types.Service{},
+			override: types.Service{ExtraHosts: []string{"db:10.0.0.2"}},
+			want:     types.Service{ExtraHosts: []string{"db:10.0.0.2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, tt.base, tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("合并结果 = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesVolumes(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     []string
+		override []string
+		want     []string
+	}{
+		{
+			name:     "相同容器内路径由 override 替换并保持位置",
+			base:     []string{"./data:/data", "./conf:/etc/app"},
+			override: []string{"app-data:/data"},
+			want:     []string{"app-data:/data", "./conf:/etc/app"},
+		},
+		{
+			name:     "不同容器内路径追加",
+			base:     []string{"./data:/data"},
+			override: []string{"./logs:/var/log/app:ro"},
+			want:     []string{"./data:/data", "./logs:/var/log/app:ro"},
+		},
+		{
+			name:     "访问模式不同视为同一路径",
+			base:     []string{"./conf:/etc/app"},
+			override: []string{"./conf:/etc/app:ro"},
+			want:     []string{"./conf:/etc/app:ro"},
+		},
+		{
+			name:     "匿名卷以自身为容器内路径",
+			base:     []string{"/cache"},
+			override: []string{"cache:/cache"},
+			want:     []string{"cache:/cache"},
+		},
+		{
+			name:     "都未设置",
+			base:     nil,
+			override: nil,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, types.Service{Volumes: tt.base}, types.Service{Volumes: tt.override})
+			if !reflect.DeepEqual(got.Volumes, tt.want) {
+				t.Errorf("volumes = %#v, want %#v", got.Volumes, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     interface{}
+		override interface{}
+		want     interface{}
+	}{
+		{
+			name:     "列表与列表合并为列表",
+			base:     []interface{}{"A=1", "B=2"},
+			override: []interface{}{"B=3", "C=4"},
+			want:     []interface{}{"A=1", "B=3", "C=4"},
+		},
+		{
+			name:     "列表中未赋值的变量",
+			base:     []interface{}{"A=1", "TOKEN"},
+			override: []interface{}{"A"},
+			want:     []interface{}{"A", "TOKEN"},
+		},
+		{
+			name:     "映射与映射合并为映射",
+			base:     map[string]interface{}{"A": "1", "B": 2},
+			override: map[string]interface{}{"B": "3", "C": nil},
+			want:     map[string]interface{}{"A": "1", "B": "3", "C": nil},
+		},
+		{
+			name:     "列表与映射合并为映射",
+			base:     []interface{}{"A=1", "B=2"},
+			override: map[string]interface{}{"B": "3"},
+			want:     map[string]interface{}{"A": "1", "B": "3"},
+		},
+		{
+			name:     "映射与列表合并为映射",
+			base:     map[string]interface{}{"A": "1"},
+			override: []interface{}{"A=2", "B=3"},
+			want:     map[string]interface{}{"A": "2", "B": "3"},
+		},
+		{
+			name:     "只有 base 设置",
+			base:     []interface{}{"A=1"},
+			override: nil,
+			want:     []interface{}{"A=1"},
+		},
+		{
+			name:     "只有 override 设置映射",
+			base:     nil,
+			override: map[string]interface{}{"A": "1"},
+			want:     map[string]interface{}{"A": "1"},
+		},
+		{
+			name:     "都未设置",
+			base:     nil,
+			override: nil,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, types.Service{Environment: tt.base}, types.Service{Environment: tt.override})
+			if !reflect.DeepEqual(got.Environment, tt.want) {
+				t.Errorf("environment = %#v, want %#v", got.Environment, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesDependsOn(t *testing.T) {
+	started := map[string]interface{}{"condition": "service_started"}
+	healthy := map[string]interface{}{"condition": "service_healthy"}
+
+	tests := []struct {
+		name     string
+		base     interface{}
+		override interface{}
+		want     interface{}
+	}{
+		{
+			name:     "列表与列表合并为去重后的列表",
+			base:     []interface{}{"db", "cache"},
+			override: []interface{}{"cache", "queue"},
+			want:     []interface{}{"db", "cache", "queue"},
+		},
+		{
+			name:     "列表与映射合并时提升为映射",
+			base:     []interface{}{"db", "cache"},
+			override: map[string]interface{}{"db": healthy},
+			want:     map[string]interface{}{"db": healthy, "cache": started},
+		},
+		{
+			name:     "映射与列表合并时保留已有条件",
+			base:     map[string]interface{}{"db": healthy},
+			override: []interface{}{"db", "queue"},
+			want:     map[string]interface{}{"db": healthy, "queue": started},
+		},
+		{
+			name:     "字符串列表与映射合并",
+			base:     []string{"db"},
+			override: map[string]interface{}{"queue": healthy},
+			want:     map[string]interface{}{"db": started, "queue": healthy},
+		},
+		{
+			name:     "只有 override 设置",
+			base:     nil,
+			override: []interface{}{"db"},
+			want:     []interface{}{"db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, types.Service{DependsOn: tt.base}, types.Service{DependsOn: tt.override})
+			if !reflect.DeepEqual(got.DependsOn, tt.want) {
+				t.Errorf("depends_on = %#v, want %#v", got.DependsOn, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     *types.BuildConfig
+		override *types.BuildConfig
+		want     *types.BuildConfig
+	}{
+		{
+			name:     "args 按键合并，override 优先",
+			base:     &types.BuildConfig{Context: ".", Args: map[string]string{"VERSION": "1", "MODE": "prod"}},
+			override: &types.BuildConfig{Args: map[string]string{"VERSION": "2", "DEBUG": "true"}},
+			want:     &types.BuildConfig{Context: ".", Args: map[string]string{"VERSION": "2", "MODE": "prod", "DEBUG": "true"}},
+		},
+		{
+			name:     "其余字段由 override 替换",
+			base:     &types.BuildConfig{Context: ".", Dockerfile: "Dockerfile", Target: "prod"},
+			override: &types.BuildConfig{Dockerfile: "Dockerfile.dev", Target: "dev"},
+			want:     &types.BuildConfig{Context: ".", Dockerfile: "Dockerfile.dev", Target: "dev"},
+		},
+		{
+			name:     "只有 base 设置",
+			base:     &types.BuildConfig{Context: "./app"},
+			override: nil,
+			want:     &types.BuildConfig{Context: "./app"},
+		},
+		{
+			name:     "只有 override 设置",
+			base:     nil,
+			override: &types.BuildConfig{Context: "./app", Args: map[string]string{"A": "1"}},
+			want:     &types.BuildConfig{Context: "./app", Args: map[string]string{"A": "1"}},
+		},
+		{
+			name:     "都未设置",
+			base:     nil,
+			override: nil,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeServices(t, types.Service{Build: tt.base}, types.Service{Build: tt.override})
+			if !reflect.DeepEqual(got.Build, tt.want) {
+				t.Errorf("build = %#v, want %#v", got.Build, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesNil(t *testing.T) {
+	file := &types.ComposeFile{
+		Version:     "3.8",
+		ProjectName: "web",
+		FilePath:    "/srv/web/docker-compose.yml",
+		Services:    map[string]types.Service{"app": {Image: "nginx:1.25", Ports: []interface{}{"80:80"}}},
+		Networks:    map[string]interface{}{"frontend": nil},
+	}
+
+	tests := []struct {
+		name     string
+		base     *types.ComposeFile
+		override *types.ComposeFile
+		want     *types.ComposeFile
+	}{
+		{
+			name:     "override 为 nil 时返回 base 的副本",
+			base:     file,
+			override: nil,
+			want:     file,
+		},
+		{
+			name:     "base 为 nil 时使用 override 的服务和项目名称",
+			base:     nil,
+			override: file,
+			want: &types.ComposeFile{
+				Version:     "3.8",
+				ProjectName: "web",
+				Services:    map[string]types.Service{"app": {Image: "nginx:1.25", Ports: []interface{}{"80:80"}}},
+				Networks:    map[string]interface{}{"frontend": nil},
+			},
+		},
+		{
+			name:     "都为 nil",
+			base:     nil,
+			override: nil,
+			want:     &types.ComposeFile{Services: map[string]types.Service{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewParser().MergeComposeFiles(tt.base, tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeComposeFiles() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesDoesNotModifyArguments(t *testing.T) {
+	base := &types.ComposeFile{Services: map[string]types.Service{
+		"app": {Image: "app:1", Ports: []interface{}{"80:80"}, Labels: map[string]string{"a": "1"}},
+	}}
+	override := &types.ComposeFile{Services: map[string]types.Service{
+		"app":    {Image: "app:2", Ports: []interface{}{"443:443"}, Labels: map[string]string{"b": "2"}},
+		"worker": {Image: "worker:1"},
+	}}
+
+	merged := NewParser().MergeComposeFiles(base, override)
+
+	if len(base.Services) != 1 || base.Services["app"].Image != "app:1" || len(base.Services["app"].Labels) != 1 {
+		t.Errorf("base 被修改: %#v", base.Services)
+	}
+	if len(merged.Services) != 2 || merged.Services["app"].Image != "app:2" {
+		t.Errorf("合并结果 = %#v", merged.Services)
+	}
+	want := map[string]string{"a": "1", "b": "2"}
+	if !reflect.DeepEqual(merged.Services["app"].Labels, want) {
+		t.Errorf("labels = %#v, want %#v", merged.Services["app"].Labels, want)
+	}
+}
@@ -1,9 +1,12 @@
 package compose
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"compman/pkg/types"
@@ -13,7 +16,20 @@ import (
 
 // Parser 负责解析 Docker Compose 文件
 type Parser struct {
-	strict bool // 严格模式，遇到错误时停止
+	strict   bool                // 严格模式，遇到错误时停止
+	profiles []string            // 启用的 profiles，为空时不过滤服务
+	encoding string              // 文件编码，为空时使用 UTF-8
+	warnings []ValidationWarning // 最近一次解析发现的警告
+}
+
+// ErrUnusedDeclarations 严格模式下 ValidateFile 发现未被服务引用的网络或数据卷声明时返回的错误
+var ErrUnusedDeclarations = errors.New("存在没有服务使用的网络或数据卷声明")
+
+// ValidationWarning 解析时发现的不影响使用的问题，如声明了但没有服务引用的网络或数据卷
+type ValidationWarning struct {
+	Kind    string // network 或 volume
+	Name    string
+	Message string
 }
 
 // NewParser 创建一个新的解析器
@@ -23,11 +39,32 @@ func NewParser() *Parser {
 	}
 }
 
-// SetStrict 设置严格模式
+// SetStrict 设置严格模式：版本或服务配置无效时解析失败；ValidateFile 发现未引用的声明时除返回警告外还返回 ErrUnusedDeclarations
 func (p *Parser) SetStrict(strict bool) {
 	p.strict = strict
 }
 
+// SetProfiles 设置启用的 Compose profiles，解析时会过滤掉未启用的服务
+// 未声明 profiles 的服务总是启用；profiles 中包含 "*" 时启用所有服务
+func (p *Parser) SetProfiles(profiles []string) {
+	p.profiles = profiles
+}
+
+// SetEncoding 设置 Compose 文件的编码：utf-8 (默认)、cp1252 或 latin-1
+// 读取时转换为 UTF-8 再解析，写回时转换回原编码；UTF-8 文件开头的 BOM 会被自动去除
+func (p *Parser) SetEncoding(encoding string) {
+	p.encoding = encoding
+}
+
+// readFile 读取文件并按设置的编码转换为 UTF-8
+func (p *Parser) readFile(filePath string) ([]byte, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return decodeContent(content, p.encoding)
+}
+
 // ParseFile 解析 Docker Compose 文件
 func (p *Parser) ParseFile(filePath string) (*types.ComposeFile, error) {
 	// 检查文件是否存在
@@ -36,7 +73,7 @@ func (p *Parser) ParseFile(filePath string) (*types.ComposeFile, error) {
 	}
 
 	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+	content, err := p.readFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("读取文件失败: %v", err)
 	}
@@ -47,8 +84,9 @@ func (p *Parser) ParseFile(filePath string) (*types.ComposeFile, error) {
 		return nil, fmt.Errorf("解析文件 %s 失败: %v", filePath, err)
 	}
 
-	// 设置文件路径
+	// 设置文件路径和默认项目名称
 	composeFile.FilePath = filePath
+	composeFile.ProjectName = ProjectNameFromPath(filePath)
 
 	return composeFile, nil
 }
@@ -58,14 +96,14 @@ func (p *Parser) ParseContent(content []byte) (*types.ComposeFile, error) {
 	var composeFile types.ComposeFile
 
 	// 使用 yaml.v3 解析，支持更好的错误处理
-	decoder := yaml.NewDecoder(strings.NewReader(string(content)))
+	decoder := yaml.NewDecoder(bytes.NewReader(bytes.TrimPrefix(content, utf8BOM)))
 	decoder.KnownFields(false) // 允许未知字段
 
 	if err := decoder.Decode(&composeFile); err != nil {
 		return nil, fmt.Errorf("YAML 解析失败: %v", err)
 	}
 
-	// 验证和规范化
+	// 验证和规范化，未引用的声明只记录为警告，通过 ValidateFile 返回，不会导致解析失败
 	if err := p.validateAndNormalize(&composeFile); err != nil {
 		if p.strict {
 			return nil, err
@@ -113,6 +151,16 @@ func (p *Parser) validateAndNormalize(cf *types.ComposeFile) error {
 		cf.Services = make(map[string]types.Service)
 	}
 
+	// 未引用的声明按所有服务检查，不受 profiles 过滤影响
+	p.warnings = p.unusedDeclarations(cf)
+
+	// 过滤未启用 profile 的服务
+	for serviceName, service := range cf.Services {
+		if !p.isServiceActive(service) {
+			delete(cf.Services, serviceName)
+		}
+	}
+
 	// 规范化服务配置
 	for serviceName, service := range cf.Services {
 		if err := p.normalizeService(serviceName, &service); err != nil {
@@ -124,6 +172,72 @@ func (p *Parser) validateAndNormalize(cf *types.ComposeFile) error {
 	return nil
 }
 
+// unusedDeclarations 检查顶层声明的网络和数据卷是否被服务引用
+// default 网络由未指定网络的服务隐式使用，不视为未引用
+func (p *Parser) unusedDeclarations(cf *types.ComposeFile) []ValidationWarning {
+	usedNetworks := make(map[string]bool)
+	for _, service := range cf.Services {
+		for _, network := range service.Networks {
+			usedNetworks[network] = true
+		}
+	}
+	usedVolumes := make(map[string]bool)
+	for _, volume := range p.GetNamedVolumes(cf) {
+		usedVolumes[volume] = true
+	}
+
+	var warnings []ValidationWarning
+	for _, name := range sortedNames(cf.Networks) {
+		if name != "default" && !usedNetworks[name] {
+			warnings = append(warnings, ValidationWarning{
+				Kind:    "network",
+				Name:    name,
+				Message: fmt.Sprintf("网络 %s 已声明但没有服务使用", name),
+			})
+		}
+	}
+	for _, name := range sortedNames(cf.Volumes) {
+		if !usedVolumes[name] {
+			warnings = append(warnings, ValidationWarning{
+				Kind:    "volume",
+				Name:    name,
+				Message: fmt.Sprintf("数据卷 %s 已声明但没有服务使用", name),
+			})
+		}
+	}
+	return warnings
+}
+
+// sortedNames 返回映射的键并排序
+func sortedNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isServiceActive 检查服务在当前启用的 profiles 下是否有效
+func (p *Parser) isServiceActive(service types.Service) bool {
+	if len(p.profiles) == 0 || len(service.Profiles) == 0 {
+		return true
+	}
+
+	for _, active := range p.profiles {
+		if active == "*" {
+			return true
+		}
+		for _, profile := range service.Profiles {
+			if profile == active {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // normalizeService 规范化服务配置
 func (p *Parser) normalizeService(name string, service *types.Service) error {
 	// 验证镜像或构建配置
@@ -146,12 +260,7 @@ func (p *Parser) normalizeService(name string, service *types.Service) error {
 		}
 	}
 
-	// 规范化重启策略
-	if service.Restart == "" {
-		service.Restart = "unless-stopped"
-	}
-
-	// 验证重启策略
+	// 验证重启策略，未指定时保持为空以便 Linter 提示
 	validRestartPolicies := map[string]bool{
 		"no":             true,
 		"always":         true,
@@ -159,13 +268,87 @@ func (p *Parser) normalizeService(name string, service *types.Service) error {
 		"unless-stopped": true,
 	}
 
-	if !validRestartPolicies[service.Restart] {
+	if service.Restart != "" && !validRestartPolicies[service.Restart] && !strings.HasPrefix(service.Restart, "on-failure:") {
 		return fmt.Errorf("无效的重启策略: %s", service.Restart)
 	}
 
+	if _, err := ParseDependsOn(service.DependsOn); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Dependency 服务的一项 depends_on 依赖
+type Dependency struct {
+	Service   string
+	Condition string // service_started、service_healthy 或 service_completed_successfully
+}
+
+// defaultDependsOnCondition 短格式 depends_on 和未指定 condition 时的启动条件
+const defaultDependsOnCondition = "service_started"
+
+// validDependsOnConditions Compose 支持的 depends_on 启动条件
+var validDependsOnConditions = map[string]bool{
+	"service_started":                true,
+	"service_healthy":                true,
+	"service_completed_successfully": true,
+}
+
+// ParseDependsOn 解析 depends_on，支持短格式 [db, cache] 和长格式 {db: {condition: service_healthy}}
+// 结果按服务名排序（短格式保持原顺序）
+func ParseDependsOn(raw interface{}) ([]Dependency, error) {
+	switch value := raw.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		dependencies := make([]Dependency, 0, len(value))
+		for _, serviceName := range value {
+			dependencies = append(dependencies, Dependency{Service: serviceName, Condition: defaultDependsOnCondition})
+		}
+		return dependencies, nil
+	case []interface{}:
+		dependencies := make([]Dependency, 0, len(value))
+		for _, item := range value {
+			serviceName, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("depends_on 中的服务名必须是字符串: %v", item)
+			}
+			dependencies = append(dependencies, Dependency{Service: serviceName, Condition: defaultDependsOnCondition})
+		}
+		return dependencies, nil
+	case map[string]interface{}:
+		serviceNames := make([]string, 0, len(value))
+		for serviceName := range value {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		dependencies := make([]Dependency, 0, len(value))
+		for _, serviceName := range serviceNames {
+			condition := defaultDependsOnCondition
+			switch options := value[serviceName].(type) {
+			case nil:
+				// 只写服务名时使用默认条件
+			case map[string]interface{}:
+				if raw, exists := options["condition"]; exists {
+					c, ok := raw.(string)
+					if !ok || !validDependsOnConditions[c] {
+						return nil, fmt.Errorf("依赖 %s 的 condition 无效: %v (支持: service_started, service_healthy, service_completed_successfully)", serviceName, raw)
+					}
+					condition = c
+				}
+			default:
+				return nil, fmt.Errorf("依赖 %s 的配置格式无效", serviceName)
+			}
+			dependencies = append(dependencies, Dependency{Service: serviceName, Condition: condition})
+		}
+		return dependencies, nil
+	default:
+		return nil, fmt.Errorf("depends_on 格式无效，应为服务名列表或映射")
+	}
+}
+
 // normalizeImageName 规范化镜像名称
 func (p *Parser) normalizeImageName(image string) string {
 	// 如果没有指定标签，添加 :latest
@@ -176,27 +359,90 @@ func (p *Parser) normalizeImageName(image string) string {
 }
 
 // WriteFile 将 ComposeFile 写入文件
+// 文件已存在时只写回服务的 image、restart 和新增的服务，其余内容保持原样
 func (p *Parser) WriteFile(composeFile *types.ComposeFile, filePath string) error {
-	// 创建目录
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
+	content, err := p.renderFile(composeFile, filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
 	}
 
-	// 序列化为 YAML
-	content, err := p.Marshal(composeFile)
+	return nil
+}
+
+// WriteFileAtomic 与 WriteFile 相同，但先写入同目录下的临时文件再重命名，写入中断时不会留下不完整的文件
+// 已存在的文件保留原有权限
+func (p *Parser) WriteFileAtomic(composeFile *types.ComposeFile, filePath string) error {
+	content, err := p.renderFile(composeFile, filePath)
 	if err != nil {
-		return fmt.Errorf("序列化失败: %v", err)
+		return err
 	}
 
-	// 写入文件
-	if err := os.WriteFile(filePath, content, 0644); err != nil {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("写入文件失败: %v", err)
 	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("替换文件失败: %v", err)
+	}
 
 	return nil
 }
 
+// renderFile 将 ComposeFile 的修改应用到原文件内容 (原文件不存在时完整序列化) 并转换为原编码，返回要写入的内容
+func (p *Parser) renderFile(composeFile *types.ComposeFile, filePath string) ([]byte, error) {
+	// 创建目录
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	// 原文件存在时只修改变化的值，保留注释、格式和未建模的字段；否则完整序列化
+	var content []byte
+	original, err := p.readFile(filePath)
+	if err == nil {
+		content, err = p.patchContent(original, composeFile)
+		if err != nil {
+			return nil, fmt.Errorf("修改文件内容失败: %v", err)
+		}
+	} else {
+		content, err = p.Marshal(composeFile)
+		if err != nil {
+			return nil, fmt.Errorf("序列化失败: %v", err)
+		}
+	}
+
+	// 按原编码写入文件
+	content, err = encodeContent(content, p.encoding)
+	if err != nil {
+		return nil, fmt.Errorf("转换文件编码失败: %v", err)
+	}
+	return content, nil
+}
+
 // Marshal 将 ComposeFile 序列化为 YAML
 func (p *Parser) Marshal(composeFile *types.ComposeFile) ([]byte, error) {
 	return yaml.Marshal(composeFile)
@@ -232,10 +478,16 @@ func (p *Parser) RestoreFromBackup(filePath, backupPath string) error {
 	return nil
 }
 
-// ValidateFile 验证 Compose 文件的语法
-func (p *Parser) ValidateFile(filePath string) error {
-	_, err := p.ParseFile(filePath)
-	return err
+// ValidateFile 验证 Compose 文件的语法，并返回未被任何服务引用的网络和数据卷声明
+// 严格模式下存在未引用的声明时同时返回这些警告和 ErrUnusedDeclarations
+func (p *Parser) ValidateFile(filePath string) ([]ValidationWarning, error) {
+	if _, err := p.ParseFile(filePath); err != nil {
+		return nil, err
+	}
+	if p.strict && len(p.warnings) > 0 {
+		return p.warnings, ErrUnusedDeclarations
+	}
+	return p.warnings, nil
 }
 
 // GetImageList 获取 Compose 文件中的所有镜像
@@ -261,3 +513,31 @@ func (p *Parser) GetServiceNames(composeFile *types.ComposeFile) []string {
 
 	return services
 }
+
+// GetNamedVolumes 获取服务中引用的命名数据卷（不包括绑定挂载的主机路径）
+func (p *Parser) GetNamedVolumes(composeFile *types.ComposeFile) []string {
+	var volumes []string
+	seen := make(map[string]bool)
+
+	for _, service := range composeFile.Services {
+		for _, volume := range service.Volumes {
+			parts := strings.SplitN(volume, ":", 2)
+			if len(parts) < 2 {
+				continue // 匿名数据卷
+			}
+
+			source := parts[0]
+			if source == "" || strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") || strings.HasPrefix(source, "$") {
+				continue // 绑定挂载
+			}
+
+			if !seen[source] {
+				seen[source] = true
+				volumes = append(volumes, source)
+			}
+		}
+	}
+
+	sort.Strings(volumes)
+	return volumes
+}
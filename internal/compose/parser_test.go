@@ -0,0 +1,64 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateFileUnusedDeclarations(t *testing.T) {
+	const content = `services:
+  web:
+    image: nginx:1.25
+    networks:
+      - frontend
+    volumes:
+      - data:/data
+networks:
+  frontend: {}
+  backend: {}
+volumes:
+  data: {}
+  cache: {}
+`
+	wantWarnings := []ValidationWarning{
+		{Kind: "network", Name: "backend", Message: "网络 backend 已声明但没有服务使用"},
+		{Kind: "volume", Name: "cache", Message: "数据卷 cache 已声明但没有服务使用"},
+	}
+
+	tests := []struct {
+		name    string
+		strict  bool
+		wantErr error
+	}{
+		{name: "非严格模式只返回警告", strict: false, wantErr: nil},
+		{name: "严格模式同时返回警告和错误", strict: true, wantErr: ErrUnusedDeclarations},
+	}
+
+	filePath := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			parser.SetStrict(tt.strict)
+
+			// 警告不影响解析
+			if _, err := parser.ParseFile(filePath); err != nil {
+				t.Fatalf("ParseFile() error = %v", err)
+			}
+
+			warnings, err := parser.ValidateFile(filePath)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateFile() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(warnings, wantWarnings) {
+				t.Errorf("ValidateFile() warnings = %#v, want %#v", warnings, wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package compose
+
+import (
+	"sort"
+	"strings"
+
+	"compman/internal/docker"
+)
+
+// rewriteImageRegistry 按 --image-registry-map 替换镜像的仓库前缀，返回替换后的镜像和是否发生替换
+// 前缀按完整镜像路径匹配，省略仓库地址的 Docker Hub 镜像视为 docker.io/library/...；
+// 多个前缀匹配时使用最长的一个，标签和摘要保持不变
+func (u *Updater) rewriteImageRegistry(image string) (string, bool) {
+	if len(u.config.ImageRegistryMap) == 0 {
+		return image, false
+	}
+
+	registry, repository := docker.ParseImageName(image)
+	fullName := registry + "/" + repository
+	suffix := image[len(imageNameWithoutReference(image)):]
+
+	prefixes := make([]string, 0, len(u.config.ImageRegistryMap))
+	for prefix := range u.config.ImageRegistryMap {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		normalized := normalizeRegistryPrefix(prefix)
+		if fullName != normalized && !strings.HasPrefix(fullName, normalized+"/") {
+			continue
+		}
+		replacement := strings.TrimSuffix(u.config.ImageRegistryMap[prefix], "/")
+		return replacement + strings.TrimPrefix(fullName, normalized) + suffix, true
+	}
+	return image, false
+}
+
+// recordRewrite 记录一次镜像仓库替换，多个文件并行处理时可能同时调用
+func (u *Updater) recordRewrite(oldImage, newImage string) {
+	u.rewriteMutex.Lock()
+	defer u.rewriteMutex.Unlock()
+	if u.rewrittenImages == nil {
+		u.rewrittenImages = make(map[string]string)
+	}
+	u.rewrittenImages[oldImage] = newImage
+}
+
+// RewrittenImages 返回本次更新中按 --image-registry-map 替换的镜像 (原镜像 -> 新镜像)
+func (u *Updater) RewrittenImages() map[string]string {
+	u.rewriteMutex.Lock()
+	defer u.rewriteMutex.Unlock()
+	rewritten := make(map[string]string, len(u.rewrittenImages))
+	for oldImage, newImage := range u.rewrittenImages {
+		rewritten[oldImage] = newImage
+	}
+	return rewritten
+}
+
+// imageNameWithoutReference 去掉镜像引用中的标签和摘要 (仓库地址中的端口不是标签)
+func imageNameWithoutReference(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name = name[:colon]
+	}
+	return name
+}
+
+// normalizeRegistryPrefix 规范化前缀中的仓库地址，使 index.docker.io 等写法与 docker.io 匹配
+func normalizeRegistryPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	registry, rest, found := strings.Cut(prefix, "/")
+	registry = docker.NormalizeRegistry(registry)
+	if !found {
+		return registry
+	}
+	return registry + "/" + rest
+}
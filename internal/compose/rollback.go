@@ -0,0 +1,245 @@
+package compose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"compman/internal/docker"
+	"compman/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 回滚策略
+const (
+	RollbackComposeFile = "compose-file" // 只恢复 Compose 文件，不重建容器
+	RollbackContainers  = "containers"   // 不修改 Compose 文件，使用原镜像重建容器
+	RollbackFull        = "full"         // 恢复 Compose 文件并重建容器
+)
+
+// RollbackStrategies 支持的回滚策略
+var RollbackStrategies = []string{RollbackComposeFile, RollbackContainers, RollbackFull}
+
+const (
+	// healthCheckDelay up -d 完成后等待容器启动的时间，避免刚启动即退出的容器被视为正常
+	healthCheckDelay = 5 * time.Second
+
+	// healthCheckTimeout 等待健康检查结束 (不再处于 starting 状态) 的最长时间
+	healthCheckTimeout = 60 * time.Second
+
+	// healthCheckInterval 查询容器状态的间隔
+	healthCheckInterval = 2 * time.Second
+)
+
+// rollbackStrategy 返回配置的回滚策略，未设置时为 full
+func (u *Updater) rollbackStrategy() string {
+	if u.config.RollbackStrategy == "" {
+		return RollbackFull
+	}
+	return u.config.RollbackStrategy
+}
+
+// rollbackIfUnhealthy 启用 --rollback-on-failure 时检查更新后的服务状态，存在异常服务时按回滚策略回滚，
+// 并将本次更新的服务结果标记为失败，错误信息中记录使用的策略和回滚结果
+func (u *Updater) rollbackIfUnhealthy(cf *types.ComposeFile, backupPath string, previousImages, runningImages map[string]string, results []*types.UpdateResult) []*types.UpdateResult {
+	if !u.config.RollbackOnFailure || u.config.NoUp || u.config.DryRun {
+		return results
+	}
+
+	unhealthy, err := u.waitForServices(cf)
+	if err != nil || len(unhealthy) == 0 {
+		// 无法获取状态时不回滚，由更新后的状态检查提示
+		return results
+	}
+
+	strategyName := u.rollbackStrategy()
+	failure := fmt.Sprintf("服务 %s 状态异常", strings.Join(unhealthy, ", "))
+	var resultErr error
+	if err := u.rollback(strategyName, cf, backupPath, previousImages, runningImages); err != nil {
+		resultErr = fmt.Errorf("%s，按 %s 策略回滚失败: %v", failure, strategyName, err)
+	} else {
+		resultErr = fmt.Errorf("%s，已按 %s 策略回滚", failure, strategyName)
+	}
+
+	for _, result := range results {
+		if _, exists := cf.Services[result.Service]; !exists || result.SkipReason != "" {
+			continue
+		}
+		result.Success = false
+		result.Error = resultErr
+	}
+
+	return results
+}
+
+// waitForServices 等待文件中的服务启动完成，返回未运行或健康检查失败的服务名
+// 超时后仍处于 starting 状态的服务也视为异常
+func (u *Updater) waitForServices(cf *types.ComposeFile) ([]string, error) {
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	time.Sleep(healthCheckDelay)
+	deadline := time.Now().Add(healthCheckTimeout)
+	for {
+		project, err := dockerClient.InspectComposeProject(RuntimeProjectName(cf))
+		if err != nil {
+			return nil, err
+		}
+
+		var unhealthy []string
+		starting := false
+		for _, service := range project.Services {
+			if _, exists := cf.Services[service.ServiceName]; !exists {
+				continue
+			}
+			if service.Health == "starting" {
+				starting = true
+			}
+			if !service.IsHealthy() || service.Health == "starting" {
+				unhealthy = append(unhealthy, service.ServiceName)
+			}
+		}
+
+		if !starting || time.Now().After(deadline) {
+			sort.Strings(unhealthy)
+			return unhealthy, nil
+		}
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+// recordRunningImages 在拉取镜像前记录服务当前运行的容器所使用镜像的 仓库@摘要 引用，供回滚时精确恢复
+// latest 策略不修改 Compose 文件，拉取后原标签指向新镜像，只能通过这些摘要找回更新前的镜像
+// 未启用 --rollback-on-failure 时不记录；无法获取摘要的服务 (如本地构建的镜像) 不记录
+func (u *Updater) recordRunningImages(cf *types.ComposeFile) map[string]string {
+	if !u.config.RollbackOnFailure || u.config.NoUp || u.config.DryRun {
+		return nil
+	}
+
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	project, err := dockerClient.InspectComposeProject(RuntimeProjectName(cf))
+	if err != nil {
+		return nil
+	}
+
+	runningImages := make(map[string]string)
+	for _, service := range project.Services {
+		if _, exists := cf.Services[service.ServiceName]; !exists || service.ImageID == "" {
+			continue
+		}
+		if _, recorded := runningImages[service.ServiceName]; recorded {
+			continue
+		}
+		reference, err := dockerClient.GetImageDigestReference(service.ImageID)
+		if err != nil {
+			continue
+		}
+		runningImages[service.ServiceName] = reference
+	}
+	return runningImages
+}
+
+// rollback 按策略回滚更新：compose-file 只恢复 Compose 文件，containers 使用更新前的镜像重建容器，
+// full 恢复 Compose 文件后使用更新前的镜像重建容器。更新前的镜像优先使用拉取前记录的运行中容器的镜像摘要，
+// 没有记录时使用修改标签前的镜像；两者都没有时无法回滚，返回错误
+func (u *Updater) rollback(strategyName string, cf *types.ComposeFile, backupPath string, previousImages, runningImages map[string]string) error {
+	switch strategyName {
+	case RollbackComposeFile:
+		// 镜像标签未被修改时 Compose 文件与备份相同，恢复文件不会回滚任何服务
+		if len(previousImages) == 0 {
+			return fmt.Errorf("镜像标签未被修改，恢复 Compose 文件无法回滚，请使用 containers 或 full 策略")
+		}
+		return u.restoreComposeFile(cf, backupPath)
+	case RollbackContainers:
+		return u.recreateWithImages(cf, rollbackImages(previousImages, runningImages))
+	case RollbackFull:
+		if len(previousImages) > 0 {
+			if err := u.restoreComposeFile(cf, backupPath); err != nil {
+				return err
+			}
+		}
+		return u.recreateWithImages(cf, rollbackImages(previousImages, runningImages))
+	default:
+		return fmt.Errorf("不支持的回滚策略: %s (支持: %s)", strategyName, strings.Join(RollbackStrategies, ", "))
+	}
+}
+
+// rollbackImages 合并回滚使用的镜像 (服务 -> 镜像)，拉取前记录的镜像摘要优先于修改标签前的镜像
+func rollbackImages(previousImages, runningImages map[string]string) map[string]string {
+	images := make(map[string]string, len(previousImages)+len(runningImages))
+	for serviceName, image := range previousImages {
+		images[serviceName] = image
+	}
+	for serviceName, image := range runningImages {
+		images[serviceName] = image
+	}
+	return images
+}
+
+// restoreComposeFile 从更新前的备份恢复 Compose 文件
+func (u *Updater) restoreComposeFile(cf *types.ComposeFile, backupPath string) error {
+	if backupPath == "" {
+		return fmt.Errorf("没有可用的备份文件")
+	}
+	if err := u.parser.RestoreFromBackup(cf.FilePath, backupPath); err != nil {
+		return fmt.Errorf("恢复 Compose 文件失败: %v", err)
+	}
+	return nil
+}
+
+// recreateWithImages 通过临时的覆盖文件为服务指定更新前的镜像并重建容器，不修改 Compose 文件本身
+func (u *Updater) recreateWithImages(cf *types.ComposeFile, images map[string]string) error {
+	if len(images) == 0 {
+		return fmt.Errorf("没有记录到更新前运行的镜像，且镜像标签未被修改，无法回滚")
+	}
+
+	services := make(map[string]map[string]string, len(images))
+	serviceNames := make([]string, 0, len(images))
+	for serviceName, image := range images {
+		services[serviceName] = map[string]string{"image": image}
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	content, err := yaml.Marshal(map[string]interface{}{"services": services})
+	if err != nil {
+		return fmt.Errorf("生成覆盖文件失败: %v", err)
+	}
+	override, err := os.CreateTemp("", "compman-rollback-*.yml")
+	if err != nil {
+		return fmt.Errorf("创建覆盖文件失败: %v", err)
+	}
+	defer os.Remove(override.Name())
+	if _, err := override.Write(content); err != nil {
+		override.Close()
+		return fmt.Errorf("写入覆盖文件失败: %v", err)
+	}
+	override.Close()
+
+	fileNames := []string{filepath.Base(cf.FilePath), override.Name()}
+	cmd := u.composeCommandWithFiles(fileNames, append(u.upArgs(), serviceNames...)...)
+	return u.runRollbackCommand(cf, cmd)
+}
+
+// runRollbackCommand 在 Compose 文件所在目录执行回滚命令，使用 up_timeout 作为超时时间
+func (u *Updater) runRollbackCommand(cf *types.ComposeFile, cmd *exec.Cmd) error {
+	ctx, cancel := context.WithTimeout(context.Background(), u.upTimeout())
+	defer cancel()
+
+	rollbackCmd := exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
+	rollbackCmd.Dir = filepath.Dir(cf.FilePath)
+	u.applyComposeEnv(rollbackCmd)
+
+	if output, err := rollbackCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("重建容器失败: %v\n输出: %s", err, string(output))
+	}
+	return nil
+}
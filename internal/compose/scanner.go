@@ -4,23 +4,38 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"compman/internal/docker"
+	"compman/internal/ui"
 	"compman/pkg/types"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // Scanner 负责扫描目录中的 Docker Compose 文件
 type Scanner struct {
-	maxDepth int
-	verbose  bool
+	maxDepth         int
+	verbose          bool
+	projectOverrides map[string]string // 文件路径 -> 项目名称
+	profiles         []string          // 启用的 Compose profiles
+	skipHidden       bool              // 是否跳过以 . 开头的隐藏目录
+	encoding         string            // Compose 文件编码，为空时使用 UTF-8
+	useLibrary       bool              // 使用 compose-go 加载 Compose 文件
+	ignoreErrors     bool              // 跳过无法读取的目录和失效的符号链接，为 false 时中止扫描
+	recursive        bool              // 是否扫描子目录，为 false 时只检查扫描路径下的文件
 }
 
 // NewScanner 创建一个新的扫描器
 func NewScanner() *Scanner {
 	return &Scanner{
-		maxDepth: 10, // 默认最大扫描深度
-		verbose:  false,
+		maxDepth:     10, // 默认最大扫描深度
+		verbose:      false,
+		skipHidden:   true,
+		ignoreErrors: true,
+		recursive:    true,
 	}
 }
 
@@ -29,6 +44,49 @@ func (s *Scanner) SetMaxDepth(depth int) {
 	s.maxDepth = depth
 }
 
+// SetRecursive 设置是否扫描子目录 (默认扫描)，为 false 时只检查每个扫描路径下的文件
+func (s *Scanner) SetRecursive(recursive bool) {
+	s.recursive = recursive
+}
+
+// SetSkipHidden 设置是否跳过以 . 开头的隐藏目录（如 .git、.venv），默认跳过
+// 直接指定为扫描路径的隐藏目录不受影响
+func (s *Scanner) SetSkipHidden(skip bool) {
+	s.skipHidden = skip
+}
+
+// isSkippedDir 判断目录是否因隐藏而被跳过
+func (s *Scanner) isSkippedDir(name string) bool {
+	return s.skipHidden && strings.HasPrefix(name, ".")
+}
+
+// SetProjectNameOverrides 设置自定义项目名称 (文件路径 -> 项目名称)
+func (s *Scanner) SetProjectNameOverrides(overrides map[string]string) {
+	s.projectOverrides = overrides
+}
+
+// SetProfiles 设置启用的 Compose profiles，解析时只保留启用的服务
+func (s *Scanner) SetProfiles(profiles []string) {
+	s.profiles = profiles
+}
+
+// SetEncoding 设置 Compose 文件编码 (utf-8、cp1252、latin-1)
+func (s *Scanner) SetEncoding(encoding string) {
+	s.encoding = encoding
+}
+
+// SetUseComposeLibrary 设置是否使用 compose-spec/compose-go 加载 Compose 文件
+// compose-go 与 docker compose 的解析逻辑一致，支持 extends、include 和变量插值
+func (s *Scanner) SetUseComposeLibrary(use bool) {
+	s.useLibrary = use
+}
+
+// SetIgnoreErrors 设置是否跳过无法读取的目录和指向不存在目标的符号链接 (默认跳过)
+// 为 false 时 (严格模式) 遇到这类错误立即中止扫描并返回错误
+func (s *Scanner) SetIgnoreErrors(ignore bool) {
+	s.ignoreErrors = ignore
+}
+
 // SetVerbose 设置详细模式
 func (s *Scanner) SetVerbose(verbose bool) {
 	s.verbose = verbose
@@ -39,6 +97,55 @@ func (s *Scanner) ScanComposeFiles(paths []string) ([]*types.ComposeFile, error)
 	var composeFiles []*types.ComposeFile
 	visited := make(map[string]bool) // 防止重复扫描
 
+	matches, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, matchPath := range matches {
+		// 扫描路径
+		err = s.walkPath(matchPath, 0, visited, func(path string) {
+			composeFile, err := s.parseComposeFile(path)
+			if err != nil {
+				// 静默处理解析错误，继续处理其他文件
+				return
+			}
+			composeFiles = append(composeFiles, composeFile)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("扫描路径失败 %s: %v", matchPath, err)
+		}
+	}
+
+	return composeFiles, nil
+}
+
+// CountComposeFiles 统计指定路径下的 Docker Compose 文件数量
+// 与 ScanComposeFiles 遍历相同的文件 (包括扫描深度限制)，但只按文件名判断，不解析 YAML，
+// 比 ScanComposeFiles 快得多，无效的 Compose 文件也会被计入
+func (s *Scanner) CountComposeFiles(paths []string) (int, error) {
+	matches, err := expandPaths(paths)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	visited := make(map[string]bool)
+	for _, matchPath := range matches {
+		err := s.walkPath(matchPath, 0, visited, func(string) {
+			count++
+		})
+		if err != nil {
+			return 0, fmt.Errorf("扫描路径失败 %s: %v", matchPath, err)
+		}
+	}
+
+	return count, nil
+}
+
+// expandPaths 将路径转换为绝对路径，包含通配符时展开为所有匹配的路径，并忽略不存在的路径
+func expandPaths(paths []string) ([]string, error) {
+	var expanded []string
 	for _, rootPath := range paths {
 		// 解析绝对路径
 		absPath, err := filepath.Abs(rootPath)
@@ -46,23 +153,28 @@ func (s *Scanner) ScanComposeFiles(paths []string) ([]*types.ComposeFile, error)
 			return nil, fmt.Errorf("解析路径失败 %s: %v", rootPath, err)
 		}
 
-		// 检查路径是否存在
-		if _, err := os.Stat(absPath); os.IsNotExist(err) {
-			continue
+		// 路径包含通配符时展开为所有匹配的路径
+		matches := []string{absPath}
+		if strings.ContainsAny(absPath, "*?[") {
+			matches, err = filepath.Glob(absPath)
+			if err != nil {
+				return nil, fmt.Errorf("无效的路径模式 %s: %v", rootPath, err)
+			}
 		}
 
-		// 扫描路径
-		err = s.walkPath(absPath, 0, visited, &composeFiles)
-		if err != nil {
-			return nil, fmt.Errorf("扫描路径失败 %s: %v", absPath, err)
+		for _, matchPath := range matches {
+			// 检查路径是否存在
+			if _, err := os.Stat(matchPath); os.IsNotExist(err) {
+				continue
+			}
+			expanded = append(expanded, matchPath)
 		}
 	}
-
-	return composeFiles, nil
+	return expanded, nil
 }
 
-// walkPath 递归遍历路径
-func (s *Scanner) walkPath(path string, depth int, visited map[string]bool, composeFiles *[]*types.ComposeFile) error {
+// walkPath 递归遍历路径，对每个 Compose 文件调用一次 visit
+func (s *Scanner) walkPath(path string, depth int, visited map[string]bool, visit func(path string)) error {
 	// 检查是否已访问过
 	if visited[path] {
 		return nil
@@ -74,42 +186,55 @@ func (s *Scanner) walkPath(path string, depth int, visited map[string]bool, comp
 		return nil
 	}
 
-	// 获取文件信息
+	// 获取文件信息，指向不存在目标的符号链接在这里返回错误
 	info, err := os.Stat(path)
 	if err != nil {
-		return err
+		return s.handleWalkError(path, err)
 	}
 
 	if info.IsDir() {
+		// 不递归时只扫描根路径本身，跳过其中的子目录
+		if depth > 0 && !s.recursive {
+			return nil
+		}
+
 		// 如果是目录，递归扫描
 		entries, err := os.ReadDir(path)
 		if err != nil {
-			return err
+			return s.handleWalkError(path, err)
 		}
 
 		for _, entry := range entries {
-			entryPath := filepath.Join(path, entry.Name())
-			err = s.walkPath(entryPath, depth+1, visited, composeFiles)
-			if err != nil {
-				// 静默处理错误，继续处理其他文件
+			if entry.IsDir() && s.isSkippedDir(entry.Name()) {
 				continue
 			}
+
+			entryPath := filepath.Join(path, entry.Name())
+			if err := s.walkPath(entryPath, depth+1, visited, visit); err != nil {
+				return err
+			}
 		}
 	} else {
 		// 如果是文件，检查是否为 Compose 文件
 		if s.isComposeFile(path) {
-			composeFile, err := s.parseComposeFile(path)
-			if err != nil {
-				// 静默处理解析错误，继续处理其他文件
-				return nil
-			}
-			*composeFiles = append(*composeFiles, composeFile)
+			visit(path)
 		}
 	}
 
 	return nil
 }
 
+// handleWalkError 处理无法读取的路径：忽略错误时跳过该路径，详细模式下输出警告；严格模式下返回错误
+func (s *Scanner) handleWalkError(path string, err error) error {
+	if !s.ignoreErrors {
+		return err
+	}
+	if s.verbose {
+		ui.PrintWarning(fmt.Sprintf("跳过无法读取的路径 %s: %v", path, err))
+	}
+	return nil
+}
+
 // isComposeFile 检查文件是否为 Docker Compose 文件
 func (s *Scanner) isComposeFile(filename string) bool {
 	base := strings.ToLower(filepath.Base(filename))
@@ -146,17 +271,80 @@ func (s *Scanner) isComposeFile(filename string) bool {
 func (s *Scanner) parseComposeFile(filePath string) (*types.ComposeFile, error) {
 	// 这里调用 parser.go 中的解析函数
 	parser := NewParser()
-	composeFile, err := parser.ParseFile(filePath)
+	parser.SetProfiles(s.profiles)
+	parser.SetEncoding(s.encoding)
+	var composeFile *types.ComposeFile
+	var err error
+	if s.useLibrary {
+		composeFile, err = s.loadWithLibrary(parser, filePath)
+	} else {
+		composeFile, err = parser.ParseFile(filePath)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// 设置文件路径
+	// 设置文件路径、项目名称和校验和
 	composeFile.FilePath = filePath
+	composeFile.ProjectName = s.projectName(filePath)
+	if composeFile.Checksum, err = fileChecksum(filePath); err != nil {
+		return nil, fmt.Errorf("计算文件校验和失败: %v", err)
+	}
+
+	return composeFile, nil
+}
 
+// loadWithLibrary 按设置的编码读取文件，再交给 compose-go 加载
+func (s *Scanner) loadWithLibrary(parser *Parser, filePath string) (*types.ComposeFile, error) {
+	content, err := parser.readFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	loader := docker.NewComposeLibraryLoader()
+	loader.SetProfiles(s.profiles)
+	composeFile, err := loader.Load(filePath, content)
+	if err != nil {
+		return nil, fmt.Errorf("解析文件 %s 失败: %v", filePath, err)
+	}
 	return composeFile, nil
 }
 
+// projectName 返回文件的项目名称，优先使用自定义名称
+func (s *Scanner) projectName(filePath string) string {
+	if name, ok := s.projectOverrides[filePath]; ok && name != "" {
+		return name
+	}
+
+	// 配置文件中的键可能是相对路径或被转为小写
+	for path, name := range s.projectOverrides {
+		if name == "" {
+			continue
+		}
+		if absPath, err := filepath.Abs(path); err == nil && strings.EqualFold(absPath, filePath) {
+			return name
+		}
+	}
+
+	return ProjectNameFromPath(filePath)
+}
+
+// RuntimeProjectName 返回 Docker Compose 运行时使用的项目名称，即容器 com.docker.compose.project 标签的值
+// project_name_override 只影响显示，执行 docker-compose 时不传 -p，运行时名称始终由文件所在目录推导
+func RuntimeProjectName(cf *types.ComposeFile) string {
+	return ProjectNameFromPath(cf.FilePath)
+}
+
+// ProjectNameFromPath 根据文件路径推导项目名称（文件所在目录名）
+func ProjectNameFromPath(filePath string) string {
+	projectName := filepath.Base(filepath.Dir(filePath))
+	if projectName == "." || projectName == "/" {
+		projectName = filepath.Base(filePath)
+		projectName = strings.TrimSuffix(projectName, filepath.Ext(projectName))
+	}
+	return projectName
+}
+
 // ScanResult 表示扫描结果的统计信息
 type ScanResult struct {
 	TotalFiles   int
@@ -165,6 +353,7 @@ type ScanResult struct {
 	ScannedPaths []string
 	Duration     time.Duration
 	Services     map[string]int // service name -> count
+	ByRegistry   map[string]int // 镜像仓库地址 -> 不重复的镜像数量
 }
 
 // ScanWithResult 扫描并返回详细结果
@@ -191,20 +380,71 @@ func (s *Scanner) ScanWithResult(paths []string) (*ScanResult, []*types.ComposeF
 			result.Services[serviceName]++
 		}
 	}
+	result.ByRegistry = countImagesByRegistry(uniqueImages(composeFiles))
 
 	return result, composeFiles, nil
 }
 
-// GetFilesByPattern 根据模式查找文件
+// FilterByLabels 过滤出匹配所有标签条件的 Compose 文件
+// 每个条件只需被项目级 x-labels 或任一服务的标签满足即可
+func FilterByLabels(composeFiles []*types.ComposeFile, labels map[string]string) []*types.ComposeFile {
+	if len(labels) == 0 {
+		return composeFiles
+	}
+
+	var filtered []*types.ComposeFile
+	for _, cf := range composeFiles {
+		if matchesAllLabels(cf, labels) {
+			filtered = append(filtered, cf)
+		}
+	}
+
+	return filtered
+}
+
+// matchesAllLabels 检查 Compose 文件是否满足所有标签条件
+func matchesAllLabels(cf *types.ComposeFile, labels map[string]string) bool {
+	for key, value := range labels {
+		if !matchesLabel(cf, key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesLabel 检查 Compose 文件的项目标签或任一服务标签是否匹配
+func matchesLabel(cf *types.ComposeFile, key, value string) bool {
+	if v, ok := cf.XLabels[key]; ok && v == value {
+		return true
+	}
+
+	for _, service := range cf.Services {
+		if v, ok := service.Labels[key]; ok && v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetFilesByPattern 根据模式查找文件，跳过隐藏目录的设置同样生效
 func (s *Scanner) GetFilesByPattern(rootPath, pattern string) ([]string, error) {
 	var matchedFiles []string
 
 	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			// 无法读取的子目录直接跳过，与 ScanComposeFiles 一致
+			if path != rootPath {
+				return nil
+			}
 			return err
 		}
 
-		if !info.IsDir() {
+		if info.IsDir() {
+			if path != rootPath && (!s.recursive || s.isSkippedDir(info.Name())) {
+				return filepath.SkipDir
+			}
+		} else {
 			matched, err := filepath.Match(pattern, filepath.Base(path))
 			if err != nil {
 				return err
@@ -219,3 +459,208 @@ func (s *Scanner) GetFilesByPattern(rootPath, pattern string) ([]string, error)
 
 	return matchedFiles, err
 }
+
+// ComputeStats 统计所有 Compose 文件的服务和镜像信息，镜像按完整名称去重后统计
+func (s *Scanner) ComputeStats(composeFiles []*types.ComposeFile) *types.ScanStats {
+	stats := &types.ScanStats{
+		TotalFiles: len(composeFiles),
+	}
+
+	for _, cf := range composeFiles {
+		stats.TotalServices += len(cf.Services)
+		for _, service := range cf.Services {
+			if service.Image == "" && service.Build != nil {
+				stats.BuildOnlyServices++
+			}
+		}
+	}
+
+	images := uniqueImages(composeFiles)
+	stats.UniqueImages = len(images)
+	stats.ByRegistry = countImagesByRegistry(images)
+	for image := range images {
+		repository, tag := splitImageReference(image)
+
+		switch {
+		case tag == "" || tag == "latest":
+			stats.LatestTagImages++
+		case isSemverTag(tag):
+			stats.SemverTagImages++
+		}
+
+		if isOfficialImage(repository) {
+			stats.OfficialImages++
+		} else {
+			stats.ThirdPartyImages++
+		}
+	}
+
+	if stats.TotalFiles > 0 {
+		stats.AvgServicesPerFile = float64(stats.TotalServices) / float64(stats.TotalFiles)
+	}
+
+	return stats
+}
+
+// uniqueImages 返回所有服务使用的不重复镜像（按完整名称去重）
+func uniqueImages(composeFiles []*types.ComposeFile) map[string]bool {
+	images := make(map[string]bool)
+	for _, cf := range composeFiles {
+		for _, service := range cf.Services {
+			if service.Image != "" {
+				images[service.Image] = true
+			}
+		}
+	}
+	return images
+}
+
+// countImagesByRegistry 按镜像仓库地址统计镜像数量，Docker Hub 的各种写法统一计为 docker.io
+func countImagesByRegistry(images map[string]bool) map[string]int {
+	counts := make(map[string]int)
+	for image := range images {
+		registry, _ := docker.ParseImageName(image)
+		counts[registry]++
+	}
+	return counts
+}
+
+// ImageUsage 描述使用同一镜像仓库（不区分标签）的所有服务
+type ImageUsage struct {
+	Repository   string            // 镜像仓库名，不含标签
+	ProjectCount int               // 使用该镜像的项目数量
+	Entries      []ImageUsageEntry // 使用该镜像的服务，按项目和服务名排序
+}
+
+// ImageUsageEntry 描述使用某个镜像的单个服务
+type ImageUsageEntry struct {
+	ProjectName string
+	FilePath    string
+	Service     string
+	Image       string // Compose 文件中的完整镜像引用
+	Tag         string // 镜像标签，未指定时为 latest；使用摘要时为摘要
+}
+
+// GroupByImage 按镜像仓库对服务分组，便于查找受某个基础镜像影响的项目
+// 结果按使用的项目数量降序排列，数量相同时按仓库名排序
+func (s *Scanner) GroupByImage(composeFiles []*types.ComposeFile) []ImageUsage {
+	groups := make(map[string]*ImageUsage)
+	projects := make(map[string]map[string]bool) // 仓库 -> 文件路径集合
+
+	for _, cf := range composeFiles {
+		for serviceName, service := range cf.Services {
+			if service.Image == "" {
+				continue
+			}
+
+			repository, tag := splitImageReference(service.Image)
+			if tag == "" {
+				tag = "latest"
+				if idx := strings.Index(service.Image, "@"); idx >= 0 {
+					tag = service.Image[idx+1:]
+				}
+			}
+
+			group, exists := groups[repository]
+			if !exists {
+				group = &ImageUsage{Repository: repository}
+				groups[repository] = group
+				projects[repository] = make(map[string]bool)
+			}
+			group.Entries = append(group.Entries, ImageUsageEntry{
+				ProjectName: cf.ProjectName,
+				FilePath:    cf.FilePath,
+				Service:     serviceName,
+				Image:       service.Image,
+				Tag:         tag,
+			})
+			projects[repository][cf.FilePath] = true
+		}
+	}
+
+	usages := make([]ImageUsage, 0, len(groups))
+	for repository, group := range groups {
+		group.ProjectCount = len(projects[repository])
+		sort.Slice(group.Entries, func(i, j int) bool {
+			a, b := group.Entries[i], group.Entries[j]
+			if a.ProjectName != b.ProjectName {
+				return a.ProjectName < b.ProjectName
+			}
+			if a.FilePath != b.FilePath {
+				return a.FilePath < b.FilePath
+			}
+			return a.Service < b.Service
+		})
+		usages = append(usages, *group)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].ProjectCount != usages[j].ProjectCount {
+			return usages[i].ProjectCount > usages[j].ProjectCount
+		}
+		return usages[i].Repository < usages[j].Repository
+	})
+
+	return usages
+}
+
+// BuildInventory 生成可序列化的服务清单，项目保持扫描顺序，服务按名称排序
+func (s *Scanner) BuildInventory(composeFiles []*types.ComposeFile) *types.ScanInventory {
+	inventory := &types.ScanInventory{
+		Projects: make([]types.ProjectInventory, 0, len(composeFiles)),
+	}
+
+	for _, cf := range composeFiles {
+		project := types.ProjectInventory{
+			Name:     cf.ProjectName,
+			FilePath: cf.FilePath,
+			Services: make([]types.ServiceInventory, 0, len(cf.Services)),
+		}
+
+		for serviceName, service := range cf.Services {
+			entry := types.ServiceInventory{
+				Name:  serviceName,
+				Image: service.Image,
+			}
+			if service.Image != "" {
+				_, entry.Tag = splitImageReference(service.Image)
+				if entry.Tag == "" && !strings.Contains(service.Image, "@") {
+					entry.Tag = "latest"
+				}
+			}
+			project.Services = append(project.Services, entry)
+		}
+		sort.Slice(project.Services, func(i, j int) bool {
+			return project.Services[i].Name < project.Services[j].Name
+		})
+
+		inventory.Projects = append(inventory.Projects, project)
+	}
+
+	return inventory
+}
+
+// splitImageReference 将镜像拆分为仓库和标签，镜像摘要不视为标签
+func splitImageReference(image string) (string, string) {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, ""
+}
+
+// isSemverTag 检查标签是否为语义版本（允许 v 前缀和省略次版本号）
+func isSemverTag(tag string) bool {
+	_, err := semver.NewVersion(tag)
+	return err == nil
+}
+
+// isOfficialImage 检查镜像是否为 Docker Hub 官方镜像，如 nginx、library/nginx、docker.io/library/nginx
+func isOfficialImage(repository string) bool {
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	repository = strings.TrimPrefix(repository, "library/")
+	return !strings.Contains(repository, "/")
+}
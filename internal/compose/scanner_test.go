@@ -0,0 +1,53 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCountComposeFilesRespectsMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"app", "app/nested/deeper/deepest"} {
+		path := filepath.Join(root, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := []byte("services:\n  web:\n    image: nginx:1.25\n")
+		if err := os.WriteFile(filepath.Join(path, "docker-compose.yml"), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     int
+	}{
+		{name: "深层文件超出扫描深度", maxDepth: 2, want: 1},
+		{name: "扫描深度足够", maxDepth: 10, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner()
+			scanner.SetMaxDepth(tt.maxDepth)
+
+			count, err := scanner.CountComposeFiles([]string{root})
+			if err != nil {
+				t.Fatalf("CountComposeFiles() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("CountComposeFiles() = %d, want %d", count, tt.want)
+			}
+
+			composeFiles, err := scanner.ScanComposeFiles([]string{root})
+			if err != nil {
+				t.Fatalf("ScanComposeFiles() error = %v", err)
+			}
+			if len(composeFiles) != count {
+				t.Errorf("CountComposeFiles() = %d, ScanComposeFiles() 找到 %d 个文件", count, len(composeFiles))
+			}
+		})
+	}
+}
@@ -8,83 +8,154 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"compman/internal/docker"
 	"compman/internal/strategy"
 	"compman/internal/ui"
 	"compman/pkg/types"
 )
 
+// failureLogTail 更新失败时每个异常容器附带的日志行数
+const failureLogTail = 20
+
+// 未配置 pull_timeout、up_timeout 和 timeout 时使用的默认超时时间
+const (
+	defaultPullTimeout = 30 * time.Minute
+	defaultUpTimeout   = 2 * time.Minute
+)
+
 // Updater 负责更新 Docker Compose 文件中的镜像
 type Updater struct {
-	config   *types.Config
-	parser   *Parser
-	strategy types.ImageTagStrategy
+	config          *types.Config
+	parser          *Parser
+	strategy        types.ImageTagStrategy
+	versionComparer *strategy.SemverStrategy // 用于 --since-tag 的版本比较，未指定时为 nil
+	composeEnv      []string                 // 传递给 docker-compose 命令的额外环境变量
+	imageManager    *docker.ImageManager     // 比较修改标签前后镜像的清单摘要
+	composeVersion  string                   // 实际使用的 Compose 命令版本 (v1 或 v2)
+	servicesOnly    *regexp.Regexp           // --services-only 服务名过滤条件，未指定时为 nil
+	verbose         bool                     // 详细模式，执行 docker-compose 命令前输出完整命令
+	rewrittenImages map[string]string        // 按 --image-registry-map 替换的镜像 (原镜像 -> 新镜像)
+	rewriteMutex    sync.Mutex
 }
 
 // NewUpdater 创建一个新的更新器
-func NewUpdater(config *types.Config) *Updater {
+func NewUpdater(config *types.Config) (*Updater, error) {
+	// 根据配置选择标签策略
+	tagStrategy, err := strategy.NewFromString(config.ImageTagStrategy, config)
+	if err != nil {
+		return nil, err
+	}
+
 	updater := &Updater{
-		config: config,
-		parser: NewParser(),
+		config:         config,
+		parser:         NewParser(),
+		strategy:       tagStrategy,
+		imageManager:   docker.NewImageManagerWithClient(docker.NewClient(), config.RegistryAPITimeout),
+		composeVersion: config.ComposeVersion,
+	}
+	// 未指定 Compose 版本时检测 docker compose 插件是否可用
+	if updater.composeVersion == "" || updater.composeVersion == "auto" {
+		updater.composeVersion = docker.NewClient().ComposeVersion()
 	}
+	if err := updater.imageManager.SetPlatform(config.Platform); err != nil {
+		return nil, err
+	}
+	updater.parser.SetEncoding(config.ComposeEncoding)
 
-	// 根据配置选择标签策略
-	switch config.ImageTagStrategy {
-	case "semver":
-		updater.strategy = strategy.NewSemverStrategy(config.SemverPattern)
-	default:
-		updater.strategy = strategy.NewLatestStrategy()
+	if config.ServicesOnly != "" {
+		pattern, err := regexp.Compile(config.ServicesOnly)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 --services-only 正则表达式 %q: %v", config.ServicesOnly, err)
+		}
+		updater.servicesOnly = pattern
+	}
+
+	// --since-tag 需要按语义版本比较，即使当前使用 latest 策略
+	if config.SinceTag != "" {
+		if semverStrategy, ok := tagStrategy.(*strategy.SemverStrategy); ok {
+			updater.versionComparer = semverStrategy
+		} else {
+			updater.versionComparer = strategy.NewSemverStrategy("")
+		}
+	}
+
+	// 让 docker-compose 命令使用相同的 profiles
+	if len(config.ComposeProfiles) > 0 {
+		updater.composeEnv = append(updater.composeEnv, "COMPOSE_PROFILES="+strings.Join(config.ComposeProfiles, ","))
+	}
+
+	// 按目标平台拉取镜像并创建容器，docker-compose 通过 DOCKER_DEFAULT_PLATFORM 支持该设置
+	if config.Platform != "" {
+		updater.composeEnv = append(updater.composeEnv, "DOCKER_DEFAULT_PLATFORM="+config.Platform)
+	}
+
+	// 让 docker-compose 命令连接同一个远程 Docker daemon
+	// docker-compose 通过 ssh 命令连接，私钥需在 ssh 配置或 ssh-agent 中提供
+	if config.DockerConfig.SSHHost != "" {
+		updater.composeEnv = append(updater.composeEnv, "DOCKER_HOST="+docker.SSHHostURL(config.DockerConfig.SSHHost))
 	}
 
-	return updater
+	return updater, nil
+}
+
+// SetVerbose 设置详细模式
+func (u *Updater) SetVerbose(verbose bool) {
+	u.verbose = verbose
 }
 
 // UpdateImages 使用 docker-compose 命令更新多个 Compose 文件
 func (u *Updater) UpdateImages(composeFiles []*types.ComposeFile) ([]*types.UpdateResult, error) {
-	var allResults []*types.UpdateResult
-
-	for _, cf := range composeFiles {
+	return u.processFiles(composeFiles, func(i int, cf *types.ComposeFile) []*types.UpdateResult {
+		fileStart := time.Now()
 		results, err := u.updateComposeFileSimple(cf)
 		if err != nil {
 			// 如果更新失败，记录错误但继续处理其他文件
-			result := &types.UpdateResult{
-				Service:   fmt.Sprintf("文件: %s", filepath.Base(cf.FilePath)),
-				OldImage:  "N/A",
-				NewImage:  "N/A",
-				Success:   false,
-				Error:     err,
-				UpdatedAt: time.Now(),
-			}
-			allResults = append(allResults, result)
-			continue
+			return []*types.UpdateResult{fileErrorResult(cf, err, time.Since(fileStart))}
 		}
-		allResults = append(allResults, results...)
-	}
-
-	return allResults, nil
+		setResultsDuration(results, time.Since(fileStart))
+		if i < len(composeFiles)-1 && u.waitsForStable(results) {
+			u.waitForStable(nil)
+		}
+		return results
+	}), nil
 }
 
-// UpdateImagesWithProgress 使用 docker-compose 命令更新多个 Compose 文件，并显示详细进度
+// UpdateImagesWithProgress 使用 docker-compose 命令依次更新多个 Compose 文件，并显示详细进度
+// 启用 --fail-fast 时，某个文件更新失败后不再处理剩余的文件，这些文件记录为跳过
 func (u *Updater) UpdateImagesWithProgress(composeFiles []*types.ComposeFile, progressBar *ui.ProgressBar) ([]*types.UpdateResult, error) {
-	var allResults []*types.UpdateResult
+	fileResults := make([][]*types.UpdateResult, len(composeFiles))
+	processed := make([]bool, len(composeFiles))
+	aborted := false
 
 	for i, cf := range composeFiles {
+		if aborted {
+			fileResults[i] = []*types.UpdateResult{failFastSkipResult(cf)}
+			continue
+		}
+		processed[i] = true
+
+		fileStart := time.Now()
 		results, err := u.updateComposeFileWithProgress(cf, progressBar, i, len(composeFiles))
 		if err != nil {
-			// 如果更新失败，记录错误但继续处理其他文件
-			result := &types.UpdateResult{
-				Service:   fmt.Sprintf("文件: %s", filepath.Base(cf.FilePath)),
-				OldImage:  "N/A",
-				NewImage:  "N/A",
-				Success:   false,
-				Error:     err,
-				UpdatedAt: time.Now(),
-			}
-			allResults = append(allResults, result)
+			// 如果更新失败，记录错误，未启用 --fail-fast 时继续处理其他文件
+			fileResults[i] = []*types.UpdateResult{fileErrorResult(cf, err, time.Since(fileStart))}
+			aborted = u.config.FailFast
 		} else {
-			allResults = append(allResults, results...)
+			setResultsDuration(results, time.Since(fileStart))
+			fileResults[i] = results
+			aborted = u.config.FailFast && hasFailedResult(results)
+			if !aborted && i < len(composeFiles)-1 && u.waitsForStable(results) {
+				u.waitForStable(func(remaining time.Duration) {
+					progressBar.SetCurrentOperation(fmt.Sprintf("⏳ 等待服务稳定，剩余 %s", remaining))
+				})
+			}
 		}
 
 		// 更新进度，但如果是最后一个文件则让 Finish() 处理
@@ -96,20 +167,20 @@ func (u *Updater) UpdateImagesWithProgress(composeFiles []*types.ComposeFile, pr
 		}
 	}
 
-	return allResults, nil
+	return u.collectFileResults(composeFiles, fileResults, processed), nil
 }
 
 // UpdateImagesWithMultiProgress 使用多进度条更新多个 Compose 文件
+// 配置了 MaxParallel 大于 1 时多个文件会并行处理，结果仍按文件顺序返回
 func (u *Updater) UpdateImagesWithMultiProgress(composeFiles []*types.ComposeFile, multiProgressBar *ui.MultiProgressBar) ([]*types.UpdateResult, error) {
-	var allResults []*types.UpdateResult
-
 	// 首先渲染所有进度条的初始状态
 	for i := range composeFiles {
 		multiProgressBar.UpdateFile(i, 0, "等待中...")
 	}
 
-	for i, cf := range composeFiles {
+	return u.processFiles(composeFiles, func(i int, cf *types.ComposeFile) []*types.UpdateResult {
 		// 开始处理文件
+		fileStart := time.Now()
 		multiProgressBar.UpdateFile(i, 5, "📄 准备处理...")
 		time.Sleep(300 * time.Millisecond)
 
@@ -117,22 +188,229 @@ func (u *Updater) UpdateImagesWithMultiProgress(composeFiles []*types.ComposeFil
 		if err != nil {
 			// 如果更新失败，标记为失败
 			multiProgressBar.UpdateFile(i, 100, "❌ 处理失败")
-			result := &types.UpdateResult{
-				Service:   fmt.Sprintf("文件: %s", filepath.Base(cf.FilePath)),
-				OldImage:  "N/A",
-				NewImage:  "N/A",
-				Success:   false,
-				Error:     err,
-				UpdatedAt: time.Now(),
+			return []*types.UpdateResult{fileErrorResult(cf, err, time.Since(fileStart))}
+		}
+
+		setResultsDuration(results, time.Since(fileStart))
+		if i < len(composeFiles)-1 && u.waitsForStable(results) {
+			u.waitForStable(func(remaining time.Duration) {
+				multiProgressBar.UpdateFile(i, 100, fmt.Sprintf("⏳ 等待服务稳定，剩余 %s", remaining))
+			})
+		}
+		multiProgressBar.FinishFile(i)
+		return results
+	}), nil
+}
+
+// 更新策略
+const (
+	UpdateStrategyFileByFile = "file-by-file" // 逐个文件完成拉取和重启后再处理下一个文件
+	UpdateStrategyBatch      = "batch"        // 先拉取所有文件的镜像，再依次重启服务
+)
+
+// UpdateStrategies 支持的更新策略
+var UpdateStrategies = []string{UpdateStrategyFileByFile, UpdateStrategyBatch}
+
+// BatchUpdateImages 按 batch 策略更新多个 Compose 文件：先并发拉取所有文件的镜像，全部完成后再按文件顺序依次重启服务，
+// 拉取期间不重启任何容器，缩短服务中断的时间。并发拉取数由 MaxParallel 限制，为 1 时依次拉取，为 0 时不限制
+// 拉取失败的文件不会重启；启用 --fail-fast 时，某个文件失败后不再重启剩余的文件，这些文件已拉取的镜像和修改后的标签会保留
+func (u *Updater) BatchUpdateImages(composeFiles []*types.ComposeFile) ([]*types.UpdateResult, error) {
+	pulled := make([]*pulledFile, len(composeFiles))
+	pullErrors := make([]error, len(composeFiles))
+	pullDurations := make([]time.Duration, len(composeFiles))
+
+	parallel := u.config.MaxParallel
+	if parallel <= 0 {
+		parallel = len(composeFiles)
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallel)
+	for i, cf := range composeFiles {
+		wg.Add(1)
+		go func(i int, cf *types.ComposeFile) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			start := time.Now()
+			pulled[i], pullErrors[i] = u.pullComposeFile(cf)
+			pullDurations[i] = time.Since(start)
+		}(i, cf)
+	}
+	wg.Wait()
+
+	fileResults := make([][]*types.UpdateResult, len(composeFiles))
+	processed := make([]bool, len(composeFiles))
+	aborted := false
+	for i, cf := range composeFiles {
+		if aborted {
+			fileResults[i] = []*types.UpdateResult{failFastSkipResult(cf)}
+			continue
+		}
+		processed[i] = true
+
+		if pullErrors[i] != nil {
+			fileResults[i] = []*types.UpdateResult{fileErrorResult(cf, pullErrors[i], pullDurations[i])}
+			aborted = u.config.FailFast
+			continue
+		}
+
+		start := time.Now()
+		results, err := u.restartPulledFile(pulled[i])
+		duration := pullDurations[i] + time.Since(start)
+		if err != nil {
+			fileResults[i] = []*types.UpdateResult{fileErrorResult(cf, err, duration)}
+			aborted = u.config.FailFast
+			continue
+		}
+		setResultsDuration(results, duration)
+		fileResults[i] = results
+		if u.config.FailFast && hasFailedResult(results) {
+			aborted = true
+			continue
+		}
+
+		if i < len(composeFiles)-1 && pulled[i].cf != nil && u.waitsForStable(results) {
+			u.waitForStable(nil)
+		}
+	}
+
+	return u.collectFileResults(composeFiles, fileResults, processed), nil
+}
+
+// waitsForStable 判断文件更新完成后是否需要等待 --wait-for-stable，只有成功重建了容器的文件才需要等待
+func (u *Updater) waitsForStable(results []*types.UpdateResult) bool {
+	return u.config.WaitForStable > 0 && !u.config.DryRun && !u.config.NoUp && !hasFailedResult(results)
+}
+
+// waitForStable 等待 --wait-for-stable 指定的时间再处理下一个文件，每秒调用一次 onTick 显示剩余时间
+func (u *Updater) waitForStable(onTick func(remaining time.Duration)) {
+	deadline := time.Now().Add(u.config.WaitForStable)
+	for remaining := u.config.WaitForStable; remaining > 0; remaining = time.Until(deadline) {
+		if onTick != nil {
+			onTick(remaining.Round(time.Second))
+		}
+		if remaining > time.Second {
+			remaining = time.Second
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// processFiles 依次或并行处理每个 Compose 文件，并发数由 MaxParallel 控制，返回按文件顺序合并的结果
+// 启用 --fail-fast 时，某个文件更新失败后不再处理尚未开始的文件，这些文件记录为跳过
+func (u *Updater) processFiles(composeFiles []*types.ComposeFile, process func(i int, cf *types.ComposeFile) []*types.UpdateResult) []*types.UpdateResult {
+	fileResults := make([][]*types.UpdateResult, len(composeFiles))
+	processed := make([]bool, len(composeFiles))
+
+	var abortMutex sync.Mutex
+	aborted := false
+	shouldAbort := func() bool {
+		abortMutex.Lock()
+		defer abortMutex.Unlock()
+		return aborted
+	}
+	finish := func(i int, results []*types.UpdateResult) {
+		abortMutex.Lock()
+		defer abortMutex.Unlock()
+		fileResults[i] = results
+		processed[i] = true
+		if u.config.FailFast && hasFailedResult(results) {
+			aborted = true
+		}
+	}
+
+	maxParallel := u.config.MaxParallel
+	if maxParallel <= 1 {
+		for i, cf := range composeFiles {
+			if shouldAbort() {
+				break
 			}
-			allResults = append(allResults, result)
-		} else {
-			allResults = append(allResults, results...)
-			multiProgressBar.FinishFile(i)
+			finish(i, process(i, cf))
+		}
+	} else {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, maxParallel)
+		for i, cf := range composeFiles {
+			semaphore <- struct{}{}
+			if shouldAbort() {
+				<-semaphore
+				break
+			}
+			wg.Add(1)
+			go func(i int, cf *types.ComposeFile) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				finish(i, process(i, cf))
+			}(i, cf)
+		}
+		wg.Wait()
+	}
+
+	for i, cf := range composeFiles {
+		if !processed[i] {
+			fileResults[i] = []*types.UpdateResult{failFastSkipResult(cf)}
 		}
 	}
 
-	return allResults, nil
+	return u.collectFileResults(composeFiles, fileResults, processed)
+}
+
+// collectFileResults 记录更新成功的文件的校验和，并按文件顺序合并结果
+// 保存校验和失败不影响更新结果
+func (u *Updater) collectFileResults(composeFiles []*types.ComposeFile, fileResults [][]*types.UpdateResult, processed []bool) []*types.UpdateResult {
+	if !u.config.DryRun {
+		var updatedFiles []string
+		for i, results := range fileResults {
+			if processed[i] && !hasFailedResult(results) {
+				updatedFiles = append(updatedFiles, composeFiles[i].FilePath)
+			}
+		}
+		_ = RecordChecksums(updatedFiles)
+	}
+
+	var allResults []*types.UpdateResult
+	for _, results := range fileResults {
+		allResults = append(allResults, results...)
+	}
+	return allResults
+}
+
+// FailFastSkipReason 因 --fail-fast 未处理的文件的跳过原因
+const FailFastSkipReason = "前面的文件更新失败，已因 --fail-fast 中止"
+
+// failFastSkipResult 创建表示文件因 --fail-fast 未处理的结果
+func failFastSkipResult(cf *types.ComposeFile) *types.UpdateResult {
+	return &types.UpdateResult{
+		Service:    fmt.Sprintf("文件: %s", filepath.Base(cf.FilePath)),
+		OldImage:   "N/A",
+		NewImage:   "N/A",
+		UpdatedAt:  time.Now(),
+		SkipReason: FailFastSkipReason,
+	}
+}
+
+// hasFailedResult 判断结果中是否存在失败的服务
+func hasFailedResult(results []*types.UpdateResult) bool {
+	for _, result := range results {
+		if !result.Success && result.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fileErrorResult 创建表示整个文件处理失败的结果
+func fileErrorResult(cf *types.ComposeFile, err error, duration time.Duration) *types.UpdateResult {
+	return &types.UpdateResult{
+		Service:   fmt.Sprintf("文件: %s", filepath.Base(cf.FilePath)),
+		OldImage:  "N/A",
+		NewImage:  "N/A",
+		Success:   false,
+		Error:     err,
+		UpdatedAt: time.Now(),
+		Duration:  duration,
+	}
 }
 
 // updateComposeFileWithMultiProgress 使用多进度条更新单个文件
@@ -148,6 +426,16 @@ func (u *Updater) updateComposeFileWithMultiProgress(cf *types.ComposeFile, mult
 		return nil, fmt.Errorf("文件不存在: %s", cf.FilePath)
 	}
 
+	// 按选中的服务、--services-only 和 --since-tag 过滤服务，被跳过的服务直接记录结果
+	cf = u.filterSelectedServices(cf)
+	cf, skipped := u.filterServicesByName(cf)
+	results = append(results, skipped...)
+	cf, skipped = u.filterServicesBySinceTag(cf)
+	results = append(results, skipped...)
+	if len(cf.Services) == 0 {
+		return results, nil
+	}
+
 	// 如果是干运行模式，只模拟操作
 	if u.config.DryRun {
 		multiProgressBar.UpdateFile(fileIndex, 20, "🧪 模拟模式 - 初始化...")
@@ -164,35 +452,80 @@ func (u *Updater) updateComposeFileWithMultiProgress(cf *types.ComposeFile, mult
 
 		for serviceName := range cf.Services {
 			result := &types.UpdateResult{
-				Service:   serviceName,
-				OldImage:  "模拟 - 当前镜像",
-				NewImage:  "模拟 - 最新镜像",
-				Success:   true,
-				Error:     nil,
-				UpdatedAt: time.Now(),
+				Service:    serviceName,
+				OldImage:   "模拟 - 当前镜像",
+				NewImage:   "模拟 - 最新镜像",
+				Success:    true,
+				Error:      nil,
+				UpdatedAt:  time.Now(),
+				SkipReason: "干运行模式",
 			}
 			results = append(results, result)
 		}
 		return results, nil
 	}
 
+	var backupPath string
+	if u.backsUpBeforePull() {
+		multiProgressBar.UpdateFile(fileIndex, 10, "💾 正在备份文件...")
+		path, err := u.backupBeforePull(cf)
+		if err != nil {
+			return nil, err
+		}
+		backupPath = path
+	}
+	runningImages := u.recordRunningImages(cf)
+
+	multiProgressBar.UpdateFile(fileIndex, 10, "🔍 正在检查镜像版本...")
+	previousImages, upToDate, err := u.applyTagUpdates(cf)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已是最新版本的服务不再拉取和重启
+	cf, skipped = u.skipUpToDateServices(cf, upToDate)
+	results = append(results, skipped...)
+	if len(cf.Services) == 0 {
+		return results, nil
+	}
+
+	if u.config.RestartPolicy != "" {
+		multiProgressBar.UpdateFile(fileIndex, 20, "🔧 正在设置重启策略...")
+		if err := u.applyRestartPolicy(cf); err != nil {
+			return nil, err
+		}
+	}
+
 	// 第一步：拉取镜像
 	multiProgressBar.UpdateFile(fileIndex, 30, "⬇️ 正在拉取最新镜像...")
-	pullResults, err := u.executeDockerComposePullWithMultiProgress(dir, fileName, cf, multiProgressBar, fileIndex)
+	var pullResults []*types.UpdateResult
+	err = u.forEachDependencyGroup(cf, func(group *types.ComposeFile) error {
+		groupResults, err := u.executeDockerComposePullWithMultiProgress(dir, fileName, group, multiProgressBar, fileIndex)
+		pullResults = append(pullResults, groupResults...)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("拉取镜像失败: %v", err)
 	}
 
-	// 第二步：重启服务
-	multiProgressBar.UpdateFile(fileIndex, 70, "🔄 正在重启服务...")
-	upResults, err := u.executeDockerComposeUpWithMultiProgress(dir, fileName, cf, multiProgressBar, fileIndex)
-	if err != nil {
-		return nil, fmt.Errorf("重启服务失败: %v", err)
+	// 第二步：重新构建只有 build 配置的服务
+	results = append(results, pullResults...)
+	if u.config.ImageBuild {
+		multiProgressBar.UpdateFile(fileIndex, 50, "🔨 正在重新构建镜像...")
+		results = append(results, u.buildServices(dir, fileName, cf)...)
 	}
 
-	// 合并结果
-	results = append(results, pullResults...)
-	results = append(results, upResults...)
+	// 第三步：重启服务，--no-up 时保留正在运行的容器
+	if !u.config.NoUp {
+		multiProgressBar.UpdateFile(fileIndex, 70, "🔄 正在重启服务...")
+		upResults, err := u.executeDockerComposeUpWithMultiProgress(dir, fileName, cf, multiProgressBar, fileIndex)
+		if err != nil {
+			return nil, fmt.Errorf("重启服务失败: %v%s", err, u.failureDiagnostics(cf))
+		}
+		results = append(results, upResults...)
+	}
+	u.setPreviousImages(results, previousImages, cf)
+	results = u.rollbackIfUnhealthy(cf, backupPath, previousImages, runningImages, results)
 
 	return results, nil
 }
@@ -210,6 +543,16 @@ func (u *Updater) updateComposeFileWithProgress(cf *types.ComposeFile, progressB
 		return nil, fmt.Errorf("文件不存在: %s", cf.FilePath)
 	}
 
+	// 按选中的服务、--services-only 和 --since-tag 过滤服务，被跳过的服务直接记录结果
+	cf = u.filterSelectedServices(cf)
+	cf, skipped := u.filterServicesByName(cf)
+	results = append(results, skipped...)
+	cf, skipped = u.filterServicesBySinceTag(cf)
+	results = append(results, skipped...)
+	if len(cf.Services) == 0 {
+		return results, nil
+	}
+
 	// 显示正在处理的文件
 	progressBar.SetCurrentOperation(fmt.Sprintf("📄 处理文件: %s", fileName))
 
@@ -218,35 +561,80 @@ func (u *Updater) updateComposeFileWithProgress(cf *types.ComposeFile, progressB
 		progressBar.SetCurrentOperation("🧪 模拟模式 - 跳过实际更新")
 		for serviceName := range cf.Services {
 			result := &types.UpdateResult{
-				Service:   serviceName,
-				OldImage:  "模拟 - 当前镜像",
-				NewImage:  "模拟 - 最新镜像",
-				Success:   true,
-				Error:     nil,
-				UpdatedAt: time.Now(),
+				Service:    serviceName,
+				OldImage:   "模拟 - 当前镜像",
+				NewImage:   "模拟 - 最新镜像",
+				Success:    true,
+				Error:      nil,
+				UpdatedAt:  time.Now(),
+				SkipReason: "干运行模式",
 			}
 			results = append(results, result)
 		}
 		return results, nil
 	}
 
+	var backupPath string
+	if u.backsUpBeforePull() {
+		progressBar.SetCurrentOperation("💾 正在备份文件...")
+		path, err := u.backupBeforePull(cf)
+		if err != nil {
+			return nil, err
+		}
+		backupPath = path
+	}
+	runningImages := u.recordRunningImages(cf)
+
+	progressBar.SetCurrentOperation("🔍 正在检查镜像版本...")
+	previousImages, upToDate, err := u.applyTagUpdates(cf)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已是最新版本的服务不再拉取和重启
+	cf, skipped = u.skipUpToDateServices(cf, upToDate)
+	results = append(results, skipped...)
+	if len(cf.Services) == 0 {
+		return results, nil
+	}
+
+	if u.config.RestartPolicy != "" {
+		progressBar.SetCurrentOperation("🔧 正在设置重启策略...")
+		if err := u.applyRestartPolicy(cf); err != nil {
+			return nil, err
+		}
+	}
+
 	// 第一步：拉取镜像
 	progressBar.SetCurrentOperation("⬇️ 正在拉取最新镜像...")
-	pullResults, err := u.executeDockerComposePullWithProgress(dir, fileName, cf, progressBar, fileIndex)
+	var pullResults []*types.UpdateResult
+	err = u.forEachDependencyGroup(cf, func(group *types.ComposeFile) error {
+		groupResults, err := u.executeDockerComposePullWithProgress(dir, fileName, group, progressBar, fileIndex)
+		pullResults = append(pullResults, groupResults...)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("拉取镜像失败: %v", err)
 	}
 
-	// 第二步：重启服务
-	progressBar.SetCurrentOperation("🔄 正在重启服务...")
-	upResults, err := u.executeDockerComposeUpWithProgress(dir, fileName, cf, progressBar, fileIndex)
-	if err != nil {
-		return nil, fmt.Errorf("重启服务失败: %v", err)
+	// 第二步：重新构建只有 build 配置的服务
+	results = append(results, pullResults...)
+	if u.config.ImageBuild {
+		progressBar.SetCurrentOperation("🔨 正在重新构建镜像...")
+		results = append(results, u.buildServices(dir, fileName, cf)...)
 	}
 
-	// 合并结果
-	results = append(results, pullResults...)
-	results = append(results, upResults...)
+	// 第三步：重启服务，--no-up 时保留正在运行的容器
+	if !u.config.NoUp {
+		progressBar.SetCurrentOperation("🔄 正在重启服务...")
+		upResults, err := u.executeDockerComposeUpWithProgress(dir, fileName, cf, progressBar, fileIndex)
+		if err != nil {
+			return nil, fmt.Errorf("重启服务失败: %v%s", err, u.failureDiagnostics(cf))
+		}
+		results = append(results, upResults...)
+	}
+	u.setPreviousImages(results, previousImages, cf)
+	results = u.rollbackIfUnhealthy(cf, backupPath, previousImages, runningImages, results)
 
 	return results, nil
 }
@@ -255,20 +643,23 @@ func (u *Updater) updateComposeFileWithProgress(cf *types.ComposeFile, progressB
 func (u *Updater) executeDockerComposePullWithProgress(dir, fileName string, cf *types.ComposeFile, progressBar *ui.ProgressBar, fileIndex int) ([]*types.UpdateResult, error) {
 	var results []*types.UpdateResult
 
-	// 构建 docker-compose pull 命令
-	var cmd *exec.Cmd
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		cmd = exec.Command("docker-compose", "pull")
-	} else {
-		cmd = exec.Command("docker-compose", "-f", fileName, "pull")
+	if u.config.UseDirectPull {
+		return u.pullImagesDirect(cf, func(image string, progress docker.PullProgress) {
+			progressBar.SetCurrentOperation(formatPullProgress(image, progress))
+		}), nil
 	}
+
+	// 构建 docker-compose pull 命令
+	cmd := u.composeCommand(fileName, "pull")
+	cmd.Args = append(cmd.Args, u.serviceArgs(cf)...)
 	cmd.Dir = dir
 
 	// 创建上下文以便取消操作
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), u.pullTimeout())
 	defer cancel()
 	cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
 	cmd.Dir = dir
+	u.applyComposeEnv(cmd)
 
 	// 获取命令输出管道
 	stdout, err := cmd.StdoutPipe()
@@ -322,19 +713,16 @@ func (u *Updater) executeDockerComposeUpWithProgress(dir, fileName string, cf *t
 	var results []*types.UpdateResult
 
 	// 构建 docker-compose up -d 命令
-	var cmd *exec.Cmd
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		cmd = exec.Command("docker-compose", "up", "-d")
-	} else {
-		cmd = exec.Command("docker-compose", "-f", fileName, "up", "-d")
-	}
+	cmd := u.upCommand(fileName)
+	cmd.Args = append(cmd.Args, u.serviceArgs(cf)...)
 	cmd.Dir = dir
 
 	// 创建上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), u.upTimeout())
 	defer cancel()
 	cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
 	cmd.Dir = dir
+	u.applyComposeEnv(cmd)
 
 	// 获取输出
 	output, err := cmd.CombinedOutput()
@@ -358,10 +746,14 @@ func (u *Updater) executeDockerComposeUpWithProgress(dir, fileName string, cf *t
 		outputStr := string(output)
 		if strings.Contains(outputStr, serviceName) && (strings.Contains(outputStr, "Starting") || strings.Contains(outputStr, "Recreating")) {
 			result.NewImage = service.Image + " (已重启)"
+			result.Changed = true
 		}
 
 		results = append(results, result)
 	}
+	if err == nil {
+		results = append(results, orphanResults(output)...)
+	}
 
 	return results, nil
 }
@@ -418,8 +810,29 @@ func (u *Updater) monitorPullProgress(stdout, stderr io.ReadCloser, progressBar
 		}
 	}()
 }
+
+// updateComposeFileSimple 更新单个文件：拉取镜像后立即重启服务
 func (u *Updater) updateComposeFileSimple(cf *types.ComposeFile) ([]*types.UpdateResult, error) {
-	var results []*types.UpdateResult
+	pulled, err := u.pullComposeFile(cf)
+	if err != nil {
+		return nil, err
+	}
+	return u.restartPulledFile(pulled)
+}
+
+// pulledFile 已完成拉取、等待重启服务的 Compose 文件，batch 更新策略在所有文件拉取完成后再依次重启
+type pulledFile struct {
+	cf             *types.ComposeFile    // 过滤后需要重启的服务，为 nil 时没有需要重启的服务
+	results        []*types.UpdateResult // 被过滤、跳过或干运行模拟的服务结果
+	pullOutput     []byte                // docker-compose pull 的输出，用于判断服务是否有更新
+	previousImages map[string]string     // 修改标签前的镜像 (服务 -> 原镜像)
+	runningImages  map[string]string     // 拉取前运行中容器的镜像 (服务 -> 仓库@摘要)，用于回滚
+	backupPath     string                // 拉取前的备份文件，用于回滚
+}
+
+// pullComposeFile 过滤服务、修改镜像标签并拉取镜像，不重启服务
+func (u *Updater) pullComposeFile(cf *types.ComposeFile) (*pulledFile, error) {
+	pulled := &pulledFile{}
 
 	// 获取文件目录
 	dir := filepath.Dir(cf.FilePath)
@@ -430,68 +843,132 @@ func (u *Updater) updateComposeFileSimple(cf *types.ComposeFile) ([]*types.Updat
 		return nil, fmt.Errorf("文件不存在: %s", cf.FilePath)
 	}
 
+	// 按选中的服务、--services-only 和 --since-tag 过滤服务，被跳过的服务直接记录结果
+	cf = u.filterSelectedServices(cf)
+	cf, skipped := u.filterServicesByName(cf)
+	pulled.results = append(pulled.results, skipped...)
+	cf, skipped = u.filterServicesBySinceTag(cf)
+	pulled.results = append(pulled.results, skipped...)
+	if len(cf.Services) == 0 {
+		return pulled, nil
+	}
+
 	// 如果是干运行模式，只模拟操作
 	if u.config.DryRun {
 		for serviceName := range cf.Services {
 			result := &types.UpdateResult{
-				Service:   serviceName,
-				OldImage:  "模拟 - 当前镜像",
-				NewImage:  "模拟 - 最新镜像",
-				Success:   true,
-				Error:     nil,
-				UpdatedAt: time.Now(),
+				Service:    serviceName,
+				OldImage:   "模拟 - 当前镜像",
+				NewImage:   "模拟 - 最新镜像",
+				Success:    true,
+				Error:      nil,
+				UpdatedAt:  time.Now(),
+				SkipReason: "干运行模式",
 			}
-			results = append(results, result)
+			pulled.results = append(pulled.results, result)
 		}
-		return results, nil
+		return pulled, nil
 	}
 
-	// 构建 docker-compose pull 命令
-	var cmd *exec.Cmd
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		// 使用默认文件名
-		cmd = exec.Command("docker-compose", "pull")
-	} else {
-		// 指定文件名
-		cmd = exec.Command("docker-compose", "-f", fileName, "pull")
+	if u.backsUpBeforePull() {
+		path, err := u.backupBeforePull(cf)
+		if err != nil {
+			return nil, err
+		}
+		pulled.backupPath = path
 	}
+	pulled.runningImages = u.recordRunningImages(cf)
 
-	cmd.Dir = dir
-
-	// 执行 pull 命令
-	output, err := cmd.CombinedOutput()
+	previousImages, upToDate, err := u.applyTagUpdates(cf)
 	if err != nil {
-		return nil, fmt.Errorf("执行 docker-compose pull 失败: %v\n输出: %s", err, string(output))
+		return nil, err
 	}
+	pulled.previousImages = previousImages
 
-	// 构建 docker-compose up -d 命令
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		cmd = exec.Command("docker-compose", "up", "-d")
-	} else {
-		cmd = exec.Command("docker-compose", "-f", fileName, "up", "-d")
+	// 已是最新版本的服务不再拉取和重启
+	cf, skipped = u.skipUpToDateServices(cf, upToDate)
+	pulled.results = append(pulled.results, skipped...)
+	if len(cf.Services) == 0 {
+		return pulled, nil
 	}
-	cmd.Dir = dir
 
-	upOutput, err := cmd.CombinedOutput()
+	err = u.forEachDependencyGroup(cf, func(group *types.ComposeFile) error {
+		if u.config.UseDirectPull {
+			for _, result := range u.pullImagesDirect(group, nil) {
+				if result.Error != nil {
+					return result.Error
+				}
+			}
+			return nil
+		}
+
+		// 构建 docker-compose pull 命令，超过 pull_timeout 时终止
+		ctx, cancel := context.WithTimeout(context.Background(), u.pullTimeout())
+		defer cancel()
+		cmd := u.composeCommand(fileName, "pull")
+		cmd.Args = append(cmd.Args, u.serviceArgs(group)...)
+		cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
+		cmd.Dir = dir
+		u.applyComposeEnv(cmd)
+
+		// 执行 pull 命令，按退出状态判断是否成功
+		groupOutput, err := cmd.CombinedOutput()
+		pulled.pullOutput = append(pulled.pullOutput, groupOutput...)
+		if err != nil {
+			return fmt.Errorf("执行 docker-compose pull 失败: %v\n输出: %s", err, string(groupOutput))
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("执行 docker-compose up -d 失败: %v\n输出: %s", err, string(upOutput))
+		return nil, err
+	}
+
+	pulled.cf = cf
+	return pulled, nil
+}
+
+// restartPulledFile 重新构建只有 build 配置的服务并执行 up -d 重启服务，返回文件中所有服务的结果
+func (u *Updater) restartPulledFile(pulled *pulledFile) ([]*types.UpdateResult, error) {
+	results := pulled.results
+	cf := pulled.cf
+	if cf == nil {
+		return results, nil
+	}
+
+	dir := filepath.Dir(cf.FilePath)
+	fileName := filepath.Base(cf.FilePath)
+
+	var buildResults []*types.UpdateResult
+	if u.config.ImageBuild {
+		buildResults = u.buildServices(dir, fileName, cf)
+	}
+
+	// 构建 docker-compose up -d 命令，--no-up 时保留正在运行的容器，超过 up_timeout 时终止
+	var upOutput []byte
+	if !u.config.NoUp {
+		ctx, cancel := context.WithTimeout(context.Background(), u.upTimeout())
+		defer cancel()
+		cmd := u.upCommand(fileName)
+		cmd.Args = append(cmd.Args, u.serviceArgs(cf)...)
+		cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
+		cmd.Dir = dir
+		u.applyComposeEnv(cmd)
+
+		var err error
+		upOutput, err = cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("执行 docker-compose up -d 失败: %v\n输出: %s%s", err, string(upOutput), u.failureDiagnostics(cf))
+		}
 	}
 
-	// 解析输出并创建结果
-	pullOutputStr := string(output)
-	upOutputStr := string(upOutput)
+	// pull 和 up -d 都以零状态退出，根据拉取输出判断服务是否有更新
+	pullOutputStr := string(pulled.pullOutput)
 
 	for serviceName, service := range cf.Services {
 		if service.Image == "" {
 			continue // 跳过没有镜像的服务
 		}
 
-		// 检查是否有错误
-		hasError := strings.Contains(pullOutputStr, "ERROR") ||
-			strings.Contains(upOutputStr, "ERROR") ||
-			strings.Contains(pullOutputStr, "failed") ||
-			strings.Contains(upOutputStr, "failed")
-
 		// 检查是否有更新
 		serviceUpdated := strings.Contains(pullOutputStr, serviceName) &&
 			(strings.Contains(pullOutputStr, "Pulling") ||
@@ -501,83 +978,864 @@ func (u *Updater) updateComposeFileSimple(cf *types.ComposeFile) ([]*types.Updat
 			Service:   serviceName,
 			OldImage:  service.Image,
 			NewImage:  service.Image,
-			Success:   !hasError,
+			Success:   true,
 			Error:     nil,
 			UpdatedAt: time.Now(),
 		}
 
-		if hasError {
-			result.Error = fmt.Errorf("更新过程中出现错误，请检查日志")
-		} else if serviceUpdated {
+		if serviceUpdated {
 			result.NewImage = service.Image + " (已更新)"
+			result.Changed = true
 		}
 
 		results = append(results, result)
 	}
+	results = append(results, buildResults...)
+	u.setPreviousImages(results, pulled.previousImages, cf)
+	results = append(results, orphanResults(upOutput)...)
+	results = u.rollbackIfUnhealthy(cf, pulled.backupPath, pulled.previousImages, pulled.runningImages, results)
 
 	return results, nil
 }
 
-// getSelectedServices 获取选择的服务列表
-func (u *Updater) getSelectedServices(filePath string) []string {
-	if u.config.SelectedServices != nil {
-		return u.config.SelectedServices[filePath]
-	}
-	return nil
-}
-
-// shouldExcludeImage 检查是否应该排除镜像
-func (u *Updater) shouldExcludeImage(image string) bool {
-	for _, excludePattern := range u.config.ExcludeImages {
-		if strings.Contains(image, excludePattern) {
-			return true
+// buildServices 对只有 build 配置 (没有 image) 的服务执行 docker-compose build --no-cache，
+// 之后的 up -d 会使用重新构建的镜像重建容器；构建失败时记录到对应服务的结果中
+func (u *Updater) buildServices(dir, fileName string, cf *types.ComposeFile) []*types.UpdateResult {
+	var serviceNames []string
+	for serviceName, service := range cf.Services {
+		if service.Image == "" && service.Build != nil {
+			serviceNames = append(serviceNames, serviceName)
 		}
 	}
-	return false
-}
-
-// executeDockerComposePullWithMultiProgress 执行 docker-compose pull 命令并显示多进度条
-func (u *Updater) executeDockerComposePullWithMultiProgress(dir, fileName string, cf *types.ComposeFile, multiProgressBar *ui.MultiProgressBar, fileIndex int) ([]*types.UpdateResult, error) {
-	var results []*types.UpdateResult
-
-	// 构建 docker-compose pull 命令
-	var cmd *exec.Cmd
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		cmd = exec.Command("docker-compose", "pull")
-	} else {
-		cmd = exec.Command("docker-compose", "-f", fileName, "pull")
+	if len(serviceNames) == 0 {
+		return nil
 	}
-	cmd.Dir = dir
+	sort.Strings(serviceNames)
 
-	// 创建上下文以便取消操作
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), u.pullTimeout())
 	defer cancel()
+	cmd := u.composeCommand(fileName, append([]string{"build", "--no-cache"}, serviceNames...)...)
 	cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
 	cmd.Dir = dir
+	u.applyComposeEnv(cmd)
 
-	// 更新进度
-	multiProgressBar.UpdateFile(fileIndex, 40, "⬇️ 开始拉取镜像...")
-
-	// 执行命令
-	_, err := cmd.CombinedOutput()
-
-	// 更新进度
-	multiProgressBar.UpdateFile(fileIndex, 60, "⬇️ 镜像拉取完成")
-
-	// 为每个服务创建结果
-	for serviceName, service := range cf.Services {
-		if service.Image == "" {
-			continue
-		}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("执行 docker-compose build 失败: %v\n输出: %s", err, string(output))
+	}
 
-		result := &types.UpdateResult{
+	results := make([]*types.UpdateResult, 0, len(serviceNames))
+	for _, serviceName := range serviceNames {
+		results = append(results, &types.UpdateResult{
 			Service:   serviceName,
-			OldImage:  service.Image,
-			NewImage:  service.Image,
+			OldImage:  "<built>",
+			NewImage:  "<rebuilt>",
 			Success:   err == nil,
 			Error:     err,
+			Changed:   err == nil,
 			UpdatedAt: time.Now(),
-		}
+		})
+	}
+	return results
+}
+
+// backsUpBeforePull 返回是否在拉取镜像前备份 Compose 文件，--rollback-on-failure 回滚时需要修改前的版本
+func (u *Updater) backsUpBeforePull() bool {
+	return u.config.BackupBeforePull || u.config.RollbackOnFailure
+}
+
+// backupBeforePull 在拉取镜像前备份 Compose 文件，保证更新失败时有可恢复的版本，返回备份文件路径
+// 启用后后续修改文件时不再重复备份，避免备份被中间状态覆盖
+func (u *Updater) backupBeforePull(cf *types.ComposeFile) (string, error) {
+	backupPath, err := u.parser.BackupFile(cf.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("拉取前备份文件失败: %v", err)
+	}
+	return backupPath, nil
+}
+
+// versionedStrategy 能够比较标签新旧的策略 (semver、regex)，更新时会将新标签写回 Compose 文件
+type versionedStrategy interface {
+	types.ImageTagStrategy
+	ShouldUpdate(currentImage, targetImage string) bool
+}
+
+// versionedStrategy 返回当前使用的可比较标签的策略，latest 策略返回 false
+func (u *Updater) versionedStrategy() (versionedStrategy, bool) {
+	switch s := u.strategy.(type) {
+	case *strategy.SemverStrategy:
+		return s, true
+	case *strategy.RegexStrategy:
+		return s, true
+	default:
+		return nil, false
+	}
+}
+
+// applyTagUpdates 使用 semver 或 regex 策略查询每个服务的最新版本，按标签格式转换后写回 Compose 文件
+// 通过 --image-tag 指定了标签的镜像直接使用该标签，不论使用哪种策略；--image-registry-map 先替换镜像仓库前缀，再查询标签
+// 返回被修改服务的原镜像 (服务名 -> 镜像) 和已是最新版本的服务；查询失败的服务保留原标签，latest 策略不修改标签
+func (u *Updater) applyTagUpdates(cf *types.ComposeFile) (map[string]string, []string, error) {
+	versioned, isVersioned := u.versionedStrategy()
+	if !isVersioned && len(u.config.ImageTagOverrides) == 0 && len(u.config.ImageRegistryMap) == 0 {
+		return nil, nil, nil
+	}
+
+	// 读取文件中未经插值的原始内容，写回时只修改镜像字段
+	current, err := u.parser.ParseFile(cf.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("更新镜像标签失败: %v", err)
+	}
+
+	previousImages := make(map[string]string)
+	var upToDate []string
+	for serviceName, service := range cf.Services {
+		if service.Image == "" || u.shouldExcludeImage(service.Image) {
+			continue
+		}
+
+		// 镜像使用了变量插值 (如 ${REGISTRY}/app:${TAG}) 时，写回插值后的值会覆盖变量引用，不修改此类服务的标签
+		if raw, exists := current.Services[serviceName]; exists && hasVariable(raw.Image) {
+			continue
+		}
+
+		// 替换了仓库前缀的服务需要拉取新仓库的镜像，不视为已是最新版本
+		originalImage := service.Image
+		rewritten, changed := u.rewriteImageRegistry(service.Image)
+		if changed {
+			u.recordRewrite(originalImage, rewritten)
+			previousImages[serviceName] = originalImage
+			service.Image = rewritten
+			cf.Services[serviceName] = service
+		}
+
+		currentTag := imageTag(service.Image)
+		repository := strings.TrimSuffix(service.Image, ":"+currentTag)
+
+		if overrideTag, ok := u.imageTagOverride(repository); ok {
+			if overrideTag == currentTag {
+				if !changed {
+					upToDate = append(upToDate, serviceName)
+				}
+				continue
+			}
+			previousImages[serviceName] = originalImage
+			service.Image = repository + ":" + overrideTag
+			cf.Services[serviceName] = service
+			continue
+		}
+		if !isVersioned {
+			continue
+		}
+
+		latestTag, err := versioned.GetLatestTag(service.Image)
+		if err != nil {
+			continue
+		}
+
+		// 当前标签已按格式转换时，先还原出版本再比较
+		currentVersion, matched := strategy.ExtractVersion(u.config.TagFormat, currentTag)
+		if !matched {
+			currentVersion = currentTag
+		}
+		if !versioned.ShouldUpdate(repository+":"+currentVersion, repository+":"+latestTag) {
+			if !changed {
+				upToDate = append(upToDate, serviceName)
+			}
+			continue
+		}
+
+		newTag, err := strategy.FormatTag(u.config.TagFormat, latestTag)
+		if err != nil {
+			return nil, nil, err
+		}
+		if newTag == currentTag {
+			if !changed {
+				upToDate = append(upToDate, serviceName)
+			}
+			continue
+		}
+
+		previousImages[serviceName] = originalImage
+		service.Image = repository + ":" + newTag
+		cf.Services[serviceName] = service
+	}
+
+	if len(previousImages) == 0 {
+		return nil, upToDate, nil
+	}
+
+	for serviceName := range previousImages {
+		if service, exists := current.Services[serviceName]; exists {
+			service.Image = cf.Services[serviceName].Image
+			current.Services[serviceName] = service
+		}
+	}
+
+	if u.config.BackupEnabled && !u.backsUpBeforePull() {
+		if _, err := u.parser.BackupFile(cf.FilePath); err != nil {
+			return nil, nil, fmt.Errorf("更新镜像标签失败: %v", err)
+		}
+	}
+	if err := u.parser.WriteFile(current, cf.FilePath); err != nil {
+		return nil, nil, fmt.Errorf("更新镜像标签失败: %v", err)
+	}
+
+	return previousImages, upToDate, nil
+}
+
+// UpdateSingleImage 将文件中指定服务的镜像标签修改为 newTag 并写回文件，不执行任何 docker 命令
+// 镜像中的摘要会被移除；服务不存在、未使用镜像或文件无法写入时返回错误
+func (u *Updater) UpdateSingleImage(filePath, serviceName, newTag string) error {
+	if newTag == "" || strings.ContainsAny(newTag, " :/@") {
+		return fmt.Errorf("无效的镜像标签: %q", newTag)
+	}
+
+	current, err := u.parser.ParseFile(filePath)
+	if err != nil {
+		return fmt.Errorf("读取 Compose 文件失败: %v", err)
+	}
+	service, exists := current.Services[serviceName]
+	if !exists {
+		return fmt.Errorf("服务 %s 不存在于 %s", serviceName, filePath)
+	}
+	if service.Image == "" {
+		return fmt.Errorf("服务 %s 没有指定镜像", serviceName)
+	}
+	if hasVariable(service.Image) {
+		return fmt.Errorf("服务 %s 的镜像 %s 使用了变量，请修改对应的环境变量", serviceName, service.Image)
+	}
+
+	service.Image = imageNameWithoutReference(service.Image) + ":" + newTag
+	current.Services[serviceName] = service
+
+	if u.config.BackupEnabled {
+		if _, err := u.parser.BackupFile(filePath); err != nil {
+			return fmt.Errorf("备份 Compose 文件失败: %v", err)
+		}
+	}
+	if err := u.parser.WriteFileAtomic(current, filePath); err != nil {
+		return fmt.Errorf("更新服务 %s 的镜像标签失败: %v", serviceName, err)
+	}
+	return nil
+}
+
+// hasVariable 检查 Compose 文件中的原始值是否包含 $VAR 或 ${VAR} 形式的变量引用
+func hasVariable(value string) bool {
+	return strings.Contains(value, "$")
+}
+
+// imageTagOverride 返回通过 --image-tag 为镜像仓库指定的标签
+// Docker Hub 官方镜像的 nginx、library/nginx 和 docker.io/library/nginx 视为同一镜像
+func (u *Updater) imageTagOverride(repository string) (string, bool) {
+	if tag, ok := u.config.ImageTagOverrides[repository]; ok {
+		return tag, true
+	}
+
+	normalized := normalizeRepository(repository)
+	for image, tag := range u.config.ImageTagOverrides {
+		if normalizeRepository(image) == normalized {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// normalizeRepository 去掉 Docker Hub 的默认前缀，用于比较镜像仓库名
+func normalizeRepository(repository string) string {
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	return strings.TrimPrefix(repository, "library/")
+}
+
+// skipsUpToDate 判断是否跳过已是最新版本的服务，只有 semver 和 regex 策略能根据标签判断服务是否已是最新
+func (u *Updater) skipsUpToDate() bool {
+	_, versioned := u.versionedStrategy()
+	return u.config.SkipUpToDate && versioned
+}
+
+// skipUpToDateServices 返回移除已是最新版本服务后的 Compose 文件副本，被移除的服务记录为跳过
+func (u *Updater) skipUpToDateServices(cf *types.ComposeFile, upToDate []string) (*types.ComposeFile, []*types.UpdateResult) {
+	if !u.skipsUpToDate() || len(upToDate) == 0 {
+		return cf, nil
+	}
+
+	filtered := *cf
+	filtered.Services = make(map[string]types.Service, len(cf.Services))
+	for serviceName, service := range cf.Services {
+		filtered.Services[serviceName] = service
+	}
+
+	var skipped []*types.UpdateResult
+	for _, serviceName := range upToDate {
+		service := filtered.Services[serviceName]
+		delete(filtered.Services, serviceName)
+
+		skipped = append(skipped, &types.UpdateResult{
+			Service:    serviceName,
+			OldImage:   service.Image,
+			NewImage:   service.Image,
+			UpdatedAt:  time.Now(),
+			SkipReason: "已是最新版本",
+		})
+	}
+
+	return &filtered, skipped
+}
+
+// setPreviousImages 将更新结果中的原镜像设置为修改标签之前的镜像
+// 新旧标签指向同一清单时（如 1.25 和 1.25.3）服务实际没有变化，不标记为已变更
+func (u *Updater) setPreviousImages(results []*types.UpdateResult, previousImages map[string]string, cf *types.ComposeFile) {
+	for _, result := range results {
+		if previous, exists := previousImages[result.Service]; exists {
+			result.OldImage = previous
+			result.Changed = u.imageContentChanged(previous, cf.Services[result.Service].Image)
+		}
+	}
+}
+
+// imageContentChanged 比较两个镜像的清单摘要，无法获取摘要时视为已变化
+func (u *Updater) imageContentChanged(oldImage, newImage string) bool {
+	oldDigest, err := u.imageManager.GetImageManifestDigest(oldImage)
+	if err != nil {
+		return true
+	}
+	newDigest, err := u.imageManager.GetImageManifestDigest(newImage)
+	if err != nil {
+		return true
+	}
+	return oldDigest != newDigest
+}
+
+// applyRestartPolicy 将配置的重启策略写入 Compose 文件中的所有服务，确保主机重启后服务能够自动恢复
+// docker-compose up 不支持通过参数指定重启策略，因此需要修改文件本身
+func (u *Updater) applyRestartPolicy(cf *types.ComposeFile) error {
+	policy := u.config.RestartPolicy
+	if policy == "" {
+		return nil
+	}
+
+	// 重新读取文件，避免写回时带入内存中的其他修改
+	current, err := u.parser.ParseFile(cf.FilePath)
+	if err != nil {
+		return fmt.Errorf("设置重启策略失败: %v", err)
+	}
+
+	changed := false
+	for serviceName, service := range current.Services {
+		if service.Restart != policy {
+			service.Restart = policy
+			current.Services[serviceName] = service
+			changed = true
+		}
+	}
+
+	if changed {
+		if u.config.BackupEnabled && !u.backsUpBeforePull() {
+			if _, err := u.parser.BackupFile(cf.FilePath); err != nil {
+				return fmt.Errorf("设置重启策略失败: %v", err)
+			}
+		}
+		if err := u.parser.WriteFile(current, cf.FilePath); err != nil {
+			return fmt.Errorf("设置重启策略失败: %v", err)
+		}
+	}
+
+	for serviceName, service := range cf.Services {
+		service.Restart = policy
+		cf.Services[serviceName] = service
+	}
+
+	return nil
+}
+
+// filterServicesByName 返回只包含服务名匹配 --services-only 的服务的 Compose 文件副本，
+// 以及其余服务的跳过结果；未指定 --services-only 时原样返回
+func (u *Updater) filterServicesByName(cf *types.ComposeFile) (*types.ComposeFile, []*types.UpdateResult) {
+	if u.servicesOnly == nil {
+		return cf, nil
+	}
+
+	filtered := *cf
+	filtered.Services = make(map[string]types.Service)
+
+	var skipped []*types.UpdateResult
+	for serviceName, service := range cf.Services {
+		if u.servicesOnly.MatchString(serviceName) {
+			filtered.Services[serviceName] = service
+			continue
+		}
+		if service.Image == "" {
+			continue
+		}
+
+		skipped = append(skipped, &types.UpdateResult{
+			Service:    serviceName,
+			OldImage:   service.Image,
+			NewImage:   service.Image,
+			UpdatedAt:  time.Now(),
+			SkipReason: "服务名不匹配 --services-only 过滤条件",
+		})
+	}
+
+	return &filtered, skipped
+}
+
+// filterServicesBySinceTag 返回只包含镜像标签低于 --since-tag 的服务的 Compose 文件副本，
+// 以及其余服务的跳过结果；未指定 --since-tag 时原样返回
+func (u *Updater) filterServicesBySinceTag(cf *types.ComposeFile) (*types.ComposeFile, []*types.UpdateResult) {
+	if u.versionComparer == nil {
+		return cf, nil
+	}
+
+	filtered := *cf
+	filtered.Services = make(map[string]types.Service)
+
+	var skipped []*types.UpdateResult
+	for serviceName, service := range cf.Services {
+		if service.Image == "" {
+			continue
+		}
+
+		skipReason := ""
+		if !u.versionComparer.ValidateTag(service.Image) {
+			skipReason = "标签不是语义版本，无法与 --since-tag 比较"
+		} else if currentTag := imageTag(service.Image); u.versionComparer.CompareVersions(currentTag, u.config.SinceTag) >= 0 {
+			skipReason = fmt.Sprintf("当前版本 %s 不低于 %s", currentTag, u.config.SinceTag)
+		}
+
+		if skipReason == "" {
+			filtered.Services[serviceName] = service
+			continue
+		}
+
+		skipped = append(skipped, &types.UpdateResult{
+			Service:    serviceName,
+			OldImage:   service.Image,
+			NewImage:   service.Image,
+			UpdatedAt:  time.Now(),
+			SkipReason: skipReason,
+		})
+	}
+
+	return &filtered, skipped
+}
+
+// forEachDependencyGroup 启用 respect_dependencies 时按依赖层级依次处理服务，被依赖的服务所在的组先处理；
+// 每组传入只包含该组服务的 Compose 文件副本。未启用时直接处理整个文件
+func (u *Updater) forEachDependencyGroup(cf *types.ComposeFile, fn func(group *types.ComposeFile) error) error {
+	if !u.config.RespectDependencies {
+		return fn(cf)
+	}
+
+	graph, err := BuildDependencyGraph(cf)
+	if err != nil {
+		return err
+	}
+
+	for _, serviceNames := range graph.IndependentGroups() {
+		group := *cf
+		group.Services = make(map[string]types.Service, len(serviceNames))
+		for _, serviceName := range serviceNames {
+			group.Services[serviceName] = cf.Services[serviceName]
+		}
+		if err := fn(&group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serviceArgs 返回传递给 docker-compose 命令的服务名参数
+// 仅在服务经过过滤或按依赖分组时指定，否则为空以处理文件中的所有服务
+func (u *Updater) serviceArgs(cf *types.ComposeFile) []string {
+	if u.versionComparer == nil && u.servicesOnly == nil && !u.config.RespectDependencies && !u.skipsUpToDate() && len(u.getSelectedServices(cf.FilePath)) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(cf.Services))
+	for serviceName := range cf.Services {
+		names = append(names, serviceName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageTag 返回镜像引用中的标签，未指定时为 latest
+func imageTag(image string) string {
+	if idx := strings.LastIndex(image, ":"); idx >= 0 && !strings.Contains(image[idx+1:], "/") {
+		return image[idx+1:]
+	}
+	return "latest"
+}
+
+// failureDiagnostics 获取项目中异常容器的最近日志，用于补充 up -d 失败时的错误信息
+func (u *Updater) failureDiagnostics(cf *types.ComposeFile) string {
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+
+	logs := dockerClient.DiagnoseComposeProject(RuntimeProjectName(cf), failureLogTail)
+	if logs == "" {
+		return ""
+	}
+	return "\n" + logs
+}
+
+// LoadComposeEnvFile 读取 key=value 格式的环境变量文件，其内容会传递给所有 docker-compose 命令
+// 与 Docker Compose 自身用于变量替换的 .env 文件不同
+func (u *Updater) LoadComposeEnvFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	env, err := parseEnvFile(path)
+	if err != nil {
+		return err
+	}
+
+	u.composeEnv = append(u.composeEnv, env...)
+	return nil
+}
+
+// AddComposeEnv 为 docker-compose 命令添加 KEY=VALUE 格式的环境变量
+// 在 LoadComposeEnvFile 之后调用时，同名变量覆盖环境变量文件中的值
+func (u *Updater) AddComposeEnv(pairs []string) error {
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("无效的环境变量 %s (正确格式: KEY=VALUE)", pair)
+		}
+		u.composeEnv = append(u.composeEnv, key+"="+value)
+	}
+	return nil
+}
+
+// pullTimeout 返回拉取镜像的超时时间，未配置 pull_timeout 时使用 timeout
+func (u *Updater) pullTimeout() time.Duration {
+	return firstPositiveDuration(u.config.PullTimeout, u.config.Timeout, defaultPullTimeout)
+}
+
+// upTimeout 返回 up -d 重启服务的超时时间，未配置 up_timeout 时使用 timeout
+func (u *Updater) upTimeout() time.Duration {
+	return firstPositiveDuration(u.config.UpTimeout, u.config.Timeout, defaultUpTimeout)
+}
+
+// firstPositiveDuration 返回第一个大于 0 的时长，都不大于 0 时返回 0
+func firstPositiveDuration(durations ...time.Duration) time.Duration {
+	for _, d := range durations {
+		if d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// ComposeVersion 返回更新器使用的 Compose 命令版本，compose_version 为 auto 时为检测结果
+func (u *Updater) ComposeVersion() string {
+	return u.composeVersion
+}
+
+// upCommand 构建 up -d 命令
+func (u *Updater) upCommand(fileName string) *exec.Cmd {
+	return u.composeCommand(fileName, u.upArgs()...)
+}
+
+// upArgs 返回 up -d 命令的参数，启用 remove_orphans 时同时删除孤立容器，
+// 设置了 grace_period 时通过 --timeout 指定停止容器的宽限期 (秒，向上取整)
+func (u *Updater) upArgs() []string {
+	args := []string{"up", "-d"}
+	if u.config.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+	if u.config.GracePeriod > 0 {
+		seconds := int((u.config.GracePeriod + time.Second - 1) / time.Second)
+		args = append(args, "--timeout", strconv.Itoa(seconds))
+	}
+	return args
+}
+
+// orphanContainerPatterns 匹配 up --remove-orphans 输出中被删除的孤立容器
+// v1 输出 Removing orphan container "app_old_1"，v2 输出 Container app-old-1  Removed
+var orphanContainerPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`Removing orphan container "?([^"\s]+)"?`),
+	regexp.MustCompile(`Container (\S+)\s+Removed`),
+}
+
+// orphanResults 为 up 命令删除的每个孤立容器创建结果，Service 为容器名称
+func orphanResults(output []byte) []*types.UpdateResult {
+	var results []*types.UpdateResult
+	seen := make(map[string]bool)
+	for _, pattern := range orphanContainerPatterns {
+		for _, match := range pattern.FindAllSubmatch(output, -1) {
+			name := string(match[1])
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			results = append(results, &types.UpdateResult{
+				Service:    name,
+				UpdatedAt:  time.Now(),
+				SkipReason: "已删除孤立容器",
+			})
+		}
+	}
+	return results
+}
+
+// composeCommand 按 Compose 命令版本构建命令：v1 使用 docker-compose，v2 使用 docker compose 插件
+// 文件名为 docker-compose.yml 或 docker-compose.yaml 时不指定 -f，由 Compose 自动查找
+func (u *Updater) composeCommand(fileName string, args ...string) *exec.Cmd {
+	return u.composeCommandWithFiles([]string{fileName}, args...)
+}
+
+// composeCommandWithFiles 与 composeCommand 相同，但依次使用多个 Compose 文件，后面的文件覆盖前面的配置
+func (u *Updater) composeCommandWithFiles(fileNames []string, args ...string) *exec.Cmd {
+	var cmdArgs []string
+	name := "docker-compose"
+	if u.composeVersion == "v2" {
+		name = "docker"
+		cmdArgs = append(cmdArgs, "compose")
+	}
+	if len(fileNames) > 1 || (fileNames[0] != "docker-compose.yml" && fileNames[0] != "docker-compose.yaml") {
+		for _, fileName := range fileNames {
+			cmdArgs = append(cmdArgs, "-f", fileName)
+		}
+	}
+	// --compose-args 指定的参数放在子命令之前，作为 docker-compose 的全局参数
+	cmdArgs = append(cmdArgs, u.config.ComposeArgs...)
+	return exec.Command(name, append(cmdArgs, args...)...)
+}
+
+// applyComposeEnv 为命令设置额外的环境变量，详细模式下输出即将执行的完整命令
+func (u *Updater) applyComposeEnv(cmd *exec.Cmd) {
+	ui.Debug("执行命令: "+strings.Join(cmd.Args, " "), u.verbose)
+	if len(u.composeEnv) == 0 {
+		return
+	}
+	cmd.Env = append(os.Environ(), u.composeEnv...)
+}
+
+// parseEnvFile 解析环境变量文件，忽略空行和 # 开头的注释
+func parseEnvFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开环境变量文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return nil, fmt.Errorf("环境变量文件 %s 第 %d 行格式无效: %s", path, lineNum, line)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		env = append(env, key+"="+value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取环境变量文件失败: %v", err)
+	}
+
+	return env, nil
+}
+
+// setResultsDuration 为同一文件的所有结果设置处理耗时
+func setResultsDuration(results []*types.UpdateResult, duration time.Duration) {
+	for _, result := range results {
+		result.Duration = duration
+	}
+}
+
+// getSelectedServices 获取选择的服务列表，运行时未选择服务时使用配置中固定的服务
+func (u *Updater) getSelectedServices(filePath string) []string {
+	if selected := u.config.SelectedServices[filePath]; len(selected) > 0 {
+		return selected
+	}
+
+	if pinned := u.config.PinnedServices[filePath]; len(pinned) > 0 {
+		return pinned
+	}
+	// pin 命令以绝对路径保存
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		return u.config.PinnedServices[absPath]
+	}
+	return nil
+}
+
+// filterSelectedServices 返回只包含选中服务的 Compose 文件副本，未选择服务时返回原文件
+func (u *Updater) filterSelectedServices(cf *types.ComposeFile) *types.ComposeFile {
+	selected := u.getSelectedServices(cf.FilePath)
+	if len(selected) == 0 {
+		return cf
+	}
+
+	filtered := *cf
+	filtered.Services = make(map[string]types.Service)
+	for _, serviceName := range selected {
+		if service, exists := cf.Services[serviceName]; exists {
+			filtered.Services[serviceName] = service
+		}
+	}
+	return &filtered
+}
+
+// shouldExcludeImage 检查是否应该排除镜像
+func (u *Updater) shouldExcludeImage(image string) bool {
+	for _, excludePattern := range u.config.ExcludeImages {
+		if strings.Contains(image, excludePattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// pullImagesDirect 通过 Docker API 按服务名顺序拉取镜像，代替 docker-compose pull
+// 每个有镜像的服务返回一个结果，多个服务使用同一镜像时只拉取一次；onProgress 为 nil 时不报告进度
+func (u *Updater) pullImagesDirect(cf *types.ComposeFile, onProgress func(image string, progress docker.PullProgress)) []*types.UpdateResult {
+	dockerClient := docker.NewClient()
+	defer dockerClient.Close()
+	dockerClient.SetPlatform(u.config.Platform)
+
+	serviceNames := make([]string, 0, len(cf.Services))
+	for serviceName := range cf.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	var results []*types.UpdateResult
+	pulled := make(map[string]error)
+	for _, serviceName := range serviceNames {
+		service := cf.Services[serviceName]
+		if service.Image == "" {
+			continue
+		}
+
+		err, done := pulled[service.Image]
+		if !done {
+			err = pullImageDirect(dockerClient, service.Image, onProgress)
+			pulled[service.Image] = err
+		}
+
+		result := &types.UpdateResult{
+			Service:   serviceName,
+			OldImage:  service.Image,
+			NewImage:  service.Image,
+			Success:   err == nil,
+			Error:     err,
+			UpdatedAt: time.Now(),
+		}
+		if err == nil {
+			result.NewImage = service.Image + " (已拉取)"
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// pullImageDirect 拉取单个镜像，并在拉取期间将进度转发给 onProgress
+func pullImageDirect(dockerClient *docker.Client, image string, onProgress func(image string, progress docker.PullProgress)) error {
+	if onProgress == nil {
+		return dockerClient.PullImageWithProgress(image, nil)
+	}
+
+	progressCh := make(chan docker.PullProgress)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for progress := range progressCh {
+			onProgress(image, progress)
+		}
+	}()
+
+	err := dockerClient.PullImageWithProgress(image, progressCh)
+	close(progressCh)
+	<-done
+	return err
+}
+
+// formatPullProgress 将拉取进度格式化为单行状态，如 "⬇️ nginx:1.25 3f4ca61aafcd Downloading 12.3 MB/45.6 MB"
+func formatPullProgress(image string, progress docker.PullProgress) string {
+	status := "⬇️ " + image
+	if layer := progress.Layer; layer != "" && layer != imageTag(image) {
+		if len(layer) > 12 {
+			layer = layer[:12]
+		}
+		status += " " + layer
+	}
+	if progress.Status != "" {
+		status += " " + progress.Status
+	}
+	if progress.Total > 0 {
+		status += fmt.Sprintf(" %s/%s", ui.FormatSize(progress.Current), ui.FormatSize(progress.Total))
+	}
+	return status
+}
+
+// executeDockerComposePullWithMultiProgress 执行 docker-compose pull 命令并显示多进度条
+func (u *Updater) executeDockerComposePullWithMultiProgress(dir, fileName string, cf *types.ComposeFile, multiProgressBar *ui.MultiProgressBar, fileIndex int) ([]*types.UpdateResult, error) {
+	if u.config.UseDirectPull {
+		results := u.pullImagesDirect(cf, func(image string, progress docker.PullProgress) {
+			multiProgressBar.UpdateFile(fileIndex, 40, formatPullProgress(image, progress))
+		})
+		multiProgressBar.UpdateFile(fileIndex, 60, "⬇️ 镜像拉取完成")
+		return results, nil
+	}
+
+	var results []*types.UpdateResult
+
+	// 构建 docker-compose pull 命令
+	cmd := u.composeCommand(fileName, "pull")
+	cmd.Args = append(cmd.Args, u.serviceArgs(cf)...)
+	cmd.Dir = dir
+
+	// 创建上下文以便取消操作
+	ctx, cancel := context.WithTimeout(context.Background(), u.pullTimeout())
+	defer cancel()
+	cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
+	cmd.Dir = dir
+	u.applyComposeEnv(cmd)
+
+	// 更新进度
+	multiProgressBar.UpdateFile(fileIndex, 40, "⬇️ 开始拉取镜像...")
+
+	// 执行命令
+	_, err := cmd.CombinedOutput()
+
+	// 更新进度
+	multiProgressBar.UpdateFile(fileIndex, 60, "⬇️ 镜像拉取完成")
+
+	// 为每个服务创建结果
+	for serviceName, service := range cf.Services {
+		if service.Image == "" {
+			continue
+		}
+
+		result := &types.UpdateResult{
+			Service:   serviceName,
+			OldImage:  service.Image,
+			NewImage:  service.Image,
+			Success:   err == nil,
+			Error:     err,
+			UpdatedAt: time.Now(),
+		}
 
 		if err == nil {
 			result.NewImage = service.Image + " (已拉取)"
@@ -594,19 +1852,16 @@ func (u *Updater) executeDockerComposeUpWithMultiProgress(dir, fileName string,
 	var results []*types.UpdateResult
 
 	// 构建 docker-compose up -d 命令
-	var cmd *exec.Cmd
-	if fileName == "docker-compose.yml" || fileName == "docker-compose.yaml" {
-		cmd = exec.Command("docker-compose", "up", "-d")
-	} else {
-		cmd = exec.Command("docker-compose", "-f", fileName, "up", "-d")
-	}
+	cmd := u.upCommand(fileName)
+	cmd.Args = append(cmd.Args, u.serviceArgs(cf)...)
 	cmd.Dir = dir
 
 	// 创建上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), u.upTimeout())
 	defer cancel()
 	cmd = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...)
 	cmd.Dir = dir
+	u.applyComposeEnv(cmd)
 
 	// 更新进度
 	multiProgressBar.UpdateFile(fileIndex, 80, "🔄 重启服务中...")
@@ -636,10 +1891,14 @@ func (u *Updater) executeDockerComposeUpWithMultiProgress(dir, fileName string,
 		outputStr := string(output)
 		if strings.Contains(outputStr, serviceName) && (strings.Contains(outputStr, "Starting") || strings.Contains(outputStr, "Recreating")) {
 			result.NewImage = service.Image + " (已重启)"
+			result.Changed = true
 		}
 
 		results = append(results, result)
 	}
+	if err == nil {
+		results = append(results, orphanResults(output)...)
+	}
 
 	return results, nil
 }
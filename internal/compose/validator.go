@@ -0,0 +1,342 @@
+package compose
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"compman/pkg/types"
+)
+
+// PortConflict 表示两个服务绑定了相同的宿主机端口，后启动的项目会因端口被占用而失败
+type PortConflict struct {
+	Port     string // 宿主机端口和协议，如 8080/tcp
+	File1    string
+	Service1 string
+	File2    string
+	Service2 string
+}
+
+// hostPortBinding 服务在宿主机上绑定的单个端口
+type hostPortBinding struct {
+	hostIP   string // 为空时绑定到所有网络接口
+	port     int
+	protocol string
+}
+
+// portOwner 记录绑定端口的服务
+type portOwner struct {
+	file    string
+	service string
+	hostIP  string
+}
+
+// ValidatePortConflicts 检查所有 Compose 文件中的服务是否绑定了相同的宿主机端口
+// 同时支持短格式 ("127.0.0.1:8080:80/udp") 和长格式 (published/target/host_ip/protocol) 的端口定义；
+// 只指定容器端口、使用变量或无法解析的端口会被忽略。结果按端口和文件排序
+func ValidatePortConflicts(files []*types.ComposeFile) []PortConflict {
+	var conflicts []PortConflict
+	owners := make(map[string][]portOwner) // 端口/协议 -> 绑定该端口的服务
+
+	for _, cf := range files {
+		serviceNames := make([]string, 0, len(cf.Services))
+		for serviceName := range cf.Services {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			seen := make(map[string]bool) // 同一服务重复声明的端口不视为冲突
+			for _, entry := range cf.Services[serviceName].Ports {
+				bindings, err := parsePortEntry(entry)
+				if err != nil {
+					continue
+				}
+
+				for _, binding := range bindings {
+					key := fmt.Sprintf("%d/%s", binding.port, binding.protocol)
+					if seen[key+"@"+binding.hostIP] {
+						continue
+					}
+					seen[key+"@"+binding.hostIP] = true
+
+					owner := portOwner{file: cf.FilePath, service: serviceName, hostIP: binding.hostIP}
+					for _, existing := range owners[key] {
+						if existing.file == owner.file && existing.service == owner.service {
+							continue
+						}
+						if !hostIPsOverlap(existing.hostIP, owner.hostIP) {
+							continue
+						}
+						conflicts = append(conflicts, PortConflict{
+							Port:     key,
+							File1:    existing.file,
+							Service1: existing.service,
+							File2:    owner.file,
+							Service2: owner.service,
+						})
+					}
+					owners[key] = append(owners[key], owner)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		pi, _ := strconv.Atoi(strings.SplitN(conflicts[i].Port, "/", 2)[0])
+		pj, _ := strconv.Atoi(strings.SplitN(conflicts[j].Port, "/", 2)[0])
+		return pi < pj
+	})
+
+	return conflicts
+}
+
+// HostPorts 返回 Compose 文件中所有服务绑定的宿主机端口，去重后按端口号排序
+// TCP 端口只显示端口号，其他协议带协议后缀，如 53/udp；使用变量或无法解析的端口会被忽略
+func HostPorts(cf *types.ComposeFile) []string {
+	seen := make(map[string]bool)
+	var bindings []hostPortBinding
+	for _, service := range cf.Services {
+		for _, entry := range service.Ports {
+			entryBindings, err := parsePortEntry(entry)
+			if err != nil {
+				continue
+			}
+			for _, binding := range entryBindings {
+				key := fmt.Sprintf("%d/%s", binding.port, binding.protocol)
+				if !seen[key] {
+					seen[key] = true
+					bindings = append(bindings, binding)
+				}
+			}
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].port != bindings[j].port {
+			return bindings[i].port < bindings[j].port
+		}
+		return bindings[i].protocol < bindings[j].protocol
+	})
+
+	ports := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		if binding.protocol == "tcp" {
+			ports = append(ports, strconv.Itoa(binding.port))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d/%s", binding.port, binding.protocol))
+		}
+	}
+	return ports
+}
+
+// hostIPsOverlap 判断两个绑定地址是否会争用同一个端口，绑定到所有接口时与任何地址冲突
+func hostIPsOverlap(ip1, ip2 string) bool {
+	return isWildcardIP(ip1) || isWildcardIP(ip2) || ip1 == ip2
+}
+
+// isWildcardIP 判断地址是否表示所有网络接口
+func isWildcardIP(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// parsePortEntry 解析 ports 中的一项，返回其在宿主机上绑定的端口；只指定容器端口时返回空
+func parsePortEntry(entry interface{}) ([]hostPortBinding, error) {
+	switch value := entry.(type) {
+	case string:
+		return parseShortPort(value)
+	case int:
+		// 只有容器端口，宿主机端口随机分配
+		return nil, nil
+	case map[string]interface{}:
+		return parseLongPort(value)
+	default:
+		return nil, fmt.Errorf("不支持的端口格式: %v", entry)
+	}
+}
+
+// parseShortPort 解析短格式端口，如 "80"、"8080:80"、"127.0.0.1:8080:80/udp"、"[::1]:8000-8001:80-81"
+func parseShortPort(port string) ([]hostPortBinding, error) {
+	if strings.Contains(port, "$") {
+		return nil, fmt.Errorf("端口 %s 包含变量", port)
+	}
+
+	mapping, protocol := port, "tcp"
+	if idx := strings.LastIndex(port, "/"); idx >= 0 {
+		mapping, protocol = port[:idx], port[idx+1:]
+	}
+
+	// 容器端口在最后，其前面是宿主机端口，剩余部分为宿主机地址（IPv6 地址带方括号）
+	lastColon := strings.LastIndex(mapping, ":")
+	if lastColon < 0 {
+		return nil, nil
+	}
+	rest := mapping[:lastColon]
+
+	hostIP, published := "", rest
+	if idx := strings.LastIndex(rest, ":"); idx >= 0 && !strings.HasSuffix(rest, "]") {
+		hostIP, published = rest[:idx], rest[idx+1:]
+	}
+	hostIP = strings.Trim(hostIP, "[]")
+	if published == "" {
+		return nil, nil
+	}
+
+	return expandPortRange(hostIP, published, protocol)
+}
+
+// parseLongPort 解析长格式端口定义
+func parseLongPort(port map[string]interface{}) ([]hostPortBinding, error) {
+	published := strings.TrimSpace(fmt.Sprint(port["published"]))
+	if port["published"] == nil || published == "" {
+		return nil, nil
+	}
+	if strings.Contains(published, "$") {
+		return nil, fmt.Errorf("端口 %s 包含变量", published)
+	}
+
+	protocol := "tcp"
+	if value, ok := port["protocol"].(string); ok && value != "" {
+		protocol = value
+	}
+	hostIP, _ := port["host_ip"].(string)
+
+	return expandPortRange(hostIP, published, protocol)
+}
+
+// expandPortRange 将宿主机端口或端口范围 (8000-8010) 展开为单个端口
+func expandPortRange(hostIP, published, protocol string) ([]hostPortBinding, error) {
+	startStr, endStr, isRange := strings.Cut(published, "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的端口 %s", published)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(endStr); err != nil || end < start {
+			return nil, fmt.Errorf("无效的端口范围 %s", published)
+		}
+	}
+
+	bindings := make([]hostPortBinding, 0, end-start+1)
+	for port := start; port <= end; port++ {
+		bindings = append(bindings, hostPortBinding{hostIP: hostIP, port: port, protocol: strings.ToLower(protocol)})
+	}
+	return bindings, nil
+}
+
+// DependencyIssue 表示 depends_on 配置错误或服务之间存在循环依赖，docker-compose up 会因此失败
+type DependencyIssue struct {
+	File    string
+	Service string // 配置错误的服务，循环依赖时为空
+	Reason  string
+}
+
+// ValidateDependencies 检查所有 Compose 文件的 depends_on 格式、启动条件和循环依赖
+func ValidateDependencies(files []*types.ComposeFile) []DependencyIssue {
+	var issues []DependencyIssue
+	for _, cf := range files {
+		serviceNames := make([]string, 0, len(cf.Services))
+		for serviceName := range cf.Services {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		valid := true
+		for _, serviceName := range serviceNames {
+			if _, err := ParseDependsOn(cf.Services[serviceName].DependsOn); err != nil {
+				issues = append(issues, DependencyIssue{File: cf.FilePath, Service: serviceName, Reason: err.Error()})
+				valid = false
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		if _, err := BuildDependencyGraph(cf); err != nil {
+			issues = append(issues, DependencyIssue{File: cf.FilePath, Reason: err.Error()})
+		}
+	}
+	return issues
+}
+
+// InvalidImage 表示服务使用了格式不正确的镜像名称，拉取时会失败
+type InvalidImage struct {
+	File    string
+	Service string
+	Image   string
+	Reason  string
+}
+
+// imageReferencePattern 镜像引用格式：[仓库地址/]名称[/名称...][:标签][@算法:摘要]
+// 仓库地址可包含大写字母和端口，名称部分只能使用小写字母、数字和 . _ - 分隔符
+var imageReferencePattern = regexp.MustCompile(
+	`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+		`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?$`)
+
+// maxImageNameLength 镜像名称（不含标签和摘要）的最大长度
+const maxImageNameLength = 255
+
+// ValidateImageName 检查镜像名称是否符合镜像引用格式，如 nginx、registry:5000/team/app:v1.2、redis@sha256:<digest>
+func ValidateImageName(name string) error {
+	if name == "" {
+		return fmt.Errorf("镜像名称为空")
+	}
+	if strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("镜像名称包含空白字符")
+	}
+
+	repository, _, _ := strings.Cut(name, "@")
+	if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		repository = repository[:idx]
+	}
+
+	if !imageReferencePattern.MatchString(name) {
+		// 仓库地址之后的名称部分不允许大写字母，单独提示这一常见错误
+		path := repository
+		if domain, rest, found := strings.Cut(repository, "/"); found && (strings.ContainsAny(domain, ".:") || domain == "localhost") {
+			path = rest
+		}
+		if path != strings.ToLower(path) {
+			return fmt.Errorf("镜像名称只能使用小写字母")
+		}
+		return fmt.Errorf("镜像名称格式无效")
+	}
+	if len(repository) > maxImageNameLength {
+		return fmt.Errorf("镜像名称超过 %d 个字符", maxImageNameLength)
+	}
+	return nil
+}
+
+// ValidateImages 检查所有 Compose 文件中服务的镜像名称，包含变量的镜像在运行时才能确定，不做检查
+// 结果按文件和服务名排序
+func ValidateImages(files []*types.ComposeFile) []InvalidImage {
+	var invalid []InvalidImage
+	for _, cf := range files {
+		serviceNames := make([]string, 0, len(cf.Services))
+		for serviceName := range cf.Services {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			image := cf.Services[serviceName].Image
+			if image == "" || strings.Contains(image, "$") {
+				continue
+			}
+			if err := ValidateImageName(image); err != nil {
+				invalid = append(invalid, InvalidImage{
+					File:    cf.FilePath,
+					Service: serviceName,
+					Image:   image,
+					Reason:  err.Error(),
+				})
+			}
+		}
+	}
+	return invalid
+}
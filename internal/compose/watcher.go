@@ -0,0 +1,313 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"compman/pkg/types"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeType 表示 Compose 文件的变化类型
+type ChangeType string
+
+const (
+	// ChangeAdded 新增的 Compose 文件
+	ChangeAdded ChangeType = "added"
+	// ChangeModified 内容发生变化的 Compose 文件
+	ChangeModified ChangeType = "modified"
+	// ChangeRemoved 被删除或不再有效的 Compose 文件
+	ChangeRemoved ChangeType = "removed"
+)
+
+// FileChange 表示一次扫描之间的文件变化
+type FileChange struct {
+	Type        ChangeType
+	FilePath    string
+	ComposeFile *types.ComposeFile // 删除时为 nil
+	DetectedAt  time.Time
+}
+
+// fileState 记录文件在某次扫描时的状态
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher 监控 Compose 路径的文件系统事件，并在变化时重新扫描
+type Watcher struct {
+	scanner     *Scanner
+	paths       []string
+	debounce    time.Duration
+	fsw         *fsnotify.Watcher
+	snapshot    map[string]fileState
+	reconfigure chan watchTarget
+}
+
+// watchTarget 监控使用的扫描器和路径
+type watchTarget struct {
+	scanner *Scanner
+	paths   []string
+}
+
+// NewWatcher 创建一个新的监控器，并对当前路径进行首次扫描
+func NewWatcher(scanner *Scanner, paths []string) (*Watcher, []*types.ComposeFile, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建文件监控失败: %v", err)
+	}
+
+	w := &Watcher{
+		scanner:     scanner,
+		paths:       paths,
+		debounce:    500 * time.Millisecond,
+		fsw:         fsw,
+		reconfigure: make(chan watchTarget, 1),
+	}
+
+	for _, path := range paths {
+		if err := w.addPath(path); err != nil {
+			fsw.Close()
+			return nil, nil, err
+		}
+	}
+
+	composeFiles, err := scanner.ScanComposeFiles(paths)
+	if err != nil {
+		fsw.Close()
+		return nil, nil, err
+	}
+	w.snapshot = takeSnapshot(composeFiles)
+
+	return w, composeFiles, nil
+}
+
+// SetDebounce 设置事件合并的等待时间
+func (w *Watcher) SetDebounce(debounce time.Duration) {
+	w.debounce = debounce
+}
+
+// Reconfigure 替换监控使用的扫描器和路径，例如配置热重载后；Watch 会立即按新的设置重新扫描并回调变化
+// 可以在其他 goroutine 中调用，尚未应用的设置会被新的设置替换
+func (w *Watcher) Reconfigure(scanner *Scanner, paths []string) {
+	select {
+	case <-w.reconfigure:
+	default:
+	}
+	w.reconfigure <- watchTarget{scanner: scanner, paths: paths}
+}
+
+// Watch 阻塞监控文件变化，直到 stop 被关闭；每批事件合并后回调一次变化列表
+func (w *Watcher) Watch(stop <-chan struct{}, onChange func(changes []FileChange)) error {
+	var timer *time.Timer
+	var timerC <-chan time.Time
+
+	for {
+		select {
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if !w.isRelevant(event) {
+				continue
+			}
+
+			// 监控新创建的子目录
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addDirRecursive(event.Name)
+				}
+			}
+
+			// 重置防抖计时器
+			if timer == nil {
+				timer = time.NewTimer(w.debounce)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(w.debounce)
+			}
+			timerC = timer.C
+
+		case target := <-w.reconfigure:
+			if err := w.retarget(target); err != nil {
+				return err
+			}
+			timerC = nil
+			changes, err := w.rescan()
+			if err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				onChange(changes)
+			}
+
+		case <-timerC:
+			timerC = nil
+			changes, err := w.rescan()
+			if err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				onChange(changes)
+			}
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("文件监控出错: %v", err)
+		}
+	}
+}
+
+// Close 停止文件监控
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+// retarget 切换到新的扫描器和路径，并重新添加文件系统监控
+func (w *Watcher) retarget(target watchTarget) error {
+	for _, path := range w.fsw.WatchList() {
+		w.fsw.Remove(path)
+	}
+
+	w.scanner = target.scanner
+	w.paths = target.paths
+	for _, path := range w.paths {
+		if err := w.addPath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rescan 重新扫描并与上一次结果比较
+func (w *Watcher) rescan() ([]FileChange, error) {
+	composeFiles, err := w.scanner.ScanComposeFiles(w.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	current := takeSnapshot(composeFiles)
+	var changes []FileChange
+
+	for _, cf := range composeFiles {
+		previous, existed := w.snapshot[cf.FilePath]
+		state := current[cf.FilePath]
+		if !existed {
+			changes = append(changes, FileChange{Type: ChangeAdded, FilePath: cf.FilePath, ComposeFile: cf, DetectedAt: now})
+		} else if !previous.modTime.Equal(state.modTime) || previous.size != state.size {
+			changes = append(changes, FileChange{Type: ChangeModified, FilePath: cf.FilePath, ComposeFile: cf, DetectedAt: now})
+		}
+	}
+
+	for path := range w.snapshot {
+		if _, exists := current[path]; !exists {
+			changes = append(changes, FileChange{Type: ChangeRemoved, FilePath: path, DetectedAt: now})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].FilePath < changes[j].FilePath
+	})
+
+	w.snapshot = current
+	return changes, nil
+}
+
+// isRelevant 检查事件是否可能影响扫描结果
+func (w *Watcher) isRelevant(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Rename) && !event.Has(fsnotify.Remove) {
+		return false
+	}
+
+	ext := strings.ToLower(filepath.Ext(event.Name))
+	if ext == ".yml" || ext == ".yaml" {
+		return true
+	}
+
+	// 目录的创建、删除或重命名也可能带来 Compose 文件的变化
+	if event.Has(fsnotify.Create) {
+		info, err := os.Stat(event.Name)
+		return err == nil && info.IsDir()
+	}
+	return event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
+
+// addPath 添加一个配置的路径；文件路径监控其所在目录
+func (w *Watcher) addPath(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("解析路径失败 %s: %v", path, err)
+	}
+
+	info, err := os.Stat(absPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取路径失败 %s: %v", absPath, err)
+	}
+
+	if !info.IsDir() {
+		return w.fsw.Add(filepath.Dir(absPath))
+	}
+
+	w.addDirRecursive(absPath)
+	return nil
+}
+
+// addDirRecursive 递归监控目录及其子目录（受扫描深度限制）
+func (w *Watcher) addDirRecursive(root string) {
+	rootDepth := strings.Count(root, string(os.PathSeparator))
+
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // 静默跳过无法访问的目录
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && w.scanner.isSkippedDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if strings.Count(path, string(os.PathSeparator))-rootDepth > w.scanner.maxDepth {
+			return filepath.SkipDir
+		}
+		w.fsw.Add(path)
+		return nil
+	})
+}
+
+// takeSnapshot 记录扫描结果中每个文件的状态
+func takeSnapshot(composeFiles []*types.ComposeFile) map[string]fileState {
+	snapshot := make(map[string]fileState, len(composeFiles))
+	for _, cf := range composeFiles {
+		info, err := os.Stat(cf.FilePath)
+		if err != nil {
+			continue
+		}
+		snapshot[cf.FilePath] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+	}
+	return snapshot
+}
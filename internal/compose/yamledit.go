@@ -0,0 +1,276 @@
+package compose
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"compman/pkg/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// textEdit 对原文件某一行的修改：替换 [start, end) 范围内的字符，或在该行之前插入若干行
+type textEdit struct {
+	line   int      // 行下标，从 0 开始
+	start  int      // 替换的起始字符位置 (按 rune 计)
+	end    int      // 替换的结束字符位置 (按 rune 计)
+	text   string   // 替换后的内容
+	insert []string // 不为空时在该行之前插入这些行，忽略 start、end 和 text
+}
+
+// patchContent 在原文件内容上只修改服务的 image、restart 以及新增的服务，其余内容 (注释、空行、
+// 顶层 name/secrets/configs/x-* 等未建模的字段、未启用 profile 的服务) 按原样保留
+// 解析时规范化产生的差异 (如默认的 version、未指定标签时补充的 :latest) 不会写回
+func (p *Parser) patchContent(original []byte, composeFile *types.ComposeFile) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(original, &doc); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("文件内容不是 YAML 映射")
+	}
+	root := doc.Content[0]
+
+	lineEnding := "\n"
+	if bytes.Contains(original, []byte("\r\n")) {
+		lineEnding = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(string(original), "\r\n", "\n"), "\n")
+
+	servicesKey, services := mappingEntry(root, "services")
+	var edits []textEdit
+	var newServices []string
+	for _, serviceName := range sortedServiceNames(composeFile.Services) {
+		service := composeFile.Services[serviceName]
+		_, node := mappingEntry(services, serviceName)
+		if node == nil {
+			newServices = append(newServices, serviceName)
+			continue
+		}
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+			return nil, fmt.Errorf("服务 %s 不是块格式的映射，无法修改", serviceName)
+		}
+
+		imageEdit, err := scalarEdit(lines, node, "image", service.Image, func(raw string) bool {
+			return raw == service.Image || p.normalizeImageName(raw) == service.Image
+		})
+		if err != nil {
+			return nil, fmt.Errorf("服务 %s: %v", serviceName, err)
+		}
+		restartEdit, err := scalarEdit(lines, node, "restart", service.Restart, func(raw string) bool {
+			return raw == service.Restart
+		})
+		if err != nil {
+			return nil, fmt.Errorf("服务 %s: %v", serviceName, err)
+		}
+		for _, edit := range []*textEdit{imageEdit, restartEdit} {
+			if edit != nil {
+				edits = append(edits, *edit)
+			}
+		}
+	}
+
+	if len(newServices) > 0 {
+		edit, err := newServicesEdit(lines, root, servicesKey, services, composeFile.Services, newServices)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	return []byte(strings.Join(applyTextEdits(lines, edits), lineEnding)), nil
+}
+
+// scalarEdit 生成将服务的 key 设置为 value 的修改，value 为空或 unchanged 判断原值未变化时返回 nil
+// 已有该字段时只替换值本身并保留原有的引号风格；没有该字段时插入到服务的第一个字段之前
+func scalarEdit(lines []string, service *yaml.Node, key, value string, unchanged func(raw string) bool) (*textEdit, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	_, valueNode := mappingEntry(service, key)
+	if valueNode == nil {
+		first := service.Content[0]
+		indent := strings.Repeat(" ", first.Column-1)
+		return &textEdit{line: first.Line - 1, insert: []string{indent + key + ": " + formatScalar(value, 0)}}, nil
+	}
+
+	if valueNode.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s 不是字符串", key)
+	}
+	if unchanged(valueNode.Value) {
+		return nil, nil
+	}
+
+	line := []rune(lines[valueNode.Line-1])
+	start := valueNode.Column - 1
+	end := scalarEnd(line, start, valueNode.Style)
+	if start < 0 || start > len(line) || end < start {
+		return nil, fmt.Errorf("无法定位 %s 的值", key)
+	}
+	return &textEdit{line: valueNode.Line - 1, start: start, end: end, text: formatScalar(value, valueNode.Style)}, nil
+}
+
+// scalarEnd 返回从 start 开始的单行标量在行中的结束位置，引号标量包含结尾的引号，普通标量不包含行尾注释和空白
+func scalarEnd(line []rune, start int, style yaml.Style) int {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+				continue
+			}
+			if line[i] == '"' {
+				return i + 1
+			}
+		}
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	default:
+		rest := string(line[start:])
+		if idx := findInlineComment(rest); idx >= 0 {
+			rest = rest[:idx]
+		}
+		return start + len([]rune(strings.TrimRight(rest, " \t")))
+	}
+	return len(line)
+}
+
+// formatScalar 按原有的引号风格格式化字符串，普通风格下需要引号的值由 yaml 自动加上引号
+func formatScalar(value string, style yaml.Style) string {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		return fmt.Sprintf("%q", value)
+	case style&yaml.SingleQuotedStyle != 0:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newServicesEdit 生成在 services 末尾追加新服务的修改，缩进与已有服务一致；文件中没有 services 时追加到文件末尾
+func newServicesEdit(lines []string, root, servicesKey, services *yaml.Node, all map[string]types.Service, names []string) (textEdit, error) {
+	keyIndent, unit := 2, 2
+	if services != nil && services.Kind == yaml.MappingNode && len(services.Content) > 0 {
+		if services.Style&yaml.FlowStyle != 0 {
+			return textEdit{}, fmt.Errorf("services 不是块格式的映射，无法添加服务")
+		}
+		keyIndent = services.Content[0].Column - 1
+		if child := services.Content[1]; child.Kind == yaml.MappingNode && len(child.Content) > 0 && child.Content[0].Column-1 > keyIndent {
+			unit = child.Content[0].Column - 1 - keyIndent
+		}
+	} else if services != nil && services.Kind != yaml.ScalarNode {
+		return textEdit{}, fmt.Errorf("services 格式无效，无法添加服务")
+	}
+
+	added := make(map[string]types.Service, len(names))
+	for _, name := range names {
+		added[name] = all[name]
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(unit)
+	if err := encoder.Encode(added); err != nil {
+		return textEdit{}, fmt.Errorf("序列化新服务失败: %v", err)
+	}
+	encoder.Close()
+
+	var block []string
+	if servicesKey == nil {
+		block = append(block, "services:")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		block = append(block, strings.Repeat(" ", keyIndent)+line)
+	}
+
+	// services 为空 (services: 或 services: {}) 时替换原值所在的行
+	if servicesKey != nil && (services == nil || len(services.Content) == 0) {
+		line := []rune(lines[servicesKey.Line-1])
+		return textEdit{line: servicesKey.Line - 1, start: 0, end: len(line), text: strings.Join(append([]string{"services:"}, block...), "\n")}, nil
+	}
+
+	return textEdit{line: blockEnd(lines, root, servicesKey), insert: block}, nil
+}
+
+// blockEnd 返回顶层 key 对应内容块结束后的行下标，即下一个顶层 key (及其前面的注释) 之前，不包含末尾的空行
+// key 为 nil 时返回文件末尾
+func blockEnd(lines []string, root, key *yaml.Node) int {
+	end := len(lines)
+	if key != nil {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if next := root.Content[i]; next.Line > key.Line && next.Line-1 < end {
+				end = next.Line - 1
+			}
+		}
+	}
+	for end > 0 {
+		previous := strings.TrimSpace(lines[end-1])
+		if previous != "" && !(strings.HasPrefix(previous, "#") && leadingWhitespace(lines[end-1]) == "") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// applyTextEdits 从后往前应用修改，保证前面修改的行号不受影响
+func applyTextEdits(lines []string, edits []textEdit) []string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line > edits[j].line
+		}
+		// 同一行先替换再插入
+		return edits[i].insert == nil && edits[j].insert != nil
+	})
+
+	for _, edit := range edits {
+		if edit.insert != nil {
+			merged := make([]string, 0, len(lines)+len(edit.insert))
+			merged = append(merged, lines[:edit.line]...)
+			merged = append(merged, edit.insert...)
+			merged = append(merged, lines[edit.line:]...)
+			lines = merged
+			continue
+		}
+		line := []rune(lines[edit.line])
+		lines[edit.line] = string(line[:edit.start]) + edit.text + string(line[edit.end:])
+	}
+	return lines
+}
+
+// mappingEntry 返回映射节点中 key 对应的键节点和值节点，不存在时返回 nil
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// sortedServiceNames 返回排序后的服务名
+func sortedServiceNames(services map[string]types.Service) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,136 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"compman/pkg/types"
+)
+
+const roundTripCompose = `name: shop
+
+# 公共配置
+x-logging: &logging
+  driver: json-file
+
+services:
+  web:
+    image: nginx # 未指定标签
+    logging: *logging
+    secrets:
+      - db_password
+
+  api:
+    image: "registry.example.com/api:1.0"
+    restart: 'no'
+
+  debug:
+    image: busybox
+    profiles: ["debug"]
+
+# 敏感信息
+secrets:
+  db_password:
+    file: ./db_password.txt
+
+configs:
+  app_config:
+    file: ./app.conf
+`
+
+func TestWriteFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		modify   func(cf *types.ComposeFile)
+		want     string
+	}{
+		{
+			name:   "未修改时内容不变",
+			modify: func(cf *types.ComposeFile) {},
+			want:   roundTripCompose,
+		},
+		{
+			name: "更新镜像标签",
+			modify: func(cf *types.ComposeFile) {
+				api := cf.Services["api"]
+				api.Image = "registry.example.com/api:1.1"
+				cf.Services["api"] = api
+			},
+			want: replaceOnce(roundTripCompose,
+				`image: "registry.example.com/api:1.0"`,
+				`image: "registry.example.com/api:1.1"`),
+		},
+		{
+			name: "更新未指定标签的镜像",
+			modify: func(cf *types.ComposeFile) {
+				web := cf.Services["web"]
+				web.Image = "nginx:1.25"
+				cf.Services["web"] = web
+			},
+			want: replaceOnce(roundTripCompose, "image: nginx # 未指定标签", "image: nginx:1.25 # 未指定标签"),
+		},
+		{
+			name:     "设置重启策略时保留未启用 profile 的服务",
+			profiles: []string{"prod"},
+			modify: func(cf *types.ComposeFile) {
+				for name, service := range cf.Services {
+					service.Restart = "unless-stopped"
+					cf.Services[name] = service
+				}
+			},
+			want: replaceOnce(replaceOnce(roundTripCompose,
+				"  web:\n    image: nginx",
+				"  web:\n    restart: unless-stopped\n    image: nginx"),
+				"restart: 'no'",
+				"restart: 'unless-stopped'"),
+		},
+		{
+			name: "添加服务",
+			modify: func(cf *types.ComposeFile) {
+				cf.Services["cache"] = types.Service{Image: "redis:7"}
+			},
+			want: replaceOnce(roundTripCompose,
+				"    profiles: [\"debug\"]\n",
+				"    profiles: [\"debug\"]\n  cache:\n    image: redis:7\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "docker-compose.yml")
+			if err := os.WriteFile(filePath, []byte(roundTripCompose), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			parser := NewParser()
+			parser.SetProfiles(tt.profiles)
+			cf, err := parser.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile() error = %v", err)
+			}
+			tt.modify(cf)
+			if err := parser.WriteFileAtomic(cf, filePath); err != nil {
+				t.Fatalf("WriteFileAtomic() error = %v", err)
+			}
+
+			written, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(written) != tt.want {
+				t.Errorf("写入的内容:\n%s\nwant:\n%s", written, tt.want)
+			}
+		})
+	}
+}
+
+// replaceOnce 替换 s 中唯一出现的 old，用于构造期望的文件内容
+func replaceOnce(s, old, new string) string {
+	if strings.Count(s, old) != 1 {
+		panic("replaceOnce: " + old + " 未出现或出现多次")
+	}
+	return strings.Replace(s, old, new, 1)
+}
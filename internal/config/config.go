@@ -1,19 +1,51 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"compman/internal/strategy"
 	"compman/pkg/types"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
+// legacySemverPattern 是旧版本写入配置文件的默认值，它是正则表达式而非版本约束
+const legacySemverPattern = "^v?\\d+\\.\\d+\\.\\d+$"
+
+var (
+	configFile    string
+	config        *types.Config
+	configSources map[string]string // 加载配置时记录的配置项来源 (配置项 -> 来源说明)，与 config 一同更新
+	configMutex   sync.RWMutex      // 保护 config 和 configSources，支持配置热重载时的并发访问
+)
+
+// configPollInterval 无法使用文件系统通知时轮询配置文件的间隔
+const configPollInterval = 2 * time.Second
+
+// configWatchDebounce 合并配置文件连续变化事件的等待时间
+const configWatchDebounce = 500 * time.Millisecond
+
+// fileStamp 记录文件在某一时刻的修改时间和大小
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
 var (
-	configFile string
-	config     *types.Config
+	selfWrites      = make(map[string]fileStamp) // 本进程写入的配置文件在写入后的状态 (绝对路径 -> 状态)
+	selfWritesMutex sync.Mutex
 )
 
 // getDefaultConfigPath returns the default configuration file path
@@ -26,6 +58,11 @@ func getDefaultConfigPath() string {
 	return filepath.Join(configDir, "config.yml")
 }
 
+// DefaultConfigPath 返回默认配置文件路径 ~/.config/compman/config.yml
+func DefaultConfigPath() string {
+	return getDefaultConfigPath()
+}
+
 // ensureConfigDir creates the configuration directory if it doesn't exist
 func ensureConfigDir() error {
 	defaultPath := getDefaultConfigPath()
@@ -52,45 +89,100 @@ func SetConfigName(name string) {
 
 // LoadConfig loads configuration from file or creates default config
 func LoadConfig() (*types.Config, error) {
+	configMutex.RLock()
+	if config != nil {
+		defer configMutex.RUnlock()
+		return config, nil
+	}
+	configMutex.RUnlock()
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	return loadConfigLocked(true)
+}
+
+// loadConfigLocked 加载配置，调用方需持有 configMutex 写锁
+// persist 为 false 时只在内存中合并和迁移配置，不写回默认配置文件，用于热重载，避免写入再次触发重载
+func loadConfigLocked(persist bool) (*types.Config, error) {
 	if config != nil {
 		return config, nil
 	}
 
 	defaultPath := getDefaultConfigPath()
+	sources := newConfigSources()
 
 	// 如果用户指定了不同的配置文件，加载并合并到默认配置
 	if configFile != "" && configFile != defaultPath {
 		// 读取用户配置文件
-		userCfg, err := loadConfigFromFile(configFile)
+		userCfg, v, err := readConfigFile(configFile)
 		if err != nil {
 			return nil, fmt.Errorf("加载用户配置文件失败: %v", err)
 		}
+		recordFileSources(sources, v, fmt.Sprintf("来自 --config 指定的配置文件 %s", configFile))
 
 		// 合并配置：用户配置优先，缺失的使用系统默认配置
 		systemDefaultCfg := getDefaultConfig()
+		// 用户配置文件只在内存中迁移，不会写回，因此不提示迁移，否则每次运行都会重复提示
 		config = mergeConfigs(systemDefaultCfg, userCfg)
+		config.SchemaVersion = CurrentSchemaVersion
 
 		// 将合并后的配置保存到默认位置
-		if err := SaveConfigToDefault(config); err != nil {
-			return nil, fmt.Errorf("保存配置到默认位置失败: %v", err)
+		if persist {
+			if err := SaveConfigToDefault(config); err != nil {
+				return nil, fmt.Errorf("保存配置到默认位置失败: %v", err)
+			}
 		}
 	} else {
 		// 尝试加载默认配置文件
 		if _, err := os.Stat(defaultPath); err == nil {
-			config, err = loadConfigFromFile(defaultPath)
+			var v *viper.Viper
+			config, v, err = readConfigFile(defaultPath)
 			if err != nil {
 				return nil, fmt.Errorf("加载默认配置文件失败: %v", err)
 			}
+			recordFileSources(sources, v, fmt.Sprintf("来自默认配置文件 %s", defaultPath))
+			if oldVersion := config.SchemaVersion; oldVersion < CurrentSchemaVersion {
+				config.SchemaVersion = CurrentSchemaVersion
+				// 热重载时只在内存中迁移，迁移后的配置在下次正常加载时写回并提示
+				if persist {
+					if err := SaveConfigToDefault(config); err != nil {
+						return nil, fmt.Errorf("保存迁移后的配置失败: %v", err)
+					}
+					notifyMigrated(oldVersion)
+				}
+			}
 		} else {
 			// 配置文件不存在，使用默认配置
 			config = getDefaultConfig()
 			// 创建默认配置文件
-			if err := SaveConfigToDefault(config); err != nil {
-				return nil, fmt.Errorf("创建默认配置文件失败: %v", err)
+			if persist {
+				if err := SaveConfigToDefault(config); err != nil {
+					return nil, fmt.Errorf("创建默认配置文件失败: %v", err)
+				}
 			}
 		}
 	}
 
+	// 合并环境变量，环境变量优先级最高
+	envCfg := *config
+	if applied, err := applyEnvConfig(&envCfg); err != nil {
+		return nil, err
+	} else if applied {
+		config = mergeConfigs(config, &envCfg)
+		recordEnvSources(sources)
+	}
+
+	// 追加路径文件中的 Compose 路径，只影响本次运行，不写回配置文件
+	if config.PathsFile != "" {
+		expanded := *config
+		if err := ApplyPathsFile(&expanded, config.PathsFile); err != nil {
+			return nil, err
+		}
+		config = &expanded
+		sources["compose_paths"] += fmt.Sprintf("，并追加路径文件 %s 中的路径", config.PathsFile)
+	}
+	configSources = sources
+
 	// 验证配置
 	if err := validateConfig(config); err != nil {
 		return nil, fmt.Errorf("配置验证失败: %v", err)
@@ -99,19 +191,241 @@ func LoadConfig() (*types.Config, error) {
 	return config, nil
 }
 
+// ApplyPathsFile 读取路径文件并将其中的路径追加到 cfg.ComposePaths，已存在的路径不重复添加
+func ApplyPathsFile(cfg *types.Config, filePath string) error {
+	paths, err := readPathsFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(cfg.ComposePaths))
+	merged := append([]string(nil), cfg.ComposePaths...)
+	for _, path := range cfg.ComposePaths {
+		existing[path] = true
+	}
+	for _, path := range paths {
+		if !existing[path] {
+			existing[path] = true
+			merged = append(merged, path)
+		}
+	}
+	cfg.ComposePaths = merged
+	return nil
+}
+
+// readPathsFile 读取每行一个路径的文件，忽略空行和 # 开头的注释，相对路径相对于该文件所在目录
+func readPathsFile(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取路径文件失败: %v", err)
+	}
+
+	baseDir := filepath.Dir(filePath)
+	var paths []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(baseDir, line)
+		}
+		paths = append(paths, line)
+	}
+	return paths, nil
+}
+
+// LoadConfigFile 读取 SaveConfig 写入的配置文件并与默认配置合并，不应用环境变量
+// 用于修改配置后写回文件，避免将环境变量中的临时配置持久化
+func LoadConfigFile() (*types.Config, error) {
+	path := configFile
+	if path == "" {
+		path = getDefaultConfigPath()
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return getDefaultConfig(), nil
+	}
+
+	fileCfg, err := loadConfigFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置文件 %s 失败: %v", path, err)
+	}
+
+	// 迁移时已补充缺失的配置项，写回时记录为当前版本
+	fileCfg.SchemaVersion = CurrentSchemaVersion
+	return mergeConfigs(getDefaultConfig(), fileCfg), nil
+}
+
+// envPrefix 配置环境变量的前缀
+const envPrefix = "COMPMAN_"
+
+// LoadConfigFromEnv 从 COMPMAN_ 开头的环境变量读取配置，未设置的字段保持零值
+// 变量名为配置项名称的大写形式，如 COMPMAN_COMPOSE_PATHS、COMPMAN_DOCKER_CONFIG_HOST
+// 列表使用逗号分隔，映射使用 key=value 并以逗号分隔
+func LoadConfigFromEnv() (*types.Config, error) {
+	cfg := &types.Config{}
+	if _, err := applyEnvConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// EnvVarNames 返回所有支持的配置环境变量名称
+func EnvVarNames() []string {
+	var names []string
+	walkConfigFields(reflect.ValueOf(&types.Config{}).Elem(), envPrefix, func(name string, _ reflect.Value) {
+		names = append(names, name)
+	})
+	return names
+}
+
+// applyEnvConfig 将已设置的环境变量写入 cfg，返回是否设置了任何配置
+func applyEnvConfig(cfg *types.Config) (bool, error) {
+	applied := false
+	var firstErr error
+
+	walkConfigFields(reflect.ValueOf(cfg).Elem(), envPrefix, func(name string, field reflect.Value) {
+		value, ok := os.LookupEnv(name)
+		if !ok || firstErr != nil {
+			return
+		}
+		if err := setFieldFromEnv(field, value); err != nil {
+			firstErr = fmt.Errorf("环境变量 %s 的值无效: %v", name, err)
+			return
+		}
+		applied = true
+	})
+
+	return applied, firstErr
+}
+
+// walkConfigFields 遍历配置结构体中可序列化的字段，name 为对应的环境变量名称
+func walkConfigFields(v reflect.Value, prefix string, visit func(name string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "-" || tag == "" || !field.IsExported() {
+			continue
+		}
+
+		name := prefix + strings.ToUpper(tag)
+		if field.Type.Kind() == reflect.Struct {
+			walkConfigFields(v.Field(i), name+"_", visit)
+			continue
+		}
+		visit(name, v.Field(i))
+	}
+}
+
+// setFieldFromEnv 按字段类型解析环境变量的值
+func setFieldFromEnv(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch field.Interface().(type) {
+	case time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case []string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	case map[string]string:
+		items := make(map[string]string)
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, val, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("%q 不是 key=value 格式", pair)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	case map[string][]string:
+		items := make(map[string][]string)
+		for _, pair := range strings.Split(value, ";") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			key, val, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("%q 不是 key=value 格式", pair)
+			}
+			var list []string
+			for _, item := range strings.Split(val, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			items[strings.TrimSpace(key)] = list
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", field.Type())
+	}
+
+	return nil
+}
+
 // loadConfigFromFile loads configuration from a specific file
 func loadConfigFromFile(filePath string) (*types.Config, error) {
+	cfg, _, err := readConfigFile(filePath)
+	return cfg, err
+}
+
+// readConfigFile 读取配置文件，同时返回读取文件的 viper 实例，用于判断文件中实际设置了哪些配置项
+func readConfigFile(filePath string) (*types.Config, *viper.Viper, error) {
 	v := viper.New()
 	v.SetConfigFile(filePath)
 	v.SetConfigType("yaml")
 
 	if err := v.ReadInConfig(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// 旧版本配置文件缺少后来新增的配置项，先补充默认值再解析
+	// 返回的 SchemaVersion 仍为文件中记录的版本，由调用方决定是否写回
+	storedVersion := v.GetInt("schema_version")
+	if storedVersion < CurrentSchemaVersion {
+		if err := migrateViper(v, storedVersion, CurrentSchemaVersion); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	cfg := &types.Config{}
 	if err := v.Unmarshal(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 手动设置如果 Unmarshal 失败或为空
@@ -130,9 +444,80 @@ func loadConfigFromFile(filePath string) (*types.Config, error) {
 	if cfg.SemverPattern == "" {
 		cfg.SemverPattern = v.GetString("semver_pattern")
 	}
+	if cfg.WebhookURL == "" {
+		cfg.WebhookURL = v.GetString("webhook_url")
+	}
+	if cfg.ComposeEnvFile == "" {
+		cfg.ComposeEnvFile = v.GetString("compose_env_file")
+	}
 	// 布尔值总是需要手动设置
 	cfg.BackupEnabled = v.GetBool("backup_enabled")
 	cfg.DryRun = v.GetBool("dry_run")
+	cfg.CleanVolumes = v.GetBool("clean_volumes")
+	if len(cfg.ProjectNameOverride) == 0 {
+		cfg.ProjectNameOverride = v.GetStringMapString("project_name_override")
+	}
+	// viper 会将映射的键转为小写，而 pinned_services 的键是区分大小写的文件路径，因此单独解析
+	pinned, err := readPinnedServices(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.PinnedServices = pinned
+	if cfg.RestartPolicy == "" {
+		cfg.RestartPolicy = v.GetString("restart_policy")
+	}
+	if cfg.MaxParallel == 0 {
+		cfg.MaxParallel = v.GetInt("max_parallel")
+	}
+	if len(cfg.ComposeProfiles) == 0 {
+		cfg.ComposeProfiles = v.GetStringSlice("compose_profiles")
+	}
+	cfg.CompactOutput = v.GetBool("compact_output")
+	cfg.CleanNetworks = v.GetBool("clean_networks")
+	if cfg.TagFormat == "" {
+		cfg.TagFormat = v.GetString("tag_format")
+	}
+	cfg.RespectDependencies = v.GetBool("respect_dependencies")
+	cfg.BackupBeforePull = v.GetBool("backup_before_pull")
+	if cfg.DockerConfig.SSHHost == "" {
+		cfg.DockerConfig.SSHHost = v.GetString("docker_config.ssh_host")
+	}
+	if cfg.DockerConfig.SSHKeyPath == "" {
+		cfg.DockerConfig.SSHKeyPath = v.GetString("docker_config.ssh_key_path")
+	}
+	if cfg.Platform == "" {
+		cfg.Platform = v.GetString("platform")
+	}
+	// 默认开启，旧配置文件中没有该项时保持默认值
+	cfg.SkipUpToDate = !v.IsSet("skip_up_to_date") || v.GetBool("skip_up_to_date")
+	cfg.UseDirectPull = v.GetBool("use_direct_pull")
+	cfg.SchemaVersion = storedVersion
+	if cfg.LogFile == "" {
+		cfg.LogFile = v.GetString("log_file")
+	}
+	if cfg.ComposeVersion == "" {
+		cfg.ComposeVersion = v.GetString("compose_version")
+	}
+	if cfg.RegexPattern == "" {
+		cfg.RegexPattern = v.GetString("regex_pattern")
+	}
+	// 捕获组 0 表示整个匹配，只有文件中未设置时才使用默认值
+	if v.IsSet("regex_capture_group") {
+		cfg.RegexCaptureGroup = v.GetInt("regex_capture_group")
+	} else {
+		cfg.RegexCaptureGroup = getDefaultConfig().RegexCaptureGroup
+	}
+	cfg.RemoveOrphans = v.GetBool("remove_orphans")
+	if cfg.ComposeEncoding == "" {
+		cfg.ComposeEncoding = v.GetString("compose_encoding")
+	}
+	cfg.UseComposeLibrary = v.GetBool("use_compose_library")
+	if cfg.RollbackStrategy == "" {
+		cfg.RollbackStrategy = v.GetString("rollback_strategy")
+	}
+	if cfg.PathsFile == "" {
+		cfg.PathsFile = v.GetString("paths_file")
+	}
 
 	// 处理持续时间
 	if cfg.Timeout == 0 {
@@ -142,8 +527,52 @@ func loadConfigFromFile(filePath string) (*types.Config, error) {
 			}
 		}
 	}
+	if cfg.RegistryAPITimeout == 0 {
+		if timeoutStr := v.GetString("registry_api_timeout"); timeoutStr != "" {
+			if duration, err := time.ParseDuration(timeoutStr); err == nil {
+				cfg.RegistryAPITimeout = duration
+			}
+		}
+	}
+	if cfg.PullTimeout == 0 {
+		if durationStr := v.GetString("pull_timeout"); durationStr != "" {
+			if duration, err := time.ParseDuration(durationStr); err == nil {
+				cfg.PullTimeout = duration
+			}
+		}
+	}
+	if cfg.UpTimeout == 0 {
+		if durationStr := v.GetString("up_timeout"); durationStr != "" {
+			if duration, err := time.ParseDuration(durationStr); err == nil {
+				cfg.UpTimeout = duration
+			}
+		}
+	}
+	if cfg.GracePeriod == 0 {
+		if durationStr := v.GetString("grace_period"); durationStr != "" {
+			if duration, err := time.ParseDuration(durationStr); err == nil {
+				cfg.GracePeriod = duration
+			}
+		}
+	}
 
-	return cfg, nil
+	return cfg, v, nil
+}
+
+// readPinnedServices 直接从配置文件解析 pinned_services，保留文件路径的大小写
+func readPinnedServices(filePath string) (map[string][]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		PinnedServices map[string][]string `yaml:"pinned_services"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析 pinned_services 失败: %v", err)
+	}
+	return raw.PinnedServices, nil
 }
 
 // SaveConfig saves the current configuration to file
@@ -166,14 +595,47 @@ func SaveConfig(cfg *types.Config) error {
 	viper.Set("dry_run", cfg.DryRun)
 	viper.Set("backup_enabled", cfg.BackupEnabled)
 	viper.Set("timeout", cfg.Timeout)
+	viper.Set("registry_api_timeout", cfg.RegistryAPITimeout.String())
 	viper.Set("docker_config", cfg.DockerConfig)
+	viper.Set("webhook_url", cfg.WebhookURL)
+	viper.Set("compose_env_file", cfg.ComposeEnvFile)
+	viper.Set("clean_volumes", cfg.CleanVolumes)
+	viper.Set("project_name_override", cfg.ProjectNameOverride)
+	viper.Set("pinned_services", cfg.PinnedServices)
+	viper.Set("restart_policy", cfg.RestartPolicy)
+	viper.Set("max_parallel", cfg.MaxParallel)
+	viper.Set("compose_profiles", cfg.ComposeProfiles)
+	viper.Set("compact_output", cfg.CompactOutput)
+	viper.Set("clean_networks", cfg.CleanNetworks)
+	viper.Set("tag_format", cfg.TagFormat)
+	viper.Set("respect_dependencies", cfg.RespectDependencies)
+	viper.Set("backup_before_pull", cfg.BackupBeforePull)
+	viper.Set("platform", cfg.Platform)
+	viper.Set("skip_up_to_date", cfg.SkipUpToDate)
+	viper.Set("use_direct_pull", cfg.UseDirectPull)
+	viper.Set("schema_version", cfg.SchemaVersion)
+	viper.Set("log_file", cfg.LogFile)
+	viper.Set("compose_version", cfg.ComposeVersion)
+	viper.Set("regex_pattern", cfg.RegexPattern)
+	viper.Set("regex_capture_group", cfg.RegexCaptureGroup)
+	viper.Set("pull_timeout", cfg.PullTimeout.String())
+	viper.Set("up_timeout", cfg.UpTimeout.String())
+	viper.Set("remove_orphans", cfg.RemoveOrphans)
+	viper.Set("compose_encoding", cfg.ComposeEncoding)
+	viper.Set("use_compose_library", cfg.UseComposeLibrary)
+	viper.Set("grace_period", cfg.GracePeriod.String())
+	viper.Set("rollback_strategy", cfg.RollbackStrategy)
+	viper.Set("paths_file", cfg.PathsFile)
 
 	// 写入文件
 	if err := viper.WriteConfigAs(configFile); err != nil {
 		return fmt.Errorf("写入配置文件失败: %v", err)
 	}
+	recordSelfWrite(configFile)
 
+	configMutex.Lock()
 	config = cfg
+	configMutex.Unlock()
 	return nil
 }
 
@@ -200,12 +662,43 @@ func SaveConfigToDefault(cfg *types.Config) error {
 	v.Set("dry_run", cfg.DryRun)
 	v.Set("backup_enabled", cfg.BackupEnabled)
 	v.Set("timeout", cfg.Timeout)
+	v.Set("registry_api_timeout", cfg.RegistryAPITimeout.String())
 	v.Set("docker_config", cfg.DockerConfig)
+	v.Set("webhook_url", cfg.WebhookURL)
+	v.Set("compose_env_file", cfg.ComposeEnvFile)
+	v.Set("clean_volumes", cfg.CleanVolumes)
+	v.Set("project_name_override", cfg.ProjectNameOverride)
+	v.Set("pinned_services", cfg.PinnedServices)
+	v.Set("restart_policy", cfg.RestartPolicy)
+	v.Set("max_parallel", cfg.MaxParallel)
+	v.Set("compose_profiles", cfg.ComposeProfiles)
+	v.Set("compact_output", cfg.CompactOutput)
+	v.Set("clean_networks", cfg.CleanNetworks)
+	v.Set("tag_format", cfg.TagFormat)
+	v.Set("respect_dependencies", cfg.RespectDependencies)
+	v.Set("backup_before_pull", cfg.BackupBeforePull)
+	v.Set("platform", cfg.Platform)
+	v.Set("skip_up_to_date", cfg.SkipUpToDate)
+	v.Set("use_direct_pull", cfg.UseDirectPull)
+	v.Set("schema_version", cfg.SchemaVersion)
+	v.Set("log_file", cfg.LogFile)
+	v.Set("compose_version", cfg.ComposeVersion)
+	v.Set("regex_pattern", cfg.RegexPattern)
+	v.Set("regex_capture_group", cfg.RegexCaptureGroup)
+	v.Set("pull_timeout", cfg.PullTimeout.String())
+	v.Set("up_timeout", cfg.UpTimeout.String())
+	v.Set("remove_orphans", cfg.RemoveOrphans)
+	v.Set("compose_encoding", cfg.ComposeEncoding)
+	v.Set("use_compose_library", cfg.UseComposeLibrary)
+	v.Set("grace_period", cfg.GracePeriod.String())
+	v.Set("rollback_strategy", cfg.RollbackStrategy)
+	v.Set("paths_file", cfg.PathsFile)
 
 	// 写入文件
 	if err := v.WriteConfig(); err != nil {
 		return fmt.Errorf("写入默认配置文件失败: %v", err)
 	}
+	recordSelfWrite(defaultPath)
 
 	return nil
 }
@@ -245,10 +738,99 @@ func mergeConfigs(defaultCfg, userCfg *types.Config) *types.Config {
 	if userCfg.BackupEnabled != defaultCfg.BackupEnabled {
 		merged.BackupEnabled = userCfg.BackupEnabled
 	}
+	if userCfg.CleanVolumes != defaultCfg.CleanVolumes {
+		merged.CleanVolumes = userCfg.CleanVolumes
+	}
 
 	if userCfg.Timeout > 0 {
 		merged.Timeout = userCfg.Timeout
 	}
+	if userCfg.RegistryAPITimeout > 0 {
+		merged.RegistryAPITimeout = userCfg.RegistryAPITimeout
+	}
+	if userCfg.WebhookURL != "" {
+		merged.WebhookURL = userCfg.WebhookURL
+	}
+	if userCfg.ComposeEnvFile != "" {
+		merged.ComposeEnvFile = userCfg.ComposeEnvFile
+	}
+	if len(userCfg.ProjectNameOverride) > 0 {
+		merged.ProjectNameOverride = userCfg.ProjectNameOverride
+	}
+	if len(userCfg.PinnedServices) > 0 {
+		merged.PinnedServices = userCfg.PinnedServices
+	}
+	if userCfg.RestartPolicy != "" {
+		merged.RestartPolicy = userCfg.RestartPolicy
+	}
+	if userCfg.MaxParallel > 0 {
+		merged.MaxParallel = userCfg.MaxParallel
+	}
+	if len(userCfg.ComposeProfiles) > 0 {
+		merged.ComposeProfiles = userCfg.ComposeProfiles
+	}
+	if userCfg.CompactOutput != defaultCfg.CompactOutput {
+		merged.CompactOutput = userCfg.CompactOutput
+	}
+	if userCfg.CleanNetworks != defaultCfg.CleanNetworks {
+		merged.CleanNetworks = userCfg.CleanNetworks
+	}
+	if userCfg.TagFormat != "" {
+		merged.TagFormat = userCfg.TagFormat
+	}
+	if userCfg.RespectDependencies != defaultCfg.RespectDependencies {
+		merged.RespectDependencies = userCfg.RespectDependencies
+	}
+	if userCfg.BackupBeforePull != defaultCfg.BackupBeforePull {
+		merged.BackupBeforePull = userCfg.BackupBeforePull
+	}
+	if userCfg.Platform != "" {
+		merged.Platform = userCfg.Platform
+	}
+	if userCfg.SkipUpToDate != defaultCfg.SkipUpToDate {
+		merged.SkipUpToDate = userCfg.SkipUpToDate
+	}
+	if userCfg.UseDirectPull != defaultCfg.UseDirectPull {
+		merged.UseDirectPull = userCfg.UseDirectPull
+	}
+	if userCfg.SchemaVersion > 0 {
+		merged.SchemaVersion = userCfg.SchemaVersion
+	}
+	if userCfg.LogFile != "" {
+		merged.LogFile = userCfg.LogFile
+	}
+	if userCfg.ComposeVersion != "" {
+		merged.ComposeVersion = userCfg.ComposeVersion
+	}
+	if userCfg.RegexPattern != "" {
+		merged.RegexPattern = userCfg.RegexPattern
+	}
+	// 读取配置文件时已为未设置的 regex_capture_group 补充默认值，0 是有效的取值
+	merged.RegexCaptureGroup = userCfg.RegexCaptureGroup
+	if userCfg.PullTimeout > 0 {
+		merged.PullTimeout = userCfg.PullTimeout
+	}
+	if userCfg.UpTimeout > 0 {
+		merged.UpTimeout = userCfg.UpTimeout
+	}
+	if userCfg.RemoveOrphans != defaultCfg.RemoveOrphans {
+		merged.RemoveOrphans = userCfg.RemoveOrphans
+	}
+	if userCfg.ComposeEncoding != "" {
+		merged.ComposeEncoding = userCfg.ComposeEncoding
+	}
+	if userCfg.UseComposeLibrary != defaultCfg.UseComposeLibrary {
+		merged.UseComposeLibrary = userCfg.UseComposeLibrary
+	}
+	if userCfg.GracePeriod > 0 {
+		merged.GracePeriod = userCfg.GracePeriod
+	}
+	if userCfg.RollbackStrategy != "" {
+		merged.RollbackStrategy = userCfg.RollbackStrategy
+	}
+	if userCfg.PathsFile != "" {
+		merged.PathsFile = userCfg.PathsFile
+	}
 
 	// Docker 配置合并
 	if userCfg.DockerConfig.Host != "" {
@@ -263,6 +845,12 @@ func mergeConfigs(defaultCfg, userCfg *types.Config) *types.Config {
 	if userCfg.DockerConfig.TLSVerify != defaultCfg.DockerConfig.TLSVerify {
 		merged.DockerConfig.TLSVerify = userCfg.DockerConfig.TLSVerify
 	}
+	if userCfg.DockerConfig.SSHHost != "" {
+		merged.DockerConfig.SSHHost = userCfg.DockerConfig.SSHHost
+	}
+	if userCfg.DockerConfig.SSHKeyPath != "" {
+		merged.DockerConfig.SSHKeyPath = userCfg.DockerConfig.SSHKeyPath
+	}
 
 	return &merged
 }
@@ -284,35 +872,98 @@ func setDefaults() {
 	viper.SetDefault("compose_paths", []string{"./docker-compose.yml", "./compose.yml"})
 	viper.SetDefault("image_tag_strategy", "latest")
 	viper.SetDefault("environment", "production")
-	viper.SetDefault("semver_pattern", "^v?\\d+\\.\\d+\\.\\d+$")
+	viper.SetDefault("semver_pattern", "*")
 	viper.SetDefault("exclude_images", []string{})
 	viper.SetDefault("dry_run", false)
 	viper.SetDefault("backup_enabled", true)
 	viper.SetDefault("timeout", "5m")
+	viper.SetDefault("registry_api_timeout", "30s")
+	viper.SetDefault("webhook_url", "")
+	viper.SetDefault("compose_env_file", "")
+	viper.SetDefault("clean_volumes", false)
+	viper.SetDefault("project_name_override", map[string]string{})
+	viper.SetDefault("pinned_services", map[string][]string{})
+	viper.SetDefault("restart_policy", "")
+	viper.SetDefault("max_parallel", 1)
+	viper.SetDefault("compose_profiles", []string{})
+	viper.SetDefault("compact_output", false)
+	viper.SetDefault("clean_networks", false)
+	viper.SetDefault("tag_format", "")
+	viper.SetDefault("respect_dependencies", false)
+	viper.SetDefault("backup_before_pull", false)
+	viper.SetDefault("platform", "")
+	viper.SetDefault("skip_up_to_date", true)
+	viper.SetDefault("use_direct_pull", false)
+	viper.SetDefault("schema_version", CurrentSchemaVersion)
+	viper.SetDefault("log_file", "")
+	viper.SetDefault("compose_version", "auto")
+	viper.SetDefault("regex_pattern", "")
+	viper.SetDefault("regex_capture_group", 1)
+	viper.SetDefault("pull_timeout", "30m")
+	viper.SetDefault("up_timeout", "2m")
+	viper.SetDefault("remove_orphans", true)
+	viper.SetDefault("compose_encoding", "utf-8")
+	viper.SetDefault("use_compose_library", false)
+	viper.SetDefault("grace_period", "")
+	viper.SetDefault("rollback_strategy", "full")
+	viper.SetDefault("paths_file", "")
 
 	// Docker configuration defaults
 	viper.SetDefault("docker_config.host", "")
 	viper.SetDefault("docker_config.api_version", "")
 	viper.SetDefault("docker_config.tls_verify", false)
 	viper.SetDefault("docker_config.cert_path", "")
+	viper.SetDefault("docker_config.ssh_host", "")
+	viper.SetDefault("docker_config.ssh_key_path", "")
 }
 
 // getDefaultConfig returns a default configuration
 func getDefaultConfig() *types.Config {
 	return &types.Config{
-		ComposePaths:     []string{"./docker-compose.yml", "./compose.yml"},
-		ImageTagStrategy: "latest",
-		Environment:      "production",
-		SemverPattern:    "^v?\\d+\\.\\d+\\.\\d+$",
-		ExcludeImages:    []string{},
-		DryRun:           false,
-		BackupEnabled:    true,
-		Timeout:          5 * time.Minute,
+		ComposePaths:        []string{"./docker-compose.yml", "./compose.yml"},
+		ImageTagStrategy:    "latest",
+		Environment:         "production",
+		SemverPattern:       "*",
+		ExcludeImages:       []string{},
+		DryRun:              false,
+		BackupEnabled:       true,
+		Timeout:             5 * time.Minute,
+		RegistryAPITimeout:  30 * time.Second,
+		WebhookURL:          "",
+		ComposeEnvFile:      "",
+		CleanVolumes:        false,
+		ProjectNameOverride: map[string]string{},
+		PinnedServices:      map[string][]string{},
+		RestartPolicy:       "",
+		MaxParallel:         1,
+		ComposeProfiles:     []string{},
+		CompactOutput:       false,
+		CleanNetworks:       false,
+		TagFormat:           "",
+		RespectDependencies: false,
+		BackupBeforePull:    false,
+		Platform:            "",
+		SkipUpToDate:        true,
+		UseDirectPull:       false,
+		SchemaVersion:       CurrentSchemaVersion,
+		LogFile:             "",
+		ComposeVersion:      "auto",
+		RegexPattern:        "",
+		RegexCaptureGroup:   1,
+		PullTimeout:         30 * time.Minute,
+		UpTimeout:           2 * time.Minute,
+		RemoveOrphans:       true,
+		ComposeEncoding:     "utf-8",
+		UseComposeLibrary:   false,
+		RollbackStrategy:    "full",
+		PathsFile:           "",
 		DockerConfig: types.DockerConfig{
 			Host:       "",
 			APIVersion: "",
 			TLSVerify:  false,
 			CertPath:   "",
+			SSHHost:    "",
+			SSHKeyPath: "",
 		},
 	}
 }
@@ -326,30 +977,448 @@ func validateConfig(cfg *types.Config) error {
 	validStrategies := map[string]bool{
 		"latest": true,
 		"semver": true,
+		"regex":  true,
 	}
 
 	if !validStrategies[cfg.ImageTagStrategy] {
-		return fmt.Errorf("无效的镜像标签策略: %s (支持: latest, semver)", cfg.ImageTagStrategy)
+		return fmt.Errorf("无效的镜像标签策略: %s (支持: latest, semver, regex)", cfg.ImageTagStrategy)
+	}
+
+	if cfg.ImageTagStrategy == "regex" {
+		if err := strategy.ValidateRegexPattern(cfg.RegexPattern, cfg.RegexCaptureGroup); err != nil {
+			return err
+		}
+	}
+
+	// 旧版默认值无法解析为版本约束，按接受任意版本处理
+	if cfg.SemverPattern == legacySemverPattern {
+		cfg.SemverPattern = "*"
+	}
+
+	if err := validateSemverConstraint(cfg.SemverPattern); err != nil {
+		return err
+	}
+
+	validRestartPolicies := map[string]bool{
+		"":               true,
+		"always":         true,
+		"unless-stopped": true,
+		"on-failure":     true,
+	}
+
+	if !validRestartPolicies[cfg.RestartPolicy] {
+		return fmt.Errorf("无效的重启策略: %s (支持: always, unless-stopped, on-failure)", cfg.RestartPolicy)
+	}
+
+	if cfg.SinceTag != "" {
+		if _, err := semver.NewVersion(cfg.SinceTag); err != nil {
+			return fmt.Errorf("无效的 --since-tag 版本 %q: %v", cfg.SinceTag, err)
+		}
+	}
+
+	if cfg.ServicesOnly != "" {
+		if _, err := regexp.Compile(cfg.ServicesOnly); err != nil {
+			return fmt.Errorf("无效的 --services-only 正则表达式 %q: %v", cfg.ServicesOnly, err)
+		}
+	}
+
+	switch cfg.ComposeVersion {
+	case "", "auto", "v1", "v2":
+	default:
+		return fmt.Errorf("无效的 Compose 版本: %s (支持: auto, v1, v2)", cfg.ComposeVersion)
+	}
+
+	switch strings.ToLower(cfg.ComposeEncoding) {
+	case "", "utf-8", "utf8", "cp1252", "windows-1252", "latin-1", "latin1", "iso-8859-1":
+	default:
+		return fmt.Errorf("无效的 Compose 文件编码: %s (支持: utf-8, cp1252, latin-1)", cfg.ComposeEncoding)
+	}
+
+	if cfg.Platform != "" {
+		if err := validatePlatform(cfg.Platform); err != nil {
+			return err
+		}
+	}
+
+	switch cfg.RollbackStrategy {
+	case "", "compose-file", "containers", "full":
+	default:
+		return fmt.Errorf("无效的回滚策略: %s (支持: compose-file, containers, full)", cfg.RollbackStrategy)
+	}
+
+	if cfg.GracePeriod < 0 {
+		return fmt.Errorf("无效的宽限期: %s (不能为负数)", cfg.GracePeriod)
+	}
+
+	switch cfg.UpdateStrategy {
+	case "", "file-by-file", "batch":
+	default:
+		return fmt.Errorf("无效的更新策略: %s (支持: file-by-file, batch)", cfg.UpdateStrategy)
+	}
+
+	if cfg.WaitForStable < 0 {
+		return fmt.Errorf("无效的 --wait-for-stable: %s (不能为负数)", cfg.WaitForStable)
 	}
 
 	if cfg.Timeout <= 0 {
 		cfg.Timeout = 5 * time.Minute
 	}
 
+	if cfg.RegistryAPITimeout <= 0 {
+		cfg.RegistryAPITimeout = 30 * time.Second
+	}
+
+	if cfg.MaxParallel <= 0 {
+		cfg.MaxParallel = 1
+	}
+
+	return nil
+}
+
+// validatePlatform 检查平台格式是否为 os/arch 或 os/arch/variant
+func validatePlatform(platform string) error {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("无效的平台 %q (格式: os/arch[/variant]，如 linux/amd64)", platform)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return fmt.Errorf("无效的平台 %q (格式: os/arch[/variant]，如 linux/amd64)", platform)
+		}
+	}
+	return nil
+}
+
+// validateSemverConstraint 验证语义版本约束是否可以被解析
+// 支持 ~1.2.0 (仅补丁版本)、^1.0.0 (次版本和补丁版本)、>=、< 以及逗号组合的约束
+func validateSemverConstraint(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+
+	if _, err := semver.NewConstraint(pattern); err != nil {
+		return fmt.Errorf("无效的语义版本约束 %q: %v", pattern, err)
+	}
+
 	return nil
 }
 
+// Validate 验证配置，用于命令行参数覆盖配置后再次校验
+func Validate(cfg *types.Config) error {
+	return validateConfig(cfg)
+}
+
+// DiffFromDefault 返回与默认配置不同的字段，键为 YAML 字段名（嵌套字段以 . 连接）
+func DiffFromDefault(cfg *types.Config) map[string]interface{} {
+	diff := make(map[string]interface{})
+	if cfg == nil {
+		return diff
+	}
+
+	diffStruct(reflect.ValueOf(*cfg), reflect.ValueOf(*getDefaultConfig()), "", diff)
+	return diff
+}
+
+// diffStruct 逐字段比较两个结构体，将不同的字段写入 diff
+func diffStruct(current, defaults reflect.Value, prefix string, diff map[string]interface{}) {
+	t := current.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := prefix + name
+
+		cur, def := current.Field(i), defaults.Field(i)
+		if cur.Kind() == reflect.Struct {
+			diffStruct(cur, def, key+".", diff)
+			continue
+		}
+
+		if !fieldEqual(cur, def) {
+			diff[key] = cur.Interface()
+		}
+	}
+}
+
+// fieldEqual 比较两个字段的值，nil 与空集合视为相同
+func fieldEqual(a, b reflect.Value) bool {
+	if (a.Kind() == reflect.Slice || a.Kind() == reflect.Map) && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// GetEffectiveConfig 返回当前生效的配置以及每个配置项的来源说明，如 "timeout: 默认值"
+// 来源在加载配置时记录：环境变量优先于配置文件，配置文件中显式设置的配置项即使与默认值相同也记为来自该文件
+// 加载配置失败时返回 nil
+func GetEffectiveConfig() (*types.Config, []string) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, nil
+	}
+
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	var sources []string
+	walkConfigKeys(reflect.TypeOf(types.Config{}), "", envPrefix, func(key, _ string) {
+		sources = append(sources, fmt.Sprintf("%s: %s", key, configSources[key]))
+	})
+	return cfg, sources
+}
+
+// newConfigSources 创建所有配置项都来自默认值的来源记录
+func newConfigSources() map[string]string {
+	sources := make(map[string]string)
+	walkConfigKeys(reflect.TypeOf(types.Config{}), "", envPrefix, func(key, _ string) {
+		sources[key] = "默认值"
+	})
+	return sources
+}
+
+// recordFileSources 将配置文件中实际设置的配置项记录为来自该文件，迁移时补充的默认值不计入
+func recordFileSources(sources map[string]string, v *viper.Viper, source string) {
+	walkConfigKeys(reflect.TypeOf(types.Config{}), "", envPrefix, func(key, _ string) {
+		if v.InConfig(key) {
+			sources[key] = source
+		}
+	})
+}
+
+// recordEnvSources 将设置了对应环境变量的配置项记录为来自环境变量
+func recordEnvSources(sources map[string]string) {
+	walkConfigKeys(reflect.TypeOf(types.Config{}), "", envPrefix, func(key, name string) {
+		if _, ok := os.LookupEnv(name); ok {
+			sources[key] = fmt.Sprintf("来自环境变量 %s", name)
+		}
+	})
+}
+
+// walkConfigKeys 遍历配置结构体中可序列化的字段，key 为以 . 连接的 YAML 键，name 为对应的环境变量名称
+func walkConfigKeys(t reflect.Type, keyPrefix, envName string, visit func(key, name string)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "-" || tag == "" || !field.IsExported() {
+			continue
+		}
+
+		key := keyPrefix + tag
+		name := envName + strings.ToUpper(tag)
+		if field.Type.Kind() == reflect.Struct {
+			walkConfigKeys(field.Type, key+".", name+"_", visit)
+			continue
+		}
+		visit(key, name)
+	}
+}
+
 // GetConfig returns the current configuration
 func GetConfig() *types.Config {
-	if config == nil {
-		config, _ = LoadConfig()
-	}
-	return config
+	cfg, _ := LoadConfig()
+	return cfg
 }
 
 // ReloadConfig reloads the configuration from file
+// 重新加载时不会写回默认配置文件
 func ReloadConfig() error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	previous := config
 	config = nil
-	_, err := LoadConfig()
-	return err
+	if _, err := loadConfigLocked(false); err != nil {
+		// 重新加载失败时保留原有配置
+		config = previous
+		return err
+	}
+	return nil
+}
+
+// ListConfigFiles 按应用顺序返回参与当前配置的文件的绝对路径：默认配置文件、--config 指定的配置文件 (如有)，
+// 以及配置中 paths_file 指定的路径文件 (配置已加载且设置了该项时)
+func ListConfigFiles() []string {
+	candidates := []string{getDefaultConfigPath()}
+	if configFile != "" {
+		candidates = append(candidates, configFile)
+	}
+
+	configMutex.RLock()
+	if config != nil && config.PathsFile != "" {
+		candidates = append(candidates, config.PathsFile)
+	}
+	configMutex.RUnlock()
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		path, err := filepath.Abs(candidate)
+		if err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+	return files
+}
+
+// WatchConfigFile 监控 ListConfigFiles 返回的配置文件，任一文件在磁盘上发生变化时向返回的通道发送一次通知
+// 连续的多次写入会被合并，本进程自身写入配置文件引起的变化会被忽略；无法使用文件系统通知时退回到定时轮询
+// ctx 取消后停止监控
+func WatchConfigFile(ctx context.Context) (<-chan struct{}, error) {
+	paths := ListConfigFiles()
+	watched := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		watched[path] = true
+	}
+
+	changes := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+			// 已有未处理的通知
+		}
+	}
+	pollAll := func() {
+		for _, path := range paths {
+			go pollConfigFile(ctx, path, notify)
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		pollAll()
+		return changes, nil
+	}
+
+	// 监控所在目录，以便捕获编辑器通过重命名替换文件的情况
+	for _, path := range paths {
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			pollAll()
+			return changes, nil
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		pending := make(map[string]bool)
+
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				path := filepath.Clean(event.Name)
+				if !watched[path] || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				pending[path] = true
+
+				// 重置防抖计时器
+				if timer == nil {
+					timer = time.NewTimer(configWatchDebounce)
+				} else {
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					timer.Reset(configWatchDebounce)
+				}
+				timerC = timer.C
+			case <-timerC:
+				timerC = nil
+				for path := range pending {
+					if !isSelfWrite(path) {
+						notify()
+						break
+					}
+				}
+				pending = make(map[string]bool)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// pollConfigFile 定时检查配置文件的修改时间和大小，直到 ctx 取消
+func pollConfigFile(ctx context.Context, path string, notify func()) {
+	var last fileStamp
+	if info, err := os.Stat(path); err == nil {
+		last = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(last.modTime) || info.Size() != last.size {
+			last = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			if !isSelfWrite(path) {
+				notify()
+			}
+		}
+	}
+}
+
+// recordSelfWrite 记录本进程写入配置文件后的状态，监控时据此忽略自身写入引起的变化
+func recordSelfWrite(path string) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return
+	}
+
+	selfWritesMutex.Lock()
+	defer selfWritesMutex.Unlock()
+	selfWrites[absPath] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// isSelfWrite 检查文件的当前状态是否与本进程最后一次写入后的状态相同
+func isSelfWrite(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	selfWritesMutex.Lock()
+	defer selfWritesMutex.Unlock()
+	stamp, ok := selfWrites[path]
+	return ok && stamp.modTime.Equal(info.ModTime()) && stamp.size == info.Size()
 }
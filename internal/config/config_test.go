@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateSemverConstraint(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		wantErr bool
+	}{
+		{name: "空约束", pattern: "", wantErr: false},
+		{name: "仅空白", pattern: "   ", wantErr: false},
+		{name: "波浪号", pattern: "~1.2.3", wantErr: false},
+		{name: "波浪号省略补丁版本", pattern: "~1.2", wantErr: false},
+		{name: "插入号", pattern: "^1.2.3", wantErr: false},
+		{name: "插入号主版本为零", pattern: "^0.2", wantErr: false},
+		{name: "大于等于", pattern: ">=1.0.0", wantErr: false},
+		{name: "小于", pattern: "<2.0.0", wantErr: false},
+		{name: "带前后空白", pattern: "  >= 1.2  ", wantErr: false},
+		{name: "逗号组合", pattern: ">=1.2.0, <2.0.0", wantErr: false},
+		{name: "空格组合", pattern: ">=1.2.0 <2.0.0", wantErr: false},
+		{name: "或组合", pattern: "~1.2 || ^2.0", wantErr: false},
+		{name: "通配符", pattern: "1.x", wantErr: false},
+		{name: "非版本字符串", pattern: "latest", wantErr: true},
+		{name: "缺少版本号", pattern: ">=", wantErr: true},
+		{name: "非法运算符", pattern: "!1.0.0", wantErr: true},
+		{name: "组合中含非法版本", pattern: ">=1.0.0, <abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSemverConstraint(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSemverConstraint(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// useTempConfig 将默认配置目录指向临时目录并使用 userConfig 作为 --config 指定的配置文件，测试结束后恢复全局状态
+func useTempConfig(t *testing.T, userConfig string) (defaultPath, userPath string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	userPath = filepath.Join(home, "compman.yml")
+	if err := os.WriteFile(userPath, []byte(userConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previousFile, previousConfig := configFile, config
+	configFile, config = userPath, nil
+	t.Cleanup(func() {
+		configMutex.Lock()
+		defer configMutex.Unlock()
+		configFile, config = previousFile, previousConfig
+	})
+
+	return getDefaultConfigPath(), userPath
+}
+
+func TestReloadConfigDoesNotWriteDefaultFile(t *testing.T) {
+	defaultPath, _ := useTempConfig(t, "compose_paths:\n  - /srv/compose\n")
+
+	if err := ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig() error = %v", err)
+	}
+	if _, err := os.Stat(defaultPath); !os.IsNotExist(err) {
+		t.Errorf("重新加载后写入了默认配置文件 %s (err = %v)", defaultPath, err)
+	}
+	if got := GetConfig().ComposePaths; !reflect.DeepEqual(got, []string{"/srv/compose"}) {
+		t.Errorf("ComposePaths = %v, want [/srv/compose]", got)
+	}
+}
+
+func TestWatchConfigFileIgnoresSelfWrites(t *testing.T) {
+	_, userPath := useTempConfig(t, "compose_paths:\n  - /srv/compose\n")
+	if err := ensureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := WatchConfigFile(ctx)
+	if err != nil {
+		t.Fatalf("WatchConfigFile() error = %v", err)
+	}
+
+	if err := SaveConfigToDefault(getDefaultConfig()); err != nil {
+		t.Fatalf("SaveConfigToDefault() error = %v", err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("本进程写入默认配置文件后收到了变化通知")
+	case <-time.After(3 * configWatchDebounce):
+	}
+
+	if err := os.WriteFile(userPath, []byte("compose_paths:\n  - /srv/other\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(3*configWatchDebounce + configPollInterval):
+		t.Fatal("修改配置文件后没有收到变化通知")
+	}
+}
+
+func TestLoadConfigPullAndUpTimeoutDefaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		userConfig string
+		wantPull   time.Duration
+		wantUp     time.Duration
+	}{
+		{
+			name:       "未设置时使用各自的默认值",
+			userConfig: "compose_paths:\n  - /srv/compose\ntimeout: \"10m\"\n",
+			wantPull:   30 * time.Minute,
+			wantUp:     2 * time.Minute,
+		},
+		{
+			name:       "配置文件中的值优先",
+			userConfig: "compose_paths:\n  - /srv/compose\npull_timeout: \"45m\"\nup_timeout: \"90s\"\n",
+			wantPull:   45 * time.Minute,
+			wantUp:     90 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempConfig(t, tt.userConfig)
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.PullTimeout != tt.wantPull {
+				t.Errorf("PullTimeout = %v, want %v", cfg.PullTimeout, tt.wantPull)
+			}
+			if cfg.UpTimeout != tt.wantUp {
+				t.Errorf("UpTimeout = %v, want %v", cfg.UpTimeout, tt.wantUp)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"compman/pkg/types"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormats 支持导出的配置格式
+var ExportFormats = []string{"yaml", "json", "toml", "env"}
+
+// maskedValue 导出时替代敏感配置项的值
+const maskedValue = "******"
+
+// ExportConfig 将当前配置按指定格式 (yaml、json、toml、env) 写入 w，敏感配置项以 ****** 代替
+// env 格式每行一个 COMPMAN_ 环境变量，可直接在 shell 中 source，与 LoadConfigFromEnv 读取的变量一致
+func ExportConfig(format string, w io.Writer) error {
+	return exportCurrentConfig(format, w, true)
+}
+
+// ExportConfigWithSecrets 与 ExportConfig 相同，但保留敏感配置项的原始值
+func ExportConfigWithSecrets(format string, w io.Writer) error {
+	return exportCurrentConfig(format, w, false)
+}
+
+// exportCurrentConfig 加载当前配置并导出
+func exportCurrentConfig(format string, w io.Writer, mask bool) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	return exportConfig(cfg, format, w, mask)
+}
+
+// exportConfig 按格式序列化配置
+func exportConfig(cfg *types.Config, format string, w io.Writer, mask bool) error {
+	exported := *cfg
+	if mask {
+		maskSecrets(&exported)
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case "yaml":
+		data, err = yaml.Marshal(configValues(reflect.ValueOf(exported)))
+	case "json":
+		data, err = json.MarshalIndent(configValues(reflect.ValueOf(exported)), "", "  ")
+		data = append(data, '\n')
+	case "toml":
+		data, err = toml.Marshal(configValues(reflect.ValueOf(exported)))
+	case "env":
+		data = []byte(configEnvLines(&exported))
+	default:
+		return fmt.Errorf("不支持的导出格式: %s (支持: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("写入配置失败: %v", err)
+	}
+	return nil
+}
+
+// maskSecrets 隐藏可能包含凭据的配置项，如 Webhook 地址中的令牌
+func maskSecrets(cfg *types.Config) {
+	if cfg.WebhookURL != "" {
+		cfg.WebhookURL = maskedValue
+	}
+}
+
+// configValues 将配置结构体转换为以 YAML 字段名为键的映射，时长转换为 5m0s 形式的字符串
+func configValues(v reflect.Value) map[string]interface{} {
+	values := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || name == "" || !field.IsExported() {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		switch value := fieldValue.Interface().(type) {
+		case time.Duration:
+			values[name] = value.String()
+		case []string:
+			values[name] = append([]string{}, value...)
+		default:
+			if fieldValue.Kind() == reflect.Struct {
+				values[name] = configValues(fieldValue)
+			} else if fieldValue.Kind() == reflect.Map && fieldValue.IsNil() {
+				values[name] = map[string]interface{}{}
+			} else {
+				values[name] = value
+			}
+		}
+	}
+	return values
+}
+
+// configEnvLines 将配置转换为 COMPMAN_ 环境变量，每行一个，值使用单引号以便在 shell 中 source
+func configEnvLines(cfg *types.Config) string {
+	var b strings.Builder
+	walkConfigFields(reflect.ValueOf(cfg).Elem(), envPrefix, func(name string, field reflect.Value) {
+		fmt.Fprintf(&b, "%s=%s\n", name, shellQuote(formatEnvValue(field)))
+	})
+	return b.String()
+}
+
+// formatEnvValue 按 setFieldFromEnv 能够解析的格式输出字段值
+func formatEnvValue(field reflect.Value) string {
+	switch value := field.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case []string:
+		return strings.Join(value, ",")
+	case map[string]string:
+		pairs := make([]string, 0, len(value))
+		for _, key := range sortedKeys(value) {
+			pairs = append(pairs, key+"="+value[key])
+		}
+		return strings.Join(pairs, ",")
+	case map[string][]string:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+strings.Join(value[key], ","))
+		}
+		return strings.Join(pairs, ";")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// sortedKeys 返回映射的键并排序
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellQuote 使用单引号包裹字符串，值中的单引号按 shell 规则转义
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
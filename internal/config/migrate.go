@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+
+	"compman/internal/ui"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentSchemaVersion 当前的配置文件结构版本，新增需要默认值的配置项时递增并添加对应的迁移函数
+const CurrentSchemaVersion = 4
+
+// migrations 按版本号索引的迁移函数，migrations[n] 将配置从版本 n 迁移到 n+1
+var migrations = map[int]func(v *viper.Viper){
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+	3: migrateV3ToV4,
+}
+
+// MigrateConfig 将全局配置从 oldVersion 依次迁移到 newVersion，为缺失的配置项补充默认值
+func MigrateConfig(oldVersion, newVersion int) error {
+	if err := migrateViper(viper.GetViper(), oldVersion, newVersion); err != nil {
+		return err
+	}
+	viper.Set("schema_version", newVersion)
+	return nil
+}
+
+// migrateViper 对指定的 viper 实例依次执行 oldVersion 到 newVersion 之间的迁移函数
+func migrateViper(v *viper.Viper, oldVersion, newVersion int) error {
+	if oldVersion < 1 {
+		// 没有 schema_version 的配置文件视为第一版
+		oldVersion = 1
+	}
+	if newVersion > CurrentSchemaVersion {
+		return fmt.Errorf("不支持的配置版本 %d (当前最高版本: %d)", newVersion, CurrentSchemaVersion)
+	}
+	if oldVersion > newVersion {
+		return fmt.Errorf("无法将配置从版本 %d 降级到版本 %d", oldVersion, newVersion)
+	}
+
+	for version := oldVersion; version < newVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("缺少配置版本 %d 到 %d 的迁移", version, version+1)
+		}
+		migrate(v)
+	}
+	return nil
+}
+
+// notifyMigrated 提示用户配置文件已自动迁移
+func notifyMigrated(oldVersion int) {
+	if oldVersion < 1 {
+		oldVersion = 1
+	}
+	ui.PrintWarning(fmt.Sprintf("配置文件已从版本 %d 迁移到版本 %d，新增的配置项已使用默认值", oldVersion, CurrentSchemaVersion))
+}
+
+// migrateV1ToV2 补全第一版配置文件之后新增的配置项
+func migrateV1ToV2(v *viper.Viper) {
+	v.SetDefault("semver_pattern", "*")
+	v.SetDefault("registry_api_timeout", "30s")
+	v.SetDefault("webhook_url", "")
+	v.SetDefault("compose_env_file", "")
+	v.SetDefault("clean_volumes", false)
+	v.SetDefault("project_name_override", map[string]string{})
+	v.SetDefault("pinned_services", map[string][]string{})
+	v.SetDefault("restart_policy", "")
+	v.SetDefault("max_parallel", 1)
+	v.SetDefault("compose_profiles", []string{})
+	v.SetDefault("compact_output", false)
+	v.SetDefault("clean_networks", false)
+	v.SetDefault("tag_format", "")
+	v.SetDefault("respect_dependencies", false)
+	v.SetDefault("backup_before_pull", false)
+	v.SetDefault("platform", "")
+	v.SetDefault("skip_up_to_date", true)
+	v.SetDefault("use_direct_pull", false)
+	v.SetDefault("docker_config.ssh_host", "")
+	v.SetDefault("docker_config.ssh_key_path", "")
+}
+
+// migrateV2ToV3 补全第二版配置文件之后新增的配置项
+func migrateV2ToV3(v *viper.Viper) {
+	v.SetDefault("log_file", "")
+	v.SetDefault("compose_version", "auto")
+	v.SetDefault("regex_pattern", "")
+	v.SetDefault("regex_capture_group", 1)
+	v.SetDefault("pull_timeout", "30m")
+	v.SetDefault("up_timeout", "2m")
+}
+
+// migrateV3ToV4 补全 remove_orphans，默认在 up -d 时删除孤立容器
+func migrateV3ToV4(v *viper.Viper) {
+	v.SetDefault("remove_orphans", true)
+}
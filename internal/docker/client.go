@@ -1,9 +1,17 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"compman/internal/ui"
@@ -11,25 +19,67 @@ import (
 
 	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// composeProjectLabel Docker Compose 为容器设置的项目名称标签
+	composeProjectLabel = "com.docker.compose.project"
+
+	// composeServiceLabel Docker Compose 为容器设置的服务名称标签
+	composeServiceLabel = "com.docker.compose.service"
 )
 
 // Client Docker 客户端包装器
 type Client struct {
-	cli    *client.Client
-	ctx    context.Context
-	config *types.DockerConfig
+	cli      *client.Client
+	ctx      context.Context
+	config   *types.DockerConfig
+	platform string // 拉取镜像的目标平台，为空时使用 daemon 默认平台
+
+	composeOnce sync.Once // IsComposeV2Available 只检测一次
+	composeV2   bool
+	composeErr  error
+}
+
+// defaultConfig 由 SetDefaultConfig 设置，NewClient 创建的客户端使用该配置连接
+var defaultConfig *types.DockerConfig
+
+// SetDefaultConfig 设置 NewClient 使用的 Docker 连接配置，为 nil 时使用环境变量
+func SetDefaultConfig(config *types.DockerConfig) {
+	defaultConfig = config
 }
 
 // NewClient 创建新的 Docker 客户端
 func NewClient() *Client {
 	return &Client{
-		ctx: context.Background(),
+		ctx:    context.Background(),
+		config: defaultConfig,
 	}
 }
 
 // NewClientWithConfig 使用配置创建 Docker 客户端
 func NewClientWithConfig(config *types.DockerConfig) (*Client, error) {
+	cli, err := newAPIClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Docker 客户端失败: %v", err)
+	}
+
+	return &Client{
+		cli:    cli,
+		ctx:    context.Background(),
+		config: config,
+	}, nil
+}
+
+// newAPIClient 根据配置创建 Docker API 客户端，配置为 nil 时从环境变量读取
+func newAPIClient(config *types.DockerConfig) (*client.Client, error) {
+	if config == nil {
+		return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	}
+
 	var opts []client.Opt
 
 	// 设置 API 版本
@@ -39,34 +89,32 @@ func NewClientWithConfig(config *types.DockerConfig) (*Client, error) {
 		opts = append(opts, client.WithAPIVersionNegotiation())
 	}
 
-	// 设置主机
-	if config.Host != "" {
+	// 设置主机，SSH 地址优先
+	switch {
+	case config.SSHHost != "":
+		dialer, err := sshDialer(config.SSHHost, config.SSHKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost(sshDummyHost), client.WithDialContext(dialer))
+	case config.Host != "":
 		opts = append(opts, client.WithHost(config.Host))
-	} else {
+	default:
 		opts = append(opts, client.FromEnv)
 	}
 
 	// 设置 TLS
-	if config.TLSVerify && config.CertPath != "" {
+	if config.TLSVerify && config.CertPath != "" && config.SSHHost == "" {
 		opts = append(opts, client.WithTLSClientConfig(config.CertPath, config.CertPath, config.CertPath))
 	}
 
-	cli, err := client.NewClientWithOpts(opts...)
-	if err != nil {
-		return nil, fmt.Errorf("创建 Docker 客户端失败: %v", err)
-	}
-
-	return &Client{
-		cli:    cli,
-		ctx:    context.Background(),
-		config: config,
-	}, nil
+	return client.NewClientWithOpts(opts...)
 }
 
 // Connect 连接到 Docker daemon
 func (c *Client) Connect() error {
 	if c.cli == nil {
-		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		cli, err := newAPIClient(c.config)
 		if err != nil {
 			return fmt.Errorf("连接 Docker daemon 失败: %v", err)
 		}
@@ -147,21 +195,31 @@ func (c *Client) ListUnusedImages() ([]*types.ImageInfo, error) {
 
 // CleanupUnusedImages 清理未使用的镜像
 func (c *Client) CleanupUnusedImages() error {
-	if err := c.ensureConnected(); err != nil {
+	reclaimed, deleted, err := c.PruneUnusedImages()
+	if err != nil {
 		return err
 	}
 
+	ui.PrintSuccess(fmt.Sprintf("清理完成，回收空间: %d 字节", reclaimed))
+	ui.PrintInfo(fmt.Sprintf("删除的镜像数量: %d", deleted))
+
+	return nil
+}
+
+// PruneUnusedImages 清理未使用的镜像但不输出信息，返回回收的空间（字节）和删除的镜像数量
+func (c *Client) PruneUnusedImages() (uint64, int, error) {
+	if err := c.ensureConnected(); err != nil {
+		return 0, 0, err
+	}
+
 	// 执行镜像清理 - 使用正确的API
 	pruneFilters := filters.NewArgs()
 	report, err := c.cli.ImagesPrune(c.ctx, pruneFilters)
 	if err != nil {
-		return fmt.Errorf("清理未使用镜像失败: %v", err)
+		return 0, 0, fmt.Errorf("清理未使用镜像失败: %v", err)
 	}
 
-	ui.PrintSuccess(fmt.Sprintf("清理完成，回收空间: %d 字节", report.SpaceReclaimed))
-	ui.PrintInfo(fmt.Sprintf("删除的镜像数量: %d", len(report.ImagesDeleted)))
-
-	return nil
+	return report.SpaceReclaimed, len(report.ImagesDeleted), nil
 }
 
 // RemoveImage 删除指定镜像
@@ -181,22 +239,79 @@ func (c *Client) RemoveImage(imageID string, force bool) error {
 	return nil
 }
 
+// PullProgress 镜像拉取过程中的进度事件
+type PullProgress struct {
+	Layer   string // 镜像层 ID，镜像级别的事件（如 Pulling from、Digest）为标签或空
+	Status  string // 状态，如 Downloading、Extracting、Pull complete
+	Current int64  // 当前层已处理的字节数
+	Total   int64  // 当前层的总字节数，未知时为 0
+}
+
+// pullEvent Docker API 拉取镜像时返回的 JSON 进度事件
+type pullEvent struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// SetPlatform 设置拉取镜像时使用的平台 (os/arch[/variant])，为空时使用 daemon 默认平台
+func (c *Client) SetPlatform(platform string) {
+	c.platform = platform
+}
+
 // PullImage 拉取镜像
 func (c *Client) PullImage(imageName string) error {
+	return c.PullImageWithProgress(imageName, nil)
+}
+
+// PullImageWithProgress 拉取镜像，并将解析出的进度事件发送到 progressCh
+// progressCh 为 nil 时丢弃进度；函数不会关闭 progressCh，调用方需持续接收直到函数返回
+func (c *Client) PullImageWithProgress(imageName string, progressCh chan<- PullProgress) error {
 	if err := c.ensureConnected(); err != nil {
 		return err
 	}
 
-	reader, err := c.cli.ImagePull(c.ctx, imageName, dockertypes.ImagePullOptions{})
+	reader, err := c.cli.ImagePull(c.ctx, imageName, dockertypes.ImagePullOptions{Platform: c.platform})
 	if err != nil {
 		return fmt.Errorf("拉取镜像 %s 失败: %v", imageName, err)
 	}
 	defer reader.Close()
 
-	// 这里可以添加进度显示逻辑
-	// io.Copy(os.Stdout, reader)
+	// 拉取在读完响应流后才完成，每一行是一个 JSON 事件
+	decoder := json.NewDecoder(reader)
+	for {
+		var event pullEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("读取镜像 %s 的拉取进度失败: %v", imageName, err)
+		}
 
-	return nil
+		if event.Error != "" {
+			message := event.ErrorDetail.Message
+			if message == "" {
+				message = event.Error
+			}
+			return fmt.Errorf("拉取镜像 %s 失败: %s", imageName, message)
+		}
+
+		if progressCh != nil {
+			progressCh <- PullProgress{
+				Layer:   event.ID,
+				Status:  event.Status,
+				Current: event.ProgressDetail.Current,
+				Total:   event.ProgressDetail.Total,
+			}
+		}
+	}
 }
 
 // GetImageInfo 获取镜像详细信息
@@ -236,6 +351,54 @@ func (c *Client) GetImageInfo(imageID string) (*types.ImageInfo, error) {
 	}, nil
 }
 
+// GetImageDigestReference 返回本地镜像的 仓库@摘要 引用，用于在 Compose 文件中精确指定该镜像
+// 本地构建、从未推送或拉取过的镜像没有仓库摘要，返回错误
+func (c *Client) GetImageDigestReference(imageID string) (string, error) {
+	if err := c.ensureConnected(); err != nil {
+		return "", err
+	}
+
+	inspect, _, err := c.cli.ImageInspectWithRaw(c.ctx, imageID)
+	if err != nil {
+		return "", fmt.Errorf("获取镜像 %s 信息失败: %v", imageID, err)
+	}
+	if len(inspect.RepoDigests) == 0 {
+		return "", fmt.Errorf("镜像 %s 没有仓库摘要", imageID)
+	}
+	return inspect.RepoDigests[0], nil
+}
+
+// GetImageLabels 获取本地镜像的标签 (LABEL)，imageID 也可以是镜像名称
+func (c *Client) GetImageLabels(imageID string) (map[string]string, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	inspect, _, err := c.cli.ImageInspectWithRaw(c.ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("获取镜像 %s 信息失败: %v", imageID, err)
+	}
+
+	if inspect.Config == nil || inspect.Config.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return inspect.Config.Labels, nil
+}
+
+// GetSystemInfo 获取 Docker daemon 的系统信息，如数据目录 (DockerRootDir) 和存储驱动
+func (c *Client) GetSystemInfo() (*dockertypes.Info, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	info, err := c.cli.Info(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取 Docker 系统信息失败: %v", err)
+	}
+
+	return &info, nil
+}
+
 // ListContainers 列出容器
 func (c *Client) ListContainers() ([]dockertypes.Container, error) {
 	if err := c.ensureConnected(); err != nil {
@@ -250,6 +413,373 @@ func (c *Client) ListContainers() ([]dockertypes.Container, error) {
 	return containers, nil
 }
 
+// InspectComposeProject 获取 Compose 项目中所有容器的状态，按服务分组
+func (c *Client) InspectComposeProject(projectName string) (*types.ProjectInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	name := NormalizeProjectName(projectName)
+	listFilters := filters.NewArgs(filters.Arg("label", composeProjectLabel+"="+name))
+	containers, err := c.cli.ContainerList(c.ctx, dockertypes.ContainerListOptions{All: true, Filters: listFilters})
+	if err != nil {
+		return nil, fmt.Errorf("获取项目 %s 的容器列表失败: %v", name, err)
+	}
+
+	project := &types.ProjectInfo{Name: name}
+	for _, container := range containers {
+		project.Services = append(project.Services, types.ServiceStatus{
+			ServiceName: container.Labels[composeServiceLabel],
+			ContainerID: container.ID,
+			ImageID:     container.ImageID,
+			State:       container.State,
+			Health:      parseHealthStatus(container.Status),
+		})
+	}
+
+	sort.Slice(project.Services, func(i, j int) bool {
+		if project.Services[i].ServiceName != project.Services[j].ServiceName {
+			return project.Services[i].ServiceName < project.Services[j].ServiceName
+		}
+		return project.Services[i].ContainerID < project.Services[j].ContainerID
+	})
+
+	return project, nil
+}
+
+// GetContainerLogs 获取容器最近 tail 行的标准输出和标准错误日志，tail 不大于 0 时获取全部日志
+func (c *Client) GetContainerLogs(containerID string, tail int) (string, error) {
+	if err := c.ensureConnected(); err != nil {
+		return "", err
+	}
+
+	inspect, err := c.cli.ContainerInspect(c.ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("获取容器 %s 信息失败: %v", containerID, err)
+	}
+
+	options := dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       "all",
+	}
+	if tail > 0 {
+		options.Tail = strconv.Itoa(tail)
+	}
+
+	reader, err := c.cli.ContainerLogs(c.ctx, containerID, options)
+	if err != nil {
+		return "", fmt.Errorf("获取容器 %s 日志失败: %v", containerID, err)
+	}
+	defer reader.Close()
+
+	// 未分配 TTY 的容器日志是多路复用的，需要拆分标准输出和标准错误
+	var output bytes.Buffer
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(&output, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&output, &output, reader)
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取容器 %s 日志失败: %v", containerID, err)
+	}
+
+	return output.String(), nil
+}
+
+// GetContainerEnv 获取容器运行时的环境变量，将 KEY=VALUE 列表解析为映射
+func (c *Client) GetContainerEnv(containerID string) (map[string]string, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	inspect, err := c.cli.ContainerInspect(c.ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("获取容器 %s 信息失败: %v", containerID, err)
+	}
+
+	env := make(map[string]string)
+	if inspect.Config == nil {
+		return env, nil
+	}
+	for _, entry := range inspect.Config.Env {
+		key, value, _ := strings.Cut(entry, "=")
+		env[key] = value
+	}
+	return env, nil
+}
+
+// DiagnoseComposeProject 汇总项目中未运行或健康检查失败的容器的最近 tail 行日志，没有异常容器时返回空字符串
+func (c *Client) DiagnoseComposeProject(projectName string, tail int) string {
+	project, err := c.InspectComposeProject(projectName)
+	if err != nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, service := range project.UnhealthyServices() {
+		status := service.State
+		if service.Health != "" {
+			status += ", " + service.Health
+		}
+		fmt.Fprintf(&builder, "\n--- 服务 %s (%s) 最近日志 ---\n", service.ServiceName, status)
+
+		logs, err := c.GetContainerLogs(service.ContainerID, tail)
+		if err != nil {
+			fmt.Fprintf(&builder, "(无法获取日志: %v)\n", err)
+			continue
+		}
+		logs = strings.TrimRight(logs, "\n")
+		if logs == "" {
+			logs = "(无日志输出)"
+		}
+		builder.WriteString(logs + "\n")
+	}
+
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// NormalizeProjectName 按 Docker Compose 的规则规范化项目名称：转为小写，只保留字母、数字、- 和 _
+func NormalizeProjectName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			builder.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(builder.String(), "-_")
+}
+
+// parseHealthStatus 从容器状态描述（如 "Up 2 minutes (healthy)"）中解析健康检查状态
+func parseHealthStatus(status string) string {
+	switch {
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	default:
+		return ""
+	}
+}
+
+// GetVolumeInfo 获取数据卷详细信息
+func (c *Client) GetVolumeInfo(volumeName string) (*types.VolumeInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	vol, err := c.cli.VolumeInspect(c.ctx, volumeName)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据卷 %s 信息失败: %v", volumeName, err)
+	}
+
+	volumeInfo := &types.VolumeInfo{
+		Name:       vol.Name,
+		Driver:     vol.Driver,
+		Mountpoint: vol.Mountpoint,
+		Size:       -1,
+	}
+	if vol.UsageData != nil {
+		volumeInfo.Size = vol.UsageData.Size
+	}
+
+	// 检查数据卷使用状态
+	if err := c.checkVolumeUsage([]*types.VolumeInfo{volumeInfo}); err != nil {
+		return volumeInfo, fmt.Errorf("检查数据卷使用状态时出现警告: %v", err)
+	}
+
+	return volumeInfo, nil
+}
+
+// ListUnusedVolumes 列出未被任何容器使用的数据卷
+func (c *Client) ListUnusedVolumes() ([]*types.VolumeInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.cli.VolumeList(c.ctx, volume.ListOptions{Filters: unusedVolumeFilters()})
+	if err != nil {
+		return nil, fmt.Errorf("获取数据卷列表失败: %v", err)
+	}
+
+	// 数据卷大小只能通过磁盘使用统计获取，失败时不影响列表结果
+	sizes := c.getVolumeSizes()
+
+	var volumeInfos []*types.VolumeInfo
+	for _, vol := range resp.Volumes {
+		size, ok := sizes[vol.Name]
+		if !ok {
+			size = -1
+		}
+
+		volumeInfos = append(volumeInfos, &types.VolumeInfo{
+			Name:       vol.Name,
+			Driver:     vol.Driver,
+			Mountpoint: vol.Mountpoint,
+			InUse:      false,
+			Size:       size,
+		})
+	}
+
+	return volumeInfos, nil
+}
+
+// unusedVolumeFilters 返回筛选未使用数据卷的条件，干运行预览和实际清理共用，保证两者处理的数据卷一致
+func unusedVolumeFilters() filters.Args {
+	return filters.NewArgs(filters.Arg("dangling", "true"))
+}
+
+// getVolumeSizes 获取数据卷名称到占用空间的映射
+func (c *Client) getVolumeSizes() map[string]int64 {
+	sizes := make(map[string]int64)
+
+	usage, err := c.cli.DiskUsage(c.ctx, dockertypes.DiskUsageOptions{
+		Types: []dockertypes.DiskUsageObject{dockertypes.VolumeObject},
+	})
+	if err != nil {
+		return sizes
+	}
+
+	for _, vol := range usage.Volumes {
+		if vol.UsageData != nil && vol.UsageData.Size >= 0 {
+			sizes[vol.Name] = vol.UsageData.Size
+		}
+	}
+
+	return sizes
+}
+
+// CleanupUnusedVolumes 清理未使用的数据卷
+func (c *Client) CleanupUnusedVolumes() error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	// VolumesPrune 不支持 dangling 条件，且新版 API 默认只清理匿名卷，
+	// 因此按与干运行预览相同的条件列出数据卷后逐个删除
+	volumes, err := c.ListUnusedVolumes()
+	if err != nil {
+		return fmt.Errorf("清理未使用数据卷失败: %v", err)
+	}
+
+	var spaceReclaimed int64
+	deleted := 0
+	for _, vol := range volumes {
+		if err := c.cli.VolumeRemove(c.ctx, vol.Name, false); err != nil {
+			ui.PrintWarning(fmt.Sprintf("删除数据卷 %s 失败: %v", vol.Name, err))
+			continue
+		}
+		deleted++
+		if vol.Size > 0 {
+			spaceReclaimed += vol.Size
+		}
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("数据卷清理完成，回收空间: %d 字节", spaceReclaimed))
+	ui.PrintInfo(fmt.Sprintf("删除的数据卷数量: %d", deleted))
+
+	return nil
+}
+
+// ListUnusedNetworks 列出没有容器连接的自定义网络，不包括 bridge、host、none 等内置网络以及 Swarm 管理的网络
+func (c *Client) ListUnusedNetworks() ([]*types.NetworkInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	networks, err := c.cli.NetworkList(c.ctx, dockertypes.NetworkListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("获取网络列表失败: %v", err)
+	}
+
+	var networkInfos []*types.NetworkInfo
+	for _, network := range networks {
+		if isBuiltinNetwork(network) {
+			continue
+		}
+
+		// 网络列表不包含容器信息，需要逐个查询
+		detail, err := c.cli.NetworkInspect(c.ctx, network.ID, dockertypes.NetworkInspectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("获取网络 %s 信息失败: %v", network.Name, err)
+		}
+		if len(detail.Containers) > 0 {
+			continue
+		}
+
+		networkInfos = append(networkInfos, &types.NetworkInfo{
+			ID:     network.ID,
+			Name:   network.Name,
+			Driver: network.Driver,
+		})
+	}
+
+	sort.Slice(networkInfos, func(i, j int) bool {
+		return networkInfos[i].Name < networkInfos[j].Name
+	})
+
+	return networkInfos, nil
+}
+
+// CleanupUnusedNetworks 清理未使用的网络
+func (c *Client) CleanupUnusedNetworks() error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	report, err := c.cli.NetworksPrune(c.ctx, filters.Args{})
+	if err != nil {
+		return fmt.Errorf("清理未使用网络失败: %v", err)
+	}
+
+	ui.PrintSuccess("网络清理完成")
+	ui.PrintInfo(fmt.Sprintf("删除的网络数量: %d", len(report.NetworksDeleted)))
+
+	return nil
+}
+
+// isBuiltinNetwork 判断是否为 Docker 内置网络或 Swarm 管理的网络 (swarm 作用域网络、ingress 网络和 docker_gwbridge)
+// 这些网络即使没有容器连接也不应被清理
+func isBuiltinNetwork(network dockertypes.NetworkResource) bool {
+	if network.Scope == "swarm" || network.Ingress {
+		return true
+	}
+
+	switch network.Name {
+	case "bridge", "host", "none", "docker_gwbridge":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkVolumeUsage 检查数据卷使用状态
+func (c *Client) checkVolumeUsage(volumes []*types.VolumeInfo) error {
+	containers, err := c.ListContainers()
+	if err != nil {
+		return err
+	}
+
+	// 创建数据卷名称到数据卷信息的映射
+	volumeMap := make(map[string]*types.VolumeInfo)
+	for _, vol := range volumes {
+		volumeMap[vol.Name] = vol
+	}
+
+	// 检查每个容器挂载的数据卷
+	for _, container := range containers {
+		for _, mount := range container.Mounts {
+			if vol, exists := volumeMap[mount.Name]; exists {
+				vol.InUse = true
+			}
+		}
+	}
+
+	return nil
+}
+
 // checkImageUsage 检查镜像使用状态
 func (c *Client) checkImageUsage(images []*types.ImageInfo) error {
 	containers, err := c.ListContainers()
@@ -302,3 +832,29 @@ func (c *Client) GetVersion() (string, error) {
 
 	return version.Version, nil
 }
+
+// IsComposeV2Available 检测 docker compose (v2) 插件是否可用，结果在客户端内缓存
+// 插件不可用时返回 false；未找到 docker 命令等无法检测的情况返回错误
+func (c *Client) IsComposeV2Available() (bool, error) {
+	c.composeOnce.Do(func() {
+		err := exec.Command("docker", "compose", "version", "--format", "json").Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			c.composeV2 = true
+		case errors.As(err, &exitErr):
+			c.composeV2 = false
+		default:
+			c.composeErr = fmt.Errorf("检测 docker compose 插件失败: %v", err)
+		}
+	})
+	return c.composeV2, c.composeErr
+}
+
+// ComposeVersion 返回应使用的 Compose 命令版本：docker compose 插件可用时为 "v2"，否则为 "v1"
+func (c *Client) ComposeVersion() string {
+	if available, _ := c.IsComposeV2Available(); available {
+		return "v2"
+	}
+	return "v1"
+}
@@ -0,0 +1,225 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"compman/pkg/types"
+
+	"github.com/compose-spec/compose-go/dotenv"
+	"github.com/compose-spec/compose-go/loader"
+	composetypes "github.com/compose-spec/compose-go/types"
+)
+
+// ComposeLibraryLoader 使用 compose-spec/compose-go 加载 Compose 文件
+// 与 docker compose 的解析逻辑一致，支持 extends、include、变量插值和 .env 文件
+// 加载结果已经过插值和规范化，只适合读取，不能直接写回文件
+type ComposeLibraryLoader struct {
+	profiles []string // 启用的 profiles，为空时不过滤服务
+}
+
+// NewComposeLibraryLoader 创建一个新的 compose-go 加载器
+func NewComposeLibraryLoader() *ComposeLibraryLoader {
+	return &ComposeLibraryLoader{}
+}
+
+// SetProfiles 设置启用的 Compose profiles，与 Parser.SetProfiles 的行为一致
+func (l *ComposeLibraryLoader) SetProfiles(profiles []string) {
+	l.profiles = profiles
+}
+
+// Load 加载 Compose 文件并转换为 ComposeFile；content 为空时从 filePath 读取
+// 变量插值使用当前环境变量和文件所在目录的 .env，环境变量优先
+func (l *ComposeLibraryLoader) Load(filePath string, content []byte) (*types.ComposeFile, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件绝对路径失败: %v", err)
+	}
+	if content == nil {
+		if content, err = os.ReadFile(absPath); err != nil {
+			return nil, fmt.Errorf("读取文件失败: %v", err)
+		}
+	}
+
+	workingDir := filepath.Dir(absPath)
+	environment, err := composeEnvironment(workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	details := composetypes.ConfigDetails{
+		WorkingDir:  workingDir,
+		ConfigFiles: []composetypes.ConfigFile{{Filename: absPath, Content: content}},
+		Environment: environment,
+	}
+
+	// 未指定 profiles 时启用所有服务，与内置解析器保持一致
+	profiles := l.profiles
+	if len(profiles) == 0 {
+		profiles = []string{"*"}
+	}
+
+	project, err := loader.Load(details, loader.WithProfiles(profiles), func(opts *loader.Options) {
+		opts.SetProjectName(loader.NormalizeProjectName(filepath.Base(workingDir)), false)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("compose-go 加载失败: %v", err)
+	}
+
+	composeFile := convertProject(project)
+	composeFile.FilePath = filePath
+	return composeFile, nil
+}
+
+// composeEnvironment 合并目录下 .env 文件和当前环境变量
+func composeEnvironment(workingDir string) (map[string]string, error) {
+	environment := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			environment[key] = value
+		}
+	}
+
+	dotEnv, err := dotenv.GetEnvFromFile(environment, workingDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("读取 .env 文件失败: %v", err)
+	}
+	for key, value := range dotEnv {
+		if _, exists := environment[key]; !exists {
+			environment[key] = value
+		}
+	}
+
+	return environment, nil
+}
+
+// convertProject 将 compose-go 的项目模型转换为 ComposeFile
+func convertProject(project *composetypes.Project) *types.ComposeFile {
+	composeFile := &types.ComposeFile{
+		Version:     "3.8",
+		Services:    make(map[string]types.Service, len(project.Services)),
+		ProjectName: project.Name,
+	}
+
+	for _, service := range project.Services {
+		composeFile.Services[service.Name] = convertService(service)
+	}
+
+	if len(project.Networks) > 0 {
+		composeFile.Networks = make(map[string]interface{}, len(project.Networks))
+		for name := range project.Networks {
+			composeFile.Networks[name] = nil
+		}
+	}
+	if len(project.Volumes) > 0 {
+		composeFile.Volumes = make(map[string]interface{}, len(project.Volumes))
+		for name := range project.Volumes {
+			composeFile.Volumes[name] = nil
+		}
+	}
+
+	if xLabels, ok := project.Extensions["x-labels"].(map[string]interface{}); ok {
+		composeFile.XLabels = make(map[string]string, len(xLabels))
+		for key, value := range xLabels {
+			composeFile.XLabels[key] = fmt.Sprint(value)
+		}
+	}
+
+	return composeFile
+}
+
+// convertService 将 compose-go 的服务配置转换为 Service，端口使用长格式，数据卷使用短格式
+func convertService(service composetypes.ServiceConfig) types.Service {
+	converted := types.Service{
+		Image:    service.Image,
+		Restart:  service.Restart,
+		Labels:   service.Labels,
+		Profiles: service.Profiles,
+	}
+
+	if service.Build != nil {
+		converted.Build = &types.BuildConfig{
+			Context:    service.Build.Context,
+			Dockerfile: service.Build.Dockerfile,
+			Args:       mappingToStrings(service.Build.Args),
+			Target:     service.Build.Target,
+		}
+	}
+
+	if len(service.Environment) > 0 {
+		converted.Environment = mappingToStrings(service.Environment)
+	}
+
+	for _, port := range service.Ports {
+		entry := map[string]interface{}{"target": int(port.Target)}
+		if port.Published != "" {
+			entry["published"] = port.Published
+		}
+		if port.HostIP != "" {
+			entry["host_ip"] = port.HostIP
+		}
+		if port.Protocol != "" {
+			entry["protocol"] = port.Protocol
+		}
+		converted.Ports = append(converted.Ports, entry)
+	}
+
+	for _, volume := range service.Volumes {
+		converted.Volumes = append(converted.Volumes, formatVolume(volume))
+	}
+
+	if len(service.DependsOn) > 0 {
+		dependsOn := make(map[string]interface{}, len(service.DependsOn))
+		for name, dependency := range service.DependsOn {
+			dependsOn[name] = map[string]interface{}{"condition": dependency.Condition}
+		}
+		converted.DependsOn = dependsOn
+	}
+
+	for name := range service.Networks {
+		converted.Networks = append(converted.Networks, name)
+	}
+	sort.Strings(converted.Networks)
+
+	if len(service.ExtraHosts) > 0 {
+		converted.ExtraHosts = service.ExtraHosts.AsList()
+		sort.Strings(converted.ExtraHosts)
+	}
+
+	if len(service.Command) > 0 {
+		converted.Command = []string(service.Command)
+	}
+
+	return converted
+}
+
+// mappingToStrings 将 KEY=VALUE 映射转换为 map[string]string，未赋值的键对应空字符串
+func mappingToStrings(mapping composetypes.MappingWithEquals) map[string]string {
+	if len(mapping) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(mapping))
+	for key, value := range mapping {
+		if value != nil {
+			result[key] = *value
+		} else {
+			result[key] = ""
+		}
+	}
+	return result
+}
+
+// formatVolume 将数据卷配置转换为 source:target[:ro] 短格式，匿名卷和 tmpfs 只保留容器路径
+func formatVolume(volume composetypes.ServiceVolumeConfig) string {
+	if volume.Source == "" {
+		return volume.Target
+	}
+	entry := volume.Source + ":" + volume.Target
+	if volume.ReadOnly {
+		entry += ":ro"
+	}
+	return entry
+}
@@ -0,0 +1,16 @@
+package docker
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// FreeDiskSpace 返回路径所在文件系统中非特权用户可用的剩余空间 (字节)
+// 路径需要在本机存在，连接远程 Docker daemon 时其数据目录通常无法在本机检查
+func FreeDiskSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("获取 %s 的磁盘空间失败: %v", path, err)
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+)
+
+// ContainerEvent Docker daemon 事件，如容器退出 (container/die) 和镜像拉取 (image/pull)
+type ContainerEvent struct {
+	Type       string            // 事件对象类型，如 container、image
+	Action     string            // 事件动作，如 die、start、pull
+	Actor      string            // 事件对象的 ID，镜像事件为镜像名称
+	Attributes map[string]string // 事件对象的属性，容器事件包含容器标签、名称和退出码
+	Time       time.Time
+}
+
+// ProjectName 返回容器事件所属的 Compose 项目名称，非 Compose 容器返回空字符串
+func (e ContainerEvent) ProjectName() string {
+	return e.Attributes[composeProjectLabel]
+}
+
+// ServiceName 返回容器事件所属的 Compose 服务名称，非 Compose 容器返回空字符串
+func (e ContainerEvent) ServiceName() string {
+	return e.Attributes[composeServiceLabel]
+}
+
+// GetEventStream 订阅 Docker daemon 的事件流，连接断开时关闭返回的通道
+func (c *Client) GetEventStream() (<-chan ContainerEvent, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	messages, errs := c.cli.Events(c.ctx, dockertypes.EventsOptions{})
+	events := make(chan ContainerEvent)
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				events <- ContainerEvent{
+					Type:       string(message.Type),
+					Action:     message.Action,
+					Actor:      message.Actor.ID,
+					Attributes: message.Actor.Attributes,
+					Time:       time.Unix(0, message.TimeNano),
+				}
+			case <-errs:
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -3,11 +3,12 @@ package docker
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"os"
+	"os/exec"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"compman/pkg/types"
@@ -17,30 +18,38 @@ import (
 
 // ImageManager 镜像管理器
 type ImageManager struct {
-	client     *Client
-	httpClient *http.Client
+	client          *Client
+	registry        *RegistryClient
+	manifestDigests map[string]string // GetImageManifestDigest 的缓存 (镜像 -> 清单摘要)
+	digestMutex     sync.Mutex
 }
 
+// DefaultAPITimeout 镜像仓库 API 请求的默认超时时间
+const DefaultAPITimeout = 30 * time.Second
+
 // NewImageManager 创建新的镜像管理器
 func NewImageManager() *ImageManager {
-	return &ImageManager{
-		client: NewClient(),
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+	return NewImageManagerWithClient(NewClient(), DefaultAPITimeout)
 }
 
-// NewImageManagerWithClient 使用指定客户端创建镜像管理器
-func NewImageManagerWithClient(client *Client) *ImageManager {
+// NewImageManagerWithClient 使用指定客户端和 API 超时时间创建镜像管理器，timeout 不大于 0 时使用默认值
+func NewImageManagerWithClient(client *Client, timeout time.Duration) *ImageManager {
+	registry := NewRegistryClient(timeout)
+	// 认证信息为可选项，读取失败时以匿名方式访问镜像仓库
+	_ = registry.LoadDockerConfig("")
+
 	return &ImageManager{
-		client: client,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:          client,
+		registry:        registry,
+		manifestDigests: make(map[string]string),
 	}
 }
 
+// Registry 返回镜像管理器使用的镜像仓库客户端
+func (im *ImageManager) Registry() *RegistryClient {
+	return im.registry
+}
+
 // GetLatestTag 获取镜像的最新标签
 func (im *ImageManager) GetLatestTag(imageName string, strategy string) (string, error) {
 	switch strategy {
@@ -93,95 +102,207 @@ func (im *ImageManager) getLatestSemverTag(imageName string) (string, error) {
 
 // GetImageTags 从 Docker Hub 或其他镜像仓库获取标签列表
 func (im *ImageManager) GetImageTags(imageName string) ([]string, error) {
-	// 解析镜像名称
+	tags, err := im.FetchAllTags(imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果没有找到标签，返回默认的 latest 标签
+	if len(tags) == 0 {
+		tags = append(tags, "latest")
+	}
+
+	return tags, nil
+}
+
+// FetchAllTags 获取镜像的全部标签，按镜像仓库选择获取方式并跟随分页直到最后一页
+// Docker Hub 使用 Hub API，GHCR 和其他镜像仓库使用 OCI Distribution API 的 Link 分页
+func (im *ImageManager) FetchAllTags(imageName string) ([]string, error) {
 	registry, repository := im.parseImageName(imageName)
 
-	switch registry {
-	case "docker.io", "":
-		return im.getDockerHubTags(repository)
+	var tags []string
+	var err error
+	switch normalizeRegistry(registry) {
+	case "docker.io":
+		tags, err = im.registry.fetchAllDockerHubTags(repository)
 	default:
-		return im.getRegistryTags(registry, repository)
+		tags, err = im.registry.fetchAllOCITags(registry, repository)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s/%s 的标签失败: %v", registry, repository, err)
+	}
+
+	return tags, nil
 }
 
-// parseImageName 解析镜像名称
-func (im *ImageManager) parseImageName(imageName string) (registry, repository string) {
-	// 先移除标签部分（如果有的话）
-	if strings.Contains(imageName, ":") {
-		imageName = strings.Split(imageName, ":")[0]
+// ListTagsMatchingPattern 获取镜像的全部标签并返回匹配正则表达式的标签
+// 在所有分页获取完成后再过滤，避免较早的分页中缺少匹配项时遗漏结果；pattern 为空时返回全部标签
+func (im *ImageManager) ListTagsMatchingPattern(imageName, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的标签过滤模式 %q: %v", pattern, err)
 	}
 
-	parts := strings.Split(imageName, "/")
+	tags, err := im.GetImageTags(imageName)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(parts) == 1 {
-		// 官方镜像，如 nginx
-		return "docker.io", "library/" + parts[0]
-	} else if len(parts) == 2 && !strings.Contains(parts[0], ".") {
-		// 用户镜像，如 user/repo (但第一部分不是域名)
-		return "docker.io", parts[0] + "/" + parts[1]
-	} else {
-		// 自定义镜像仓库，如 registry.com/user/repo
-		return parts[0], strings.Join(parts[1:], "/")
+	var matched []string
+	for _, tag := range tags {
+		if re.MatchString(tag) {
+			matched = append(matched, tag)
+		}
+	}
+
+	return matched, nil
+}
+
+// SetPlatform 设置查询镜像下载大小时使用的目标平台，格式为 os/arch[/variant]
+func (im *ImageManager) SetPlatform(platform string) error {
+	return im.registry.SetPlatform(platform)
+}
+
+// GetImageSize 查询镜像仓库，返回拉取镜像时需要下载的压缩大小（字节）
+func (im *ImageManager) GetImageSize(imageName string) (int64, error) {
+	registry, repository := im.parseImageName(imageName)
+	tag := im.extractTag(imageName)
+
+	size, err := im.registry.GetImageSize(registry, repository, tag)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 的下载大小失败: %v", imageName, err)
 	}
+
+	return size, nil
 }
 
-// getDockerHubTags 从 Docker Hub 获取标签
-func (im *ImageManager) getDockerHubTags(repository string) ([]string, error) {
-	url := fmt.Sprintf("https://registry.hub.docker.com/v2/repositories/%s/tags/?page_size=100", repository)
+// GetImageCreated 查询镜像仓库，返回镜像标签对应镜像的构建时间
+func (im *ImageManager) GetImageCreated(imageName string) (time.Time, error) {
+	registry, repository := im.parseImageName(imageName)
+	tag := im.extractTag(imageName)
+
+	created, err := im.registry.GetImageCreated(registry, repository, tag)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("获取 %s 的构建时间失败: %v", imageName, err)
+	}
+
+	return created, nil
+}
 
-	resp, err := im.httpClient.Get(url)
+// GetRateLimitStatus 查询 Docker Hub 的镜像拉取速率限制
+func (im *ImageManager) GetRateLimitStatus() (*RateLimitStatus, error) {
+	status, err := im.registry.GetRateLimitStatus()
 	if err != nil {
-		return nil, fmt.Errorf("请求 Docker Hub API 失败: %v", err)
+		return nil, fmt.Errorf("查询 Docker Hub 拉取速率限制失败: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		// 读取错误响应体以获取更详细的错误信息
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Docker Hub API 响应错误: %d - %s\nURL: %s\nRepository: %s",
-			resp.StatusCode, string(body), url, repository)
+	return status, nil
+}
+
+// manifestInspectEntry docker manifest inspect -v 输出中的单个清单
+type manifestInspectEntry struct {
+	Ref        string     `json:"Ref"`
+	Descriptor Descriptor `json:"Descriptor"`
+}
+
+// GetImageManifestDigest 通过 docker manifest inspect 获取镜像在仓库中的清单摘要
+// 使用 Docker CLI 的认证信息访问镜像仓库；多平台镜像返回目标平台的清单摘要。结果在本次运行中缓存
+func (im *ImageManager) GetImageManifestDigest(imageName string) (string, error) {
+	im.digestMutex.Lock()
+	digest, cached := im.manifestDigests[imageName]
+	im.digestMutex.Unlock()
+	if cached {
+		return digest, nil
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	cmd := exec.Command("docker", "manifest", "inspect", "-v", imageName)
+	// 旧版本 Docker CLI 中 manifest 为实验性命令
+	cmd.Env = append(os.Environ(), "DOCKER_CLI_EXPERIMENTAL=enabled")
+	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("获取 %s 的清单失败: %s", imageName, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("获取 %s 的清单失败: %v", imageName, err)
 	}
 
-	var response DockerHubTagsResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v\n响应内容: %s", err, string(body))
+	digest, err = parseManifestInspectDigest(output, im.registry.platform)
+	if err != nil {
+		return "", fmt.Errorf("解析 %s 的清单失败: %v", imageName, err)
 	}
 
-	var tags []string
-	for _, result := range response.Results {
-		tags = append(tags, result.Name)
+	im.digestMutex.Lock()
+	im.manifestDigests[imageName] = digest
+	im.digestMutex.Unlock()
+	return digest, nil
+}
+
+// parseManifestInspectDigest 从 docker manifest inspect -v 的输出中取出清单摘要
+// 单平台镜像输出一个对象，多平台镜像输出每个平台的清单数组
+func parseManifestInspectDigest(output []byte, target *Platform) (string, error) {
+	trimmed := strings.TrimSpace(string(output))
+
+	if !strings.HasPrefix(trimmed, "[") {
+		var entry manifestInspectEntry
+		if err := json.Unmarshal([]byte(trimmed), &entry); err != nil {
+			return "", err
+		}
+		if entry.Descriptor.Digest == "" {
+			return "", fmt.Errorf("清单中没有摘要")
+		}
+		return entry.Descriptor.Digest, nil
 	}
 
-	// 如果没有找到标签，返回默认的 latest 标签
-	if len(tags) == 0 {
-		tags = append(tags, "latest")
+	var entries []manifestInspectEntry
+	if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+		return "", err
+	}
+	descriptors := make([]Descriptor, 0, len(entries))
+	for _, entry := range entries {
+		descriptors = append(descriptors, entry.Descriptor)
 	}
 
-	return tags, nil
+	descriptor := selectPlatformManifest(descriptors, target)
+	if descriptor == nil {
+		if target != nil {
+			return "", fmt.Errorf("镜像索引中没有 %s 平台的清单", target)
+		}
+		return "", fmt.Errorf("镜像索引中没有当前平台的清单")
+	}
+	return descriptor.Digest, nil
 }
 
-// getRegistryTags 从自定义镜像仓库获取标签
-func (im *ImageManager) getRegistryTags(registry, repository string) ([]string, error) {
-	// 实现自定义镜像仓库的标签获取逻辑
-	// 这里返回一个基本的实现
-	return []string{"latest"}, nil
+// parseImageName 解析镜像名称
+func (im *ImageManager) parseImageName(imageName string) (registry, repository string) {
+	return ParseImageName(imageName)
 }
 
-// DockerHubTagsResponse Docker Hub API 响应结构
-type DockerHubTagsResponse struct {
-	Results []struct {
-		Name   string `json:"name"`
-		Images []struct {
-			Architecture string `json:"architecture"`
-			Features     string `json:"features"`
-		} `json:"images"`
-	} `json:"results"`
-	Next string `json:"next"`
+// ParseImageName 将镜像引用拆分为镜像仓库地址和仓库名，忽略标签和摘要
+// 如 nginx:1.25 返回 docker.io 和 library/nginx，ghcr.io/user/app 返回 ghcr.io 和 user/app
+func ParseImageName(imageName string) (registry, repository string) {
+	// 先移除摘要和标签部分（仓库地址中的端口不是标签）
+	imageName, _, _ = strings.Cut(imageName, "@")
+	if colon := strings.LastIndex(imageName, ":"); colon > strings.LastIndex(imageName, "/") {
+		imageName = imageName[:colon]
+	}
+
+	parts := strings.Split(imageName, "/")
+
+	if len(parts) == 1 {
+		// 官方镜像，如 nginx
+		return "docker.io", "library/" + parts[0]
+	} else if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		// 用户镜像，如 user/repo (但第一部分不是域名)
+		return "docker.io", strings.Join(parts, "/")
+	} else {
+		// 自定义镜像仓库，如 registry.com/user/repo
+		registry, repository = normalizeRegistry(parts[0]), strings.Join(parts[1:], "/")
+		if registry == "docker.io" && len(parts) == 2 {
+			// docker.io/nginx 与 nginx 相同
+			repository = "library/" + repository
+		}
+		return registry, repository
+	}
 }
 
 // cleanVersionTag 清理版本标签
@@ -0,0 +1,769 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dockerHubRegistryHost Docker Hub 的 Registry API 地址
+	dockerHubRegistryHost = "registry-1.docker.io"
+
+	// registryMaxRetries 请求失败（网络错误、429 或 5xx）时的最大重试次数
+	registryMaxRetries = 2
+
+	// dockerHubAPIHost Docker Hub 的 Hub API 地址，用于分页获取标签列表
+	dockerHubAPIHost = "hub.docker.com"
+
+	// dockerHubTagPageSize Hub API 单页返回的最大标签数
+	dockerHubTagPageSize = 100
+
+	// rateLimitPreviewRepo Docker Hub 用于查询拉取速率限制的镜像，对其发送 HEAD 请求不计入拉取次数
+	rateLimitPreviewRepo = "ratelimitpreview/test"
+)
+
+// manifestAcceptTypes 请求清单时接受的媒体类型
+var manifestAcceptTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Credential 镜像仓库认证信息
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Descriptor OCI 内容描述符
+type Descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+// Platform 镜像平台信息
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Manifest 镜像清单，兼容单平台清单和多平台索引
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers,omitempty"`
+	Manifests     []Descriptor `json:"manifests,omitempty"`
+	Digest        string       `json:"-"` // 来自 Docker-Content-Digest 响应头
+}
+
+// IsIndex 判断清单是否为多平台索引
+func (m *Manifest) IsIndex() bool {
+	return len(m.Manifests) > 0
+}
+
+// ImageConfig 镜像配置
+type ImageConfig struct {
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+	Created      time.Time `json:"created"`
+	Config       struct {
+		Env    []string          `json:"Env"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// tagListResponse 标签列表响应
+type tagListResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// hubTagListResponse Docker Hub 标签列表响应，next 为下一页的完整地址，最后一页为空
+type hubTagListResponse struct {
+	Next    string `json:"next"`
+	Results []struct {
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+// RegistryClient 实现 OCI Distribution Spec v2 的镜像仓库客户端
+// 所有镜像仓库 HTTP 请求都经由该客户端，共享认证令牌缓存和重试策略
+type RegistryClient struct {
+	httpClient  *http.Client
+	credentials map[string]Credential
+	tokens      map[string]string // scope -> bearer token
+	platform    *Platform         // 选择多平台清单时优先使用的平台，为 nil 时使用当前系统平台
+	hubAPIHost  string            // Docker Hub 的 Hub API 地址，默认为 dockerHubAPIHost
+	hubHost     string            // Docker Hub 的 Registry API 地址，默认为 dockerHubRegistryHost
+	mutex       sync.RWMutex
+}
+
+// NewRegistryClient 创建镜像仓库客户端，timeout 不大于 0 时使用默认值
+func NewRegistryClient(timeout time.Duration) *RegistryClient {
+	if timeout <= 0 {
+		timeout = DefaultAPITimeout
+	}
+
+	return &RegistryClient{
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		credentials: make(map[string]Credential),
+		tokens:      make(map[string]string),
+		hubAPIHost:  dockerHubAPIHost,
+		hubHost:     dockerHubRegistryHost,
+	}
+}
+
+// SetCredential 设置指定镜像仓库的认证信息
+func (rc *RegistryClient) SetCredential(registry string, credential Credential) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.credentials[normalizeRegistry(registry)] = credential
+}
+
+// LoadDockerConfig 从 Docker 配置文件 (~/.docker/config.json) 加载镜像仓库认证信息
+// path 为空时使用 DOCKER_CONFIG 环境变量或默认路径
+func (rc *RegistryClient) LoadDockerConfig(path string) error {
+	if path == "" {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("获取用户目录失败: %v", err)
+			}
+			dir = filepath.Join(home, ".docker")
+		}
+		path = filepath.Join(dir, "config.json")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取 Docker 配置文件失败: %v", err)
+	}
+
+	var dockerConfig struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(content, &dockerConfig); err != nil {
+		return fmt.Errorf("解析 Docker 配置文件失败: %v", err)
+	}
+
+	for registry, entry := range dockerConfig.Auths {
+		if entry.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			continue
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		rc.SetCredential(registry, Credential{Username: parts[0], Password: parts[1]})
+	}
+
+	return nil
+}
+
+// fetchAllOCITags 通过 OCI Distribution API 获取仓库的全部标签，跟随 Link 响应头分页直到最后一页
+func (rc *RegistryClient) fetchAllOCITags(registry, repo string) ([]string, error) {
+	host := rc.registryHost(registry)
+	next := fmt.Sprintf("https://%s/v2/%s/tags/list?n=1000", host, repo)
+
+	var tags []string
+	visited := make(map[string]bool)
+	for next != "" && !visited[next] {
+		visited[next] = true
+
+		resp, err := rc.do(registry, repo, next, "application/json")
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取响应失败: %v", err)
+		}
+
+		var response tagListResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("解析标签列表失败: %v", err)
+		}
+		tags = append(tags, response.Tags...)
+
+		next = nextPageURL(resp, host)
+	}
+
+	return tags, nil
+}
+
+// fetchAllDockerHubTags 通过 Hub API 获取 Docker Hub 仓库的全部标签，跟随响应中的 next 分页直到最后一页
+// Hub API 只能匿名访问公开仓库，请求失败时 (如私有仓库) 回退到 OCI Distribution API
+func (rc *RegistryClient) fetchAllDockerHubTags(repo string) ([]string, error) {
+	next := fmt.Sprintf("https://%s/v2/repositories/%s/tags?page_size=%d", rc.hubAPIHost, repo, dockerHubTagPageSize)
+
+	var tags []string
+	visited := make(map[string]bool)
+	for next != "" && !visited[next] {
+		visited[next] = true
+
+		response, err := rc.getHubTagPage(next)
+		if err != nil {
+			return rc.fetchAllOCITags("docker.io", repo)
+		}
+		for _, result := range response.Results {
+			tags = append(tags, result.Name)
+		}
+
+		next = response.Next
+	}
+
+	return tags, nil
+}
+
+// getHubTagPage 获取 Hub API 的一页标签列表
+func (rc *RegistryClient) getHubTagPage(requestURL string) (*hubTagListResponse, error) {
+	resp, err := rc.httpClient.Get(requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Docker Hub 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Docker Hub 响应错误: %d\nURL: %s", resp.StatusCode, requestURL)
+	}
+
+	var response hubTagListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("解析标签列表失败: %v", err)
+	}
+	return &response, nil
+}
+
+// GetManifest 获取指定标签或摘要的镜像清单
+func (rc *RegistryClient) GetManifest(registry, repo, reference string) (*Manifest, error) {
+	return rc.getManifest(registry, repo, reference, manifestAcceptTypes)
+}
+
+// getManifest 以指定的媒体类型请求镜像清单
+func (rc *RegistryClient) getManifest(registry, repo, reference string, acceptTypes []string) (*Manifest, error) {
+	requestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", rc.registryHost(registry), repo, reference)
+
+	resp, err := rc.do(registry, repo, requestURL, strings.Join(acceptTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("解析镜像清单失败: %v", err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = resp.Header.Get("Content-Type")
+	}
+	manifest.Digest = resp.Header.Get("Docker-Content-Digest")
+
+	return &manifest, nil
+}
+
+// SetPlatform 设置查询多平台镜像时使用的目标平台，格式为 os/arch[/variant]，为空时使用当前系统平台
+func (rc *RegistryClient) SetPlatform(platform string) error {
+	if platform == "" {
+		rc.platform = nil
+		return nil
+	}
+
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("无效的平台 %q (格式: os/arch[/variant])", platform)
+	}
+
+	rc.platform = &Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		rc.platform.Variant = parts[2]
+	}
+	return nil
+}
+
+// GetImageSize 获取单平台镜像清单中所有层的压缩大小之和，即拉取镜像时需要下载的大小
+// 镜像仓库返回多平台索引时，选择与目标平台匹配的清单（未设置目标平台时使用当前系统架构，不存在时使用 linux/amd64）
+func (rc *RegistryClient) GetImageSize(registry, repo, reference string) (int64, error) {
+	manifest, err := rc.getPlatformManifest(registry, repo, reference)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	return size, nil
+}
+
+// GetImageCreated 获取镜像的构建时间，即单平台清单引用的镜像配置中的 created 字段
+// 多平台索引按 GetImageSize 相同的规则选择平台
+func (rc *RegistryClient) GetImageCreated(registry, repo, reference string) (time.Time, error) {
+	manifest, err := rc.getPlatformManifest(registry, repo, reference)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if manifest.Config.Digest == "" {
+		return time.Time{}, fmt.Errorf("镜像清单中没有配置摘要")
+	}
+
+	config, err := rc.GetConfig(registry, repo, manifest.Config.Digest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if config.Created.IsZero() {
+		return time.Time{}, fmt.Errorf("镜像配置中没有构建时间")
+	}
+
+	return config.Created, nil
+}
+
+// getPlatformManifest 获取单平台镜像清单，镜像仓库返回多平台索引时选择与目标平台匹配的清单
+func (rc *RegistryClient) getPlatformManifest(registry, repo, reference string) (*Manifest, error) {
+	manifest, err := rc.getManifest(registry, repo, reference, []string{
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.IsIndex() {
+		descriptor := selectPlatformManifest(manifest.Manifests, rc.platform)
+		if descriptor == nil {
+			if rc.platform != nil {
+				return nil, fmt.Errorf("镜像索引中没有 %s 平台的清单", rc.platform)
+			}
+			return nil, fmt.Errorf("镜像索引中没有可用的平台清单")
+		}
+		manifest, err = rc.GetManifest(registry, repo, descriptor.Digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// selectPlatformManifest 从多平台索引中选择清单
+// 指定 target 时只匹配该平台（设置了 variant 时同时匹配 variant），否则选择与当前系统匹配的清单
+func selectPlatformManifest(manifests []Descriptor, target *Platform) *Descriptor {
+	candidates := []Platform{
+		{OS: runtime.GOOS, Architecture: runtime.GOARCH},
+		{OS: "linux", Architecture: "amd64"},
+	}
+	if target != nil {
+		candidates = []Platform{*target}
+	}
+
+	for _, platform := range candidates {
+		for i := range manifests {
+			p := manifests[i].Platform
+			if p == nil || p.OS != platform.OS || p.Architecture != platform.Architecture {
+				continue
+			}
+			if platform.Variant != "" && p.Variant != platform.Variant {
+				continue
+			}
+			return &manifests[i]
+		}
+	}
+	return nil
+}
+
+// String 返回 os/arch[/variant] 格式的平台名称
+func (p *Platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Architecture + "/" + p.Variant
+	}
+	return p.OS + "/" + p.Architecture
+}
+
+// GetConfig 获取镜像配置，digest 为清单中 config 描述符的摘要
+func (rc *RegistryClient) GetConfig(registry, repo, digest string) (*ImageConfig, error) {
+	requestURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", rc.registryHost(registry), repo, digest)
+
+	resp, err := rc.do(registry, repo, requestURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var config ImageConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, fmt.Errorf("解析镜像配置失败: %v", err)
+	}
+
+	return &config, nil
+}
+
+// RateLimitStatus Docker Hub 的镜像拉取速率限制
+// Limit 为 0 表示镜像仓库未返回限制信息（如付费账户不受限制）
+type RateLimitStatus struct {
+	Limit     int           // 时间窗口内允许的拉取次数
+	Remaining int           // 时间窗口内剩余的拉取次数
+	Window    time.Duration // 限制的时间窗口，如 6 小时
+	Reset     time.Duration // 距离限制重置的时间，镜像仓库未返回时为 0
+	Source    string        // 计数来源，匿名访问时为 IP 地址
+}
+
+// Unlimited 返回是否不受拉取速率限制
+func (s *RateLimitStatus) Unlimited() bool {
+	return s.Limit == 0
+}
+
+// GetRateLimitStatus 查询 Docker Hub 的拉取速率限制，使用 Docker Hub 的认证信息（未登录时匿名查询）
+func (rc *RegistryClient) GetRateLimitStatus() (*RateLimitStatus, error) {
+	requestURL := fmt.Sprintf("https://%s/v2/%s/manifests/latest", rc.hubHost, rateLimitPreviewRepo)
+
+	resp, err := rc.doMethod(http.MethodHead, "docker.io", rateLimitPreviewRepo, requestURL, strings.Join(manifestAcceptTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return parseRateLimitHeaders(resp.Header)
+}
+
+// parseRateLimitHeaders 解析 RateLimit-Limit、RateLimit-Remaining 和 RateLimit-Reset 响应头
+// 限制值的格式为 "100;w=21600"，w 为以秒为单位的时间窗口
+func parseRateLimitHeaders(header http.Header) (*RateLimitStatus, error) {
+	status := &RateLimitStatus{}
+
+	limitHeader := header.Get("RateLimit-Limit")
+	if limitHeader == "" {
+		return status, nil
+	}
+
+	limit, window, err := parseRateLimitValue(limitHeader)
+	if err != nil {
+		return nil, fmt.Errorf("无法解析 RateLimit-Limit: %v", err)
+	}
+	status.Limit = limit
+	status.Window = window
+
+	remaining, _, err := parseRateLimitValue(header.Get("RateLimit-Remaining"))
+	if err != nil {
+		return nil, fmt.Errorf("无法解析 RateLimit-Remaining: %v", err)
+	}
+	status.Remaining = remaining
+
+	if reset := header.Get("RateLimit-Reset"); reset != "" {
+		seconds, _, err := parseRateLimitValue(reset)
+		if err != nil {
+			return nil, fmt.Errorf("无法解析 RateLimit-Reset: %v", err)
+		}
+		status.Reset = time.Duration(seconds) * time.Second
+	}
+
+	status.Source = header.Get("Docker-RateLimit-Source")
+	return status, nil
+}
+
+// parseRateLimitValue 解析 "100;w=21600" 格式的值，返回数值和时间窗口（未指定时为 0）
+func parseRateLimitValue(value string) (int, time.Duration, error) {
+	parts := strings.Split(value, ";")
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("无效的值 %q", value)
+	}
+
+	var window time.Duration
+	for _, param := range parts[1:] {
+		key, val, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || key != "w" {
+			continue
+		}
+		seconds, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, 0, fmt.Errorf("无效的时间窗口 %q", value)
+		}
+		window = time.Duration(seconds) * time.Second
+	}
+
+	return count, window, nil
+}
+
+// do 发送 GET 请求，处理认证质询并按重试策略重试，返回状态码为 200 的响应
+func (rc *RegistryClient) do(registry, repo, requestURL, accept string) (*http.Response, error) {
+	return rc.doMethod(http.MethodGet, registry, repo, requestURL, accept)
+}
+
+// doMethod 与 do 相同，但使用指定的 HTTP 方法发送请求
+func (rc *RegistryClient) doMethod(method, registry, repo, requestURL, accept string) (*http.Response, error) {
+	scope := fmt.Sprintf("repository:%s:pull", repo)
+	authenticated := false
+
+	var lastErr error
+	for attempt := 0; attempt <= registryMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+
+		req, err := http.NewRequest(method, requestURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %v", err)
+		}
+		req.Header.Set("Accept", accept)
+		rc.authorize(req, registry, scope)
+
+		resp, err := rc.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("请求镜像仓库失败: %v", err)
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && !authenticated:
+			// 根据质询获取令牌后立即重试，不计入重试次数
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := rc.authenticate(registry, scope, challenge); err != nil {
+				return nil, err
+			}
+			authenticated = true
+			attempt--
+			continue
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("镜像仓库响应错误: %d - %s\nURL: %s", resp.StatusCode, strings.TrimSpace(string(body)), requestURL)
+			continue
+		default:
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("镜像仓库响应错误: %d - %s\nURL: %s", resp.StatusCode, strings.TrimSpace(string(body)), requestURL)
+		}
+	}
+
+	return nil, lastErr
+}
+
+// authorize 为请求添加缓存的令牌或基本认证信息
+func (rc *RegistryClient) authorize(req *http.Request, registry, scope string) {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+
+	if token, ok := rc.tokens[normalizeRegistry(registry)+"|"+scope]; ok {
+		if strings.HasPrefix(token, "Basic ") {
+			req.Header.Set("Authorization", token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// authenticate 根据 WWW-Authenticate 质询获取访问令牌并缓存
+func (rc *RegistryClient) authenticate(registry, scope, challenge string) error {
+	scheme, params := parseAuthChallenge(challenge)
+
+	rc.mutex.RLock()
+	credential, hasCredential := rc.credentials[normalizeRegistry(registry)]
+	rc.mutex.RUnlock()
+
+	cacheKey := normalizeRegistry(registry) + "|" + scope
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCredential {
+			return fmt.Errorf("镜像仓库 %s 需要认证，但未找到认证信息", registry)
+		}
+		auth := base64.StdEncoding.EncodeToString([]byte(credential.Username + ":" + credential.Password))
+		rc.mutex.Lock()
+		rc.tokens[cacheKey] = "Basic " + auth
+		rc.mutex.Unlock()
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("不支持的镜像仓库认证方式: %q", challenge)
+	}
+
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("镜像仓库认证质询缺少 realm: %q", challenge)
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return fmt.Errorf("无效的认证地址 %s: %v", realm, err)
+	}
+	query := tokenURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if challengeScope := params["scope"]; challengeScope != "" {
+		query.Set("scope", challengeScope)
+	} else {
+		query.Set("scope", scope)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("创建认证请求失败: %v", err)
+	}
+	if hasCredential {
+		req.SetBasicAuth(credential.Username, credential.Password)
+	}
+
+	resp, err := rc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("获取镜像仓库令牌失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("获取镜像仓库令牌失败: %d - %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return fmt.Errorf("解析镜像仓库令牌失败: %v", err)
+	}
+
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("镜像仓库未返回访问令牌")
+	}
+
+	rc.mutex.Lock()
+	rc.tokens[cacheKey] = token
+	rc.mutex.Unlock()
+
+	return nil
+}
+
+// parseAuthChallenge 解析 WWW-Authenticate 响应头，如 Bearer realm="...",service="...",scope="..."
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+
+	challenge = strings.TrimSpace(challenge)
+	scheme, rest, _ := strings.Cut(challenge, " ")
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, "\"") {
+			end := strings.Index(value[1:], "\"")
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			v, remaining, _ := strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(v)
+			rest = remaining
+		}
+	}
+
+	return scheme, params
+}
+
+// nextPageURL 从 Link 响应头中解析下一页地址
+// Link 可能包含多个以逗号分隔的链接 (如 prev 和 next)，只使用 rel 为 next 的链接
+func nextPageURL(resp *http.Response, host string) string {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			start := strings.Index(link, "<")
+			end := strings.Index(link, ">")
+			if start < 0 || end <= start || !isNextLink(link[end+1:]) {
+				continue
+			}
+
+			next := link[start+1 : end]
+			if strings.HasPrefix(next, "/") {
+				next = "https://" + host + next
+			}
+			return next
+		}
+	}
+	return ""
+}
+
+// isNextLink 检查 Link 中链接地址之后的参数 (如 ; rel="next") 是否包含 rel=next
+func isNextLink(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+			if strings.EqualFold(rel, "next") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registryHost 返回镜像仓库 API 的主机地址
+func (rc *RegistryClient) registryHost(registry string) string {
+	switch normalizeRegistry(registry) {
+	case "docker.io":
+		return rc.hubHost
+	default:
+		return registry
+	}
+}
+
+// NormalizeRegistry 规范化镜像仓库地址，Docker Hub 的各种写法统一为 docker.io
+func NormalizeRegistry(registry string) string {
+	return normalizeRegistry(registry)
+}
+
+// normalizeRegistry 规范化镜像仓库名称，Docker Hub 的各种写法统一为 docker.io
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry = strings.TrimSuffix(strings.Split(registry, "/")[0], "/")
+
+	switch registry {
+	case "", "docker.io", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	default:
+		return registry
+	}
+}
@@ -0,0 +1,251 @@
+package docker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestImageManager 创建请求指向测试服务器的镜像管理器，Docker Hub 的 Hub API 和 Registry API 都使用该服务器
+func newTestImageManager(server *httptest.Server) *ImageManager {
+	registry := NewRegistryClient(0)
+	registry.httpClient = server.Client()
+	host := strings.TrimPrefix(server.URL, "https://")
+	registry.hubAPIHost = host
+	registry.hubHost = host
+	return &ImageManager{registry: registry, manifestDigests: make(map[string]string)}
+}
+
+func TestFetchAllTagsDockerHubPagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/repositories/library/nginx/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"next": "%s/v2/repositories/library/nginx/tags?page=2", "results": [{"name": "1.25"}, {"name": "1.24"}]}`, server.URL)
+		case "2":
+			fmt.Fprintf(w, `{"next": "%s/v2/repositories/library/nginx/tags?page=3", "results": [{"name": "1.23"}]}`, server.URL)
+		case "3":
+			fmt.Fprint(w, `{"next": "", "results": [{"name": "latest"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tags, err := newTestImageManager(server).FetchAllTags("nginx:1.25")
+	if err != nil {
+		t.Fatalf("FetchAllTags() error = %v", err)
+	}
+	want := []string{"1.25", "1.24", "1.23", "latest"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("FetchAllTags() = %v, want %v", tags, want)
+	}
+}
+
+func TestFetchAllTagsOCILinkPagination(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(serverURL string) string
+	}{
+		{
+			name: "相对地址",
+			link: func(string) string { return `</v2/team/app/tags/list?n=2&last=b>; rel="next"` },
+		},
+		{
+			name: "绝对地址",
+			link: func(serverURL string) string {
+				return fmt.Sprintf(`<%s/v2/team/app/tags/list?n=2&last=b>; rel="next"`, serverURL)
+			},
+		},
+		{
+			name: "同时包含上一页和下一页",
+			link: func(string) string {
+				return `</v2/team/app/tags/list?n=2&last=0>; rel="prev", </v2/team/app/tags/list?n=2&last=b>; rel="next"`
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/team/app/tags/list" {
+					http.NotFound(w, r)
+					return
+				}
+				switch r.URL.Query().Get("last") {
+				case "":
+					w.Header().Set("Link", tt.link(server.URL))
+					fmt.Fprint(w, `{"name": "team/app", "tags": ["a", "b"]}`)
+				case "b":
+					fmt.Fprint(w, `{"name": "team/app", "tags": ["c"]}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			image := strings.TrimPrefix(server.URL, "https://") + "/team/app:a"
+			tags, err := newTestImageManager(server).FetchAllTags(image)
+			if err != nil {
+				t.Fatalf("FetchAllTags() error = %v", err)
+			}
+			want := []string{"a", "b", "c"}
+			if !reflect.DeepEqual(tags, want) {
+				t.Errorf("FetchAllTags() = %v, want %v", tags, want)
+			}
+		})
+	}
+}
+
+func TestFetchAllTagsTermination(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(serverURL string, requests *int) http.HandlerFunc
+		image   func(host string) string
+		want    []string
+	}{
+		{
+			name: "Docker Hub next 指向已访问的分页",
+			handler: func(serverURL string, requests *int) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					*requests++
+					fmt.Fprintf(w, `{"next": "%s/v2/repositories/library/redis/tags?page_size=%d", "results": [{"name": "7"}]}`, serverURL, dockerHubTagPageSize)
+				}
+			},
+			image: func(string) string { return "redis" },
+			want:  []string{"7"},
+		},
+		{
+			name: "Link 指向已访问的分页",
+			handler: func(serverURL string, requests *int) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					*requests++
+					w.Header().Set("Link", `</v2/team/app/tags/list?n=1000>; rel="next"`)
+					fmt.Fprint(w, `{"name": "team/app", "tags": ["1.0"]}`)
+				}
+			},
+			image: func(host string) string { return host + "/team/app" },
+			want:  []string{"1.0"},
+		},
+		{
+			name: "Link 不是下一页",
+			handler: func(serverURL string, requests *int) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					*requests++
+					w.Header().Set("Link", `</v2/team/app/tags/list?n=1000&last=x>; rel="prev"`)
+					fmt.Fprint(w, `{"name": "team/app", "tags": ["1.0"]}`)
+				}
+			},
+			image: func(host string) string { return host + "/team/app" },
+			want:  []string{"1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			var server *httptest.Server
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(server.URL, &requests)(w, r)
+			}))
+			defer server.Close()
+
+			tags, err := newTestImageManager(server).FetchAllTags(tt.image(strings.TrimPrefix(server.URL, "https://")))
+			if err != nil {
+				t.Fatalf("FetchAllTags() error = %v", err)
+			}
+			if !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("FetchAllTags() = %v, want %v", tags, tt.want)
+			}
+			if requests != 1 {
+				t.Errorf("请求次数 = %d, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestFetchAllTagsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		image   func(host string) string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Hub API 失败时回退到 OCI Distribution API",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/repositories/user/private/tags":
+					http.Error(w, "not found", http.StatusNotFound)
+				case "/v2/user/private/tags/list":
+					fmt.Fprint(w, `{"name": "user/private", "tags": ["v1"]}`)
+				default:
+					http.NotFound(w, r)
+				}
+			},
+			image: func(string) string { return "user/private:v1" },
+			want:  []string{"v1"},
+		},
+		{
+			name: "Hub API 和 OCI Distribution API 都失败",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			image:   func(string) string { return "user/missing" },
+			wantErr: true,
+		},
+		{
+			name: "仓库不存在",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			image:   func(host string) string { return host + "/team/missing" },
+			wantErr: true,
+		},
+		{
+			name: "后续分页失败",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("last") != "" {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Link", `</v2/team/app/tags/list?n=1000&last=a>; rel="next"`)
+				fmt.Fprint(w, `{"name": "team/app", "tags": ["a"]}`)
+			},
+			image:   func(host string) string { return host + "/team/app" },
+			wantErr: true,
+		},
+		{
+			name: "响应不是有效的 JSON",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `not json`)
+			},
+			image:   func(host string) string { return host + "/team/app" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(tt.handler)
+			defer server.Close()
+
+			tags, err := newTestImageManager(server).FetchAllTags(tt.image(strings.TrimPrefix(server.URL, "https://")))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchAllTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("FetchAllTags() = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// dockerHubSearchHost Docker Hub 镜像搜索 API 地址
+	dockerHubSearchHost = "registry.hub.docker.com"
+
+	// DefaultSearchLimit 搜索结果的默认数量
+	DefaultSearchLimit = 25
+)
+
+// SearchResult Docker Hub 镜像搜索结果
+type SearchResult struct {
+	Name        string
+	Description string
+	Stars       int
+	Pulls       int
+	Official    bool
+	Automated   bool
+}
+
+// hubSearchResponse Docker Hub 搜索 API 响应
+type hubSearchResponse struct {
+	Results []struct {
+		RepoName         string `json:"repo_name"`
+		ShortDescription string `json:"short_description"`
+		StarCount        int    `json:"star_count"`
+		PullCount        int    `json:"pull_count"`
+		IsOfficial       bool   `json:"is_official"`
+		IsAutomated      bool   `json:"is_automated"`
+	} `json:"results"`
+}
+
+// SearchDockerHub 在 Docker Hub 中搜索镜像，limit 不大于 0 时使用 DefaultSearchLimit
+// 结果按 Docker Hub 返回的相关度排序
+func (im *ImageManager) SearchDockerHub(query string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	requestURL := fmt.Sprintf("https://%s/v2/search/repositories/?query=%s&page_size=%d",
+		dockerHubSearchHost, url.QueryEscape(query), limit)
+	resp, err := im.registry.httpClient.Get(requestURL)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Docker Hub 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Docker Hub 响应错误: %d\nURL: %s", resp.StatusCode, requestURL)
+	}
+
+	var response hubSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("解析搜索结果失败: %v", err)
+	}
+
+	results := make([]SearchResult, 0, len(response.Results))
+	for _, item := range response.Results {
+		results = append(results, SearchResult{
+			Name:        item.RepoName,
+			Description: item.ShortDescription,
+			Stars:       item.StarCount,
+			Pulls:       item.PullCount,
+			Official:    item.IsOfficial,
+			Automated:   item.IsAutomated,
+		})
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshDummyHost 通过 SSH 连接时使用的占位地址，实际连接由 dialSSH 建立
+const sshDummyHost = "http://docker.example.com"
+
+// SSHHostURL 将 user@host[:port] 格式的地址转换为 DOCKER_HOST 使用的 ssh:// 地址
+func SSHHostURL(sshHost string) string {
+	return "ssh://" + strings.TrimPrefix(sshHost, "ssh://")
+}
+
+// sshCommandArgs 根据 SSH 地址和私钥路径生成执行 docker system dial-stdio 的 ssh 参数
+func sshCommandArgs(sshHost, keyPath string) ([]string, error) {
+	u, err := url.Parse(SSHHostURL(sshHost))
+	if err != nil {
+		return nil, fmt.Errorf("无效的 SSH 地址 %s: %v", sshHost, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("无效的 SSH 地址 %s: 缺少主机名", sshHost)
+	}
+
+	var args []string
+	if keyPath != "" {
+		args = append(args, "-i", keyPath)
+	}
+	if u.User != nil && u.User.Username() != "" {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+	return args, nil
+}
+
+// sshDialer 返回通过 ssh 子进程连接远程 Docker daemon 的拨号函数
+// 与 docker CLI 的 ssh:// 支持相同，依赖远程主机上的 docker system dial-stdio
+func sshDialer(sshHost, keyPath string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	args, err := sshCommandArgs(sshHost, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// 不使用 CommandContext：拨号的 ctx 结束后连接仍需保持
+		cmd := exec.Command("ssh", args...)
+		return newCommandConn(cmd)
+	}, nil
+}
+
+// commandConn 将子进程的标准输入输出包装为 net.Conn
+type commandConn struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	stderr    bytes.Buffer
+	closeOnce sync.Once
+}
+
+func newCommandConn(cmd *exec.Cmd) (net.Conn, error) {
+	c := &commandConn{cmd: cmd}
+
+	var err error
+	if c.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if c.stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &c.stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动 ssh 失败: %v", err)
+	}
+	return c, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF && c.stderr.Len() > 0 {
+		return n, fmt.Errorf("ssh 连接已断开: %s", strings.TrimSpace(c.stderr.String()))
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return dummyAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr { return dummyAddr{} }
+
+// 子进程管道不支持超时，deadline 相关方法为空实现
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dummyAddr struct{}
+
+func (dummyAddr) Network() string { return "ssh" }
+func (dummyAddr) String() string  { return "ssh" }
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"compman/pkg/types"
+)
+
+// SignatureHeader 携带请求体 HMAC-SHA256 签名的请求头
+const SignatureHeader = "X-Compman-Signature"
+
+// WebhookPayload 发送到 Webhook 的更新结果
+type WebhookPayload struct {
+	RunID     string                    `json:"run_id"`
+	StartedAt time.Time                 `json:"started_at"`
+	DurationS float64                   `json:"duration_s"`
+	Results   []types.UpdateReportEntry `json:"results"`
+}
+
+// WebhookNotifier 将更新结果以 JSON 格式 POST 到任意 HTTP 地址
+type WebhookNotifier struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier 创建新的 Webhook 通知器，secret 为空时不签名
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// NewPayload 根据更新结果创建通知内容
+func NewPayload(startedAt time.Time, results []*types.UpdateResult) *WebhookPayload {
+	payload := &WebhookPayload{
+		RunID:     newRunID(startedAt),
+		StartedAt: startedAt,
+		DurationS: time.Since(startedAt).Seconds(),
+		Results:   make([]types.UpdateReportEntry, 0, len(results)),
+	}
+
+	for _, result := range results {
+		payload.Results = append(payload.Results, result.ReportEntry())
+	}
+
+	return payload
+}
+
+// Send 发送通知，遇到 5xx 响应时重试一次
+func (n *WebhookNotifier) Send(payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化通知内容失败: %v", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		retry, err := n.post(body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post 发送一次请求，返回是否可以重试
+func (n *WebhookNotifier) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("创建 Webhook 请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(body, n.secret))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("发送 Webhook 请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return true, fmt.Errorf("Webhook 响应错误: %d - %s", resp.StatusCode, string(respBody))
+	}
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("Webhook 响应错误: %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	return false, nil
+}
+
+// Sign 计算请求体的 HMAC-SHA256 签名 (十六进制)
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newRunID 生成本次运行的唯一标识
+func newRunID(startedAt time.Time) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return startedAt.Format("20060102T150405")
+	}
+	return startedAt.Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,166 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	// latestReleaseURL GitHub 上 compman 最新发布版本的 API 地址
+	latestReleaseURL = "https://api.github.com/repos/QuentinHsu/compman/releases/latest"
+
+	// NoUpdateCheckEnv 设置为 1 时跳过版本检查，适用于 CI 等无需联网检查的环境
+	NoUpdateCheckEnv = "COMPMAN_NO_UPDATE_CHECK"
+
+	// cacheTTL 检查结果的缓存时间，避免频繁请求 GitHub API
+	cacheTTL = 24 * time.Hour
+
+	// requestTimeout 请求 GitHub API 的超时时间
+	requestTimeout = 10 * time.Second
+)
+
+// CheckResult 版本检查结果
+type CheckResult struct {
+	Current         string
+	Latest          string
+	UpdateAvailable bool
+	CheckedAt       time.Time
+	Cached          bool // 结果来自 24 小时内的缓存
+}
+
+// cacheEntry 缓存文件的内容
+type cacheEntry struct {
+	Latest    string    `json:"latest"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Disabled 返回是否通过 COMPMAN_NO_UPDATE_CHECK 禁用了版本检查
+func Disabled() bool {
+	return os.Getenv(NoUpdateCheckEnv) == "1"
+}
+
+// CheckLatest 查询 GitHub 上 compman 的最新发布版本，并与当前版本按语义版本比较
+// 24 小时内的检查结果从缓存读取；缓存读写失败不影响检查
+func CheckLatest(current string) (*CheckResult, error) {
+	currentVersion, err := semver.NewVersion(current)
+	if err != nil {
+		return nil, fmt.Errorf("当前版本 %s 不是有效的语义版本: %v", current, err)
+	}
+
+	entry, cached := readCache()
+	if !cached {
+		latest, err := fetchLatestTag()
+		if err != nil {
+			return nil, err
+		}
+		entry = &cacheEntry{Latest: latest, CheckedAt: time.Now()}
+		_ = writeCache(entry)
+	}
+
+	latestVersion, err := semver.NewVersion(entry.Latest)
+	if err != nil {
+		return nil, fmt.Errorf("最新版本 %s 不是有效的语义版本: %v", entry.Latest, err)
+	}
+
+	return &CheckResult{
+		Current:         current,
+		Latest:          entry.Latest,
+		UpdateAvailable: latestVersion.GreaterThan(currentVersion),
+		CheckedAt:       entry.CheckedAt,
+		Cached:          cached,
+	}, nil
+}
+
+// fetchLatestTag 请求 GitHub API，返回最新发布版本的 tag_name
+func fetchLatestTag() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "compman")
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求 GitHub 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("GitHub 响应错误: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("解析 GitHub 响应失败: %v", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("GitHub 响应中缺少 tag_name")
+	}
+
+	return release.TagName, nil
+}
+
+// cachePath 返回缓存文件路径，优先使用 XDG_DATA_HOME，默认为 ~/.local/share/compman/update_check.json
+func cachePath() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "compman", "update_check.json"), nil
+}
+
+// readCache 读取未过期的缓存，不存在、无法解析或已过期时返回 false
+func readCache() (*cacheEntry, bool) {
+	path, err := cachePath()
+	if err != nil {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Latest == "" {
+		return nil, false
+	}
+	if time.Since(entry.CheckedAt) > cacheTTL {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// writeCache 保存检查结果
+func writeCache(entry *cacheEntry) error {
+	path, err := cachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,33 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"compman/internal/docker"
+	"compman/pkg/types"
+)
+
+// NewFromString 根据策略名称创建镜像标签策略
+func NewFromString(name string, config *types.Config) (types.ImageTagStrategy, error) {
+	pattern := ""
+	regexPattern, captureGroup := "", 1
+	apiTimeout := docker.DefaultAPITimeout
+	if config != nil {
+		pattern = config.SemverPattern
+		regexPattern, captureGroup = config.RegexPattern, config.RegexCaptureGroup
+		apiTimeout = config.RegistryAPITimeout
+	}
+	imageManager := docker.NewImageManagerWithClient(docker.NewClient(), apiTimeout)
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "latest", "":
+		return newLatestStrategyWithManager(imageManager), nil
+	case "semver":
+		return newSemverStrategyWithManager(pattern, imageManager), nil
+	case "regex":
+		return newRegexStrategyWithManager(regexPattern, captureGroup, imageManager)
+	default:
+		return nil, fmt.Errorf("未知的镜像标签策略: %s (支持: latest, semver, regex)", name)
+	}
+}
@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// tagFormatData 标签格式模板中可用的字段
+type tagFormatData struct {
+	Version string // 策略返回的标签，如 1.2.3
+}
+
+// FormatTag 使用 Go text/template 模板转换策略返回的标签，如 release-{{.Version}}
+// format 为空时原样返回
+func FormatTag(format, version string) (string, error) {
+	if format == "" {
+		return version, nil
+	}
+
+	tmpl, err := template.New("tag_format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("解析标签格式 %q 失败: %v", format, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, tagFormatData{Version: version}); err != nil {
+		return "", fmt.Errorf("应用标签格式 %q 失败: %v", format, err)
+	}
+
+	tag := strings.TrimSpace(buf.String())
+	if tag == "" || strings.ContainsAny(tag, " :/@") {
+		return "", fmt.Errorf("标签格式 %q 生成了无效的标签 %q", format, tag)
+	}
+
+	return tag, nil
+}
+
+// ExtractVersion 从按 format 生成的标签中还原版本，如 release-1.2.3 → 1.2.3
+// 标签与格式不匹配时返回 false；format 为空时原样返回
+func ExtractVersion(format, tag string) (string, bool) {
+	if format == "" {
+		return tag, true
+	}
+
+	// 使用占位符生成标签，确定版本两侧的固定前缀和后缀
+	const placeholder = "\x00"
+	formatted, err := FormatTag(format, placeholder)
+	if err != nil {
+		return "", false
+	}
+	prefix, suffix, found := strings.Cut(formatted, placeholder)
+	if !found || strings.Contains(suffix, placeholder) {
+		return "", false
+	}
+
+	if len(tag) <= len(prefix)+len(suffix) || !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+		return "", false
+	}
+	return tag[len(prefix) : len(tag)-len(suffix)], true
+}
@@ -14,8 +14,13 @@ type LatestStrategy struct {
 
 // NewLatestStrategy 创建新的 latest 策略
 func NewLatestStrategy() *LatestStrategy {
+	return newLatestStrategyWithManager(docker.NewImageManager())
+}
+
+// newLatestStrategyWithManager 使用指定的镜像管理器创建 latest 策略
+func newLatestStrategyWithManager(imageManager *docker.ImageManager) *LatestStrategy {
 	return &LatestStrategy{
-		imageManager: docker.NewImageManager(),
+		imageManager: imageManager,
 	}
 }
 
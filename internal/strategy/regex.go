@@ -0,0 +1,185 @@
+package strategy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"compman/internal/docker"
+)
+
+// RegexStrategy 自定义正则表达式标签策略，适用于 build-12345-prod 这类非语义版本的标签
+// 只选择匹配 Pattern 的标签，并按第 CaptureGroup 个捕获组的内容排序
+type RegexStrategy struct {
+	Pattern      string
+	CaptureGroup int
+
+	regex        *regexp.Regexp
+	imageManager *docker.ImageManager
+}
+
+// NewRegexStrategy 创建新的正则表达式策略，captureGroup 为 0 时使用整个匹配的标签排序
+func NewRegexStrategy(pattern string, captureGroup int) (*RegexStrategy, error) {
+	return newRegexStrategyWithManager(pattern, captureGroup, docker.NewImageManager())
+}
+
+// newRegexStrategyWithManager 使用指定的镜像管理器创建正则表达式策略
+func newRegexStrategyWithManager(pattern string, captureGroup int, imageManager *docker.ImageManager) (*RegexStrategy, error) {
+	if err := ValidateRegexPattern(pattern, captureGroup); err != nil {
+		return nil, err
+	}
+
+	return &RegexStrategy{
+		Pattern:      pattern,
+		CaptureGroup: captureGroup,
+		regex:        regexp.MustCompile(pattern),
+		imageManager: imageManager,
+	}, nil
+}
+
+// ValidateRegexPattern 检查正则表达式能否编译，以及捕获组序号是否存在，配置校验和创建策略时共用
+func ValidateRegexPattern(pattern string, captureGroup int) error {
+	if pattern == "" {
+		return fmt.Errorf("regex 策略需要设置 regex_pattern")
+	}
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("无效的正则表达式 %q: %v", pattern, err)
+	}
+	if captureGroup < 0 || captureGroup > regex.NumSubexp() {
+		return fmt.Errorf("正则表达式 %q 没有第 %d 个捕获组", pattern, captureGroup)
+	}
+
+	return nil
+}
+
+// GetLatestTag 获取匹配正则表达式且排序最大的标签
+func (s *RegexStrategy) GetLatestTag(image string) (string, error) {
+	imageName := s.extractImageName(image)
+
+	tags, err := s.imageManager.GetImageTags(imageName)
+	if err != nil {
+		return "", fmt.Errorf("获取镜像标签失败: %v", err)
+	}
+
+	var matched []string
+	for _, tag := range tags {
+		if s.ValidateTag(tag) {
+			matched = append(matched, tag)
+		}
+	}
+
+	if len(matched) == 0 {
+		return "", fmt.Errorf("未找到匹配 %s 的标签", s.Pattern)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return s.CompareVersions(matched[i], matched[j]) < 0
+	})
+	return matched[len(matched)-1], nil
+}
+
+// ValidateTag 验证标签是否匹配正则表达式
+func (s *RegexStrategy) ValidateTag(tag string) bool {
+	_, ok := s.sortKey(tag)
+	return ok
+}
+
+// sortKey 返回标签中用于排序的捕获组内容
+func (s *RegexStrategy) sortKey(tag string) (string, bool) {
+	match := s.regex.FindStringSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+	return match[s.CaptureGroup], true
+}
+
+// CompareVersions 按捕获组内容比较两个标签：都是数字时按数值比较，否则按字符串比较
+// 不匹配正则表达式的标签视为小于匹配的标签
+func (s *RegexStrategy) CompareVersions(tag1, tag2 string) int {
+	key1, ok1 := s.sortKey(tag1)
+	key2, ok2 := s.sortKey(tag2)
+
+	switch {
+	case !ok1 && !ok2:
+		return strings.Compare(tag1, tag2)
+	case !ok1:
+		return -1
+	case !ok2:
+		return 1
+	}
+
+	if isDigits(key1) && isDigits(key2) {
+		// 按数值比较，不受位数限制：去掉前导零后位数多的更大，位数相同时按字符串比较
+		key1, key2 = trimLeadingZeros(key1), trimLeadingZeros(key2)
+		if len(key1) != len(key2) {
+			if len(key1) < len(key2) {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return strings.Compare(key1, key2)
+}
+
+// ShouldUpdate 检查目标镜像的标签是否排在当前标签之后
+func (s *RegexStrategy) ShouldUpdate(currentImage, targetImage string) bool {
+	return s.CompareVersions(s.extractTag(currentImage), s.extractTag(targetImage)) < 0
+}
+
+// GetStrategyName 获取策略名称
+func (s *RegexStrategy) GetStrategyName() string {
+	return "regex"
+}
+
+// GetDescription 获取策略描述
+func (s *RegexStrategy) GetDescription() string {
+	return fmt.Sprintf("正则表达式策略，匹配 %s 并按第 %d 个捕获组排序", s.Pattern, s.CaptureGroup)
+}
+
+// extractImageName 从完整镜像名称中提取不带标签的部分
+func (s *RegexStrategy) extractImageName(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[:colon]
+	}
+	return image
+}
+
+// extractTag 从镜像名称中提取标签
+func (s *RegexStrategy) extractTag(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[colon+1:]
+	}
+	return "latest"
+}
+
+// isDigits 判断字符串是否只包含数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// trimLeadingZeros 去掉数字字符串的前导零，全为零时保留一个 0
+func trimLeadingZeros(s string) string {
+	trimmed := strings.TrimLeft(s, "0")
+	if trimmed == "" {
+		return "0"
+	}
+	return trimmed
+}
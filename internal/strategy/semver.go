@@ -19,7 +19,14 @@ type SemverStrategy struct {
 }
 
 // NewSemverStrategy 创建新的语义版本策略
+// pattern 支持 Masterminds/semver 约束语法，包括 ~1.2.0 (仅补丁版本)、
+// ^1.0.0 (次版本和补丁版本)、>= 1.0.0, < 2.0.0 等组合形式
 func NewSemverStrategy(pattern string) *SemverStrategy {
+	return newSemverStrategyWithManager(pattern, docker.NewImageManager())
+}
+
+// newSemverStrategyWithManager 使用指定的镜像管理器创建语义版本策略
+func newSemverStrategyWithManager(pattern string, imageManager *docker.ImageManager) *SemverStrategy {
 	if pattern == "" {
 		pattern = "*" // 默认接受所有版本
 	}
@@ -33,7 +40,7 @@ func NewSemverStrategy(pattern string) *SemverStrategy {
 
 	return &SemverStrategy{
 		pattern:      pattern,
-		imageManager: docker.NewImageManager(),
+		imageManager: imageManager,
 		constraint:   constraint,
 	}
 }
@@ -244,6 +251,32 @@ func (s *SemverStrategy) GetVersionList(image string, limit int) ([]*semver.Vers
 	return versions, nil
 }
 
+// FormatVersionList 将版本列表格式化为升级路径，如 "1.25.0, 1.25.1, 1.25.2 (latest)"
+// 只保留比 currentTag 新的版本并按从旧到新排列；currentTag 不是语义版本时保留全部版本
+func (s *SemverStrategy) FormatVersionList(versions []*semver.Version, currentTag string) string {
+	current, err := s.parseVersion(currentTag)
+
+	var newer []*semver.Version
+	for _, version := range versions {
+		if err != nil || version.GreaterThan(current) {
+			newer = append(newer, version)
+		}
+	}
+	if len(newer) == 0 {
+		return ""
+	}
+
+	sort.Sort(semver.Collection(newer))
+
+	parts := make([]string, len(newer))
+	for i, version := range newer {
+		parts[i] = version.Original()
+	}
+	parts[len(parts)-1] += " (latest)"
+
+	return strings.Join(parts, ", ")
+}
+
 // SetConstraint 设置版本约束
 func (s *SemverStrategy) SetConstraint(pattern string) error {
 	constraint, err := semver.NewConstraint(pattern)
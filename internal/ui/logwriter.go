@@ -0,0 +1,27 @@
+package ui
+
+import (
+	"io"
+	"regexp"
+)
+
+// ansiEscapePattern 匹配终端颜色和光标控制序列
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// ansiStripWriter 去除 ANSI 控制序列后写入，用于将彩色输出保存为纯文本日志
+type ansiStripWriter struct {
+	w io.Writer
+}
+
+// NewANSIStripWriter 创建去除 ANSI 控制序列的写入器
+func NewANSIStripWriter(w io.Writer) io.Writer {
+	return &ansiStripWriter{w: w}
+}
+
+func (a *ansiStripWriter) Write(p []byte) (int, error) {
+	if _, err := a.w.Write(ansiEscapePattern.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
+	// 返回原始长度，避免 io.MultiWriter 将去除的字节视为短写入
+	return len(p), nil
+}
@@ -3,13 +3,18 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
 
+	"compman/pkg/types"
+
 	"github.com/fatih/color"
 	"golang.org/x/term"
 )
@@ -19,6 +24,7 @@ var (
 	green   = color.New(color.FgGreen)
 	blue    = color.New(color.FgBlue)
 	cyan    = color.New(color.FgCyan)
+	yellow  = color.New(color.FgYellow)
 	magenta = color.New(color.FgMagenta)
 	white   = color.New(color.FgWhite)
 
@@ -34,6 +40,23 @@ var (
 	subHeaderStyle = color.New(color.FgCyan, color.Bold)
 )
 
+// output 所有输出函数写入的目标，默认为标准输出
+var output io.Writer = os.Stdout
+
+// SetOutput 设置输出函数写入的目标，如同时写入标准输出和日志文件的 io.MultiWriter
+func SetOutput(w io.Writer) {
+	output = w
+	color.Output = w
+}
+
+// stdin 所有输入函数共用的标准输入读取器，避免多个读取器各自缓冲导致管道输入丢失
+var stdin = bufio.NewReader(os.Stdin)
+
+// Output 返回当前的输出目标
+func Output() io.Writer {
+	return output
+}
+
 // PrintSuccess prints a success message with green color and checkmark
 func PrintSuccess(message string) {
 	successStyle.Printf("✅ %s\n", message)
@@ -56,26 +79,26 @@ func PrintWarning(message string) {
 
 // PrintHeader prints a main header with decoration
 func PrintHeader(message string) {
-	fmt.Println()
+	fmt.Fprintln(output)
 	headerStyle.Printf("╭─ %s ─╮\n", strings.ToUpper(message))
 }
 
 // PrintSubHeader prints a sub header
 func PrintSubHeader(message string) {
-	fmt.Println()
+	fmt.Fprintln(output)
 	subHeaderStyle.Printf("📋 %s\n", message)
 }
 
 // PrintSection prints a section divider
 func PrintSection(title string) {
-	fmt.Println()
+	fmt.Fprintln(output)
 	cyan.Printf("═══ %s ═══\n", strings.ToUpper(title))
-	fmt.Println()
+	fmt.Fprintln(output)
 }
 
 // PrintItem prints a list item with bullet point
 func PrintItem(message string) {
-	fmt.Printf("  %s\n", message)
+	fmt.Fprintf(output, "  %s\n", message)
 }
 
 // PrintProgress prints a progress message with spinner
@@ -86,7 +109,7 @@ func PrintProgress(message string) {
 // PrintTimestamp prints a message with timestamp
 func PrintTimestamp(message string) {
 	timestamp := time.Now().Format("15:04:05")
-	fmt.Printf("[%s] %s\n", cyan.Sprint(timestamp), message)
+	fmt.Fprintf(output, "[%s] %s\n", cyan.Sprint(timestamp), message)
 }
 
 // PrintBanner prints application banner
@@ -99,9 +122,9 @@ func PrintBanner(version string) {
 `
 	magenta.Print(banner)
 	if version != "" {
-		fmt.Printf("                v%s\n", version)
+		fmt.Fprintf(output, "                v%s\n", version)
 	}
-	fmt.Println()
+	fmt.Fprintln(output)
 }
 
 // getTerminalWidth 获取终端宽度
@@ -115,23 +138,107 @@ func getTerminalWidth() int {
 
 // truncateString 截断字符串并添加省略号
 func truncateString(s string, maxLen int) string {
-	if maxLen <= 3 {
-		return "..."
-	}
 	if utf8.RuneCountInString(s) <= maxLen {
 		return s
 	}
+	if maxLen <= 3 {
+		return "..."
+	}
 	runes := []rune(s)
 	return string(runes[:maxLen-3]) + "..."
 }
 
+// TerminalWidth 返回终端宽度，无法获取时为 80
+func TerminalWidth() int {
+	return getTerminalWidth()
+}
+
+// TruncateString 将字符串截断到指定字符数，超出部分以省略号代替
+func TruncateString(s string, maxLen int) string {
+	return truncateString(s, maxLen)
+}
+
+// ansiPattern 匹配终端颜色控制序列
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// visibleWidth 返回去掉颜色控制序列后的字符宽度
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+}
+
+// formatCell 将单元格内容按对齐方式填充到指定宽度，带颜色的内容超宽时会去掉颜色后截断
+func formatCell(cell string, width int, alignment Alignment) string {
+	if visibleWidth(cell) > width {
+		cell = truncateString(ansiPattern.ReplaceAllString(cell, ""), width)
+	}
+	return padCell(cell, visibleWidth(cell), width, alignment)
+}
+
+// TreeNodeType 树节点的类型，决定节点标签的颜色
+type TreeNodeType int
+
+const (
+	TreeNodePlain   TreeNodeType = iota // 普通节点，不着色
+	TreeNodeService                     // 服务，青色
+	TreeNodeImage                       // 镜像，绿色
+	TreeNodeVolume                      // 数据卷，黄色
+)
+
+// TreeNode 树形输出中的节点
+type TreeNode struct {
+	Label    string
+	Type     TreeNodeType
+	Children []*TreeNode
+}
+
+// PrintTree prints the tree with ├──, └── and │ connectors, coloring each label by node type
+func PrintTree(root *TreeNode) {
+	if root == nil {
+		return
+	}
+	fmt.Fprintln(output, root.coloredLabel())
+	printTreeChildren(root.Children, "")
+}
+
+// printTreeChildren 递归打印子节点，prefix 为上层节点留下的竖线和缩进
+func printTreeChildren(children []*TreeNode, prefix string) {
+	for i, child := range children {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+		fmt.Fprintf(output, "%s%s%s\n", prefix, connector, child.coloredLabel())
+		printTreeChildren(child.Children, prefix+childPrefix)
+	}
+}
+
+// coloredLabel 按节点类型返回着色后的标签
+func (n *TreeNode) coloredLabel() string {
+	switch n.Type {
+	case TreeNodeService:
+		return cyan.Sprint(n.Label)
+	case TreeNodeImage:
+		return green.Sprint(n.Label)
+	case TreeNodeVolume:
+		return yellow.Sprint(n.Label)
+	default:
+		return n.Label
+	}
+}
+
 // PrintTable prints a responsive table that adapts to terminal width
+// 所有列左对齐，需要其他对齐方式时使用 Table 构建器
 func PrintTable(headers []string, rows [][]string) {
+	printTable(headers, nil, rows)
+}
+
+// printTable 打印表格，alignments 指定各列的对齐方式，缺省为左对齐
+func printTable(headers []string, alignments []Alignment, rows [][]string) {
 	if len(headers) == 0 || len(rows) == 0 {
 		return
 	}
 
-	fmt.Println() // 表格前添加空行
+	fmt.Fprintln(output) // 表格前添加空行
 
 	terminalWidth := getTerminalWidth()
 
@@ -150,7 +257,7 @@ func PrintTable(headers []string, rows [][]string) {
 	for _, row := range rows {
 		for i, cell := range row {
 			if i < len(colWidths) {
-				cellWidth := utf8.RuneCountInString(cell)
+				cellWidth := visibleWidth(cell)
 				if cellWidth > colWidths[i] {
 					colWidths[i] = cellWidth
 				}
@@ -177,81 +284,86 @@ func PrintTable(headers []string, rows [][]string) {
 	}
 
 	// 打印表头
-	fmt.Printf("┌")
+	fmt.Fprintf(output, "┌")
 	for i, width := range colWidths {
-		fmt.Printf("%s", strings.Repeat("─", width+2))
+		fmt.Fprintf(output, "%s", strings.Repeat("─", width+2))
 		if i < len(colWidths)-1 {
-			fmt.Printf("┬")
+			fmt.Fprintf(output, "┬")
 		}
 	}
-	fmt.Printf("┐\n")
+	fmt.Fprintf(output, "┐\n")
 
 	// 打印表头内容
-	fmt.Printf("│")
+	fmt.Fprintf(output, "│")
 	for i, header := range headers {
 		headerText := truncateString(header, colWidths[i])
-		fmt.Printf(" %-*s │", colWidths[i], bold.Sprint(headerText))
+		fmt.Fprintf(output, " %s │", formatCell(bold.Sprint(headerText), colWidths[i], alignmentAt(alignments, i)))
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(output, "\n")
 
 	// 打印分隔线
-	fmt.Printf("├")
+	fmt.Fprintf(output, "├")
 	for i, width := range colWidths {
-		fmt.Printf("%s", strings.Repeat("─", width+2))
+		fmt.Fprintf(output, "%s", strings.Repeat("─", width+2))
 		if i < len(colWidths)-1 {
-			fmt.Printf("┼")
+			fmt.Fprintf(output, "┼")
 		}
 	}
-	fmt.Printf("┤\n")
+	fmt.Fprintf(output, "┤\n")
 
 	// 打印数据行
 	for _, row := range rows {
-		fmt.Printf("│")
+		fmt.Fprintf(output, "│")
 		for i, cell := range row {
 			if i < len(colWidths) {
-				cellText := truncateString(cell, colWidths[i])
-				fmt.Printf(" %-*s │", colWidths[i], cellText)
+				fmt.Fprintf(output, " %s │", formatCell(cell, colWidths[i], alignmentAt(alignments, i)))
 			}
 		}
-		fmt.Printf("\n")
+		fmt.Fprintf(output, "\n")
 	}
 
 	// 打印底部边框
-	fmt.Printf("└")
+	fmt.Fprintf(output, "└")
 	for i, width := range colWidths {
-		fmt.Printf("%s", strings.Repeat("─", width+2))
+		fmt.Fprintf(output, "%s", strings.Repeat("─", width+2))
 		if i < len(colWidths)-1 {
-			fmt.Printf("┴")
+			fmt.Fprintf(output, "┴")
 		}
 	}
-	fmt.Printf("┘\n")
-	fmt.Println() // 表格后添加空行
+	fmt.Fprintf(output, "┘\n")
+	fmt.Fprintln(output) // 表格后添加空行
 }
 
 // printCompactTable 打印紧凑模式的表格，适用于小屏幕
-func printCompactTable(_ []string, rows [][]string) {
-	// 对于小屏幕，使用列表格式显示
+func printCompactTable(headers []string, rows [][]string) {
+	// 对于小屏幕，使用列表格式显示：前两列作为标题，其余列按 "表头: 值" 显示
 	for i, row := range rows {
-		fmt.Printf("%s %s\n", bold.Sprint(fmt.Sprintf("[%s]", row[0])), cyan.Sprint(row[1])) // 序号和项目名称
-
-		if len(row) > 2 && row[2] != "" {
-			fmt.Printf("    📁 %s\n", truncateString(row[2], 60)) // 文件路径
+		if len(row) == 0 {
+			continue
 		}
 
-		if len(row) > 3 && row[3] != "" {
-			fmt.Printf("    🔧 服务数量: %s\n", row[3])
+		if len(row) > 1 {
+			fmt.Fprintf(output, "%s %s\n", bold.Sprint(fmt.Sprintf("[%s]", row[0])), cyan.Sprint(row[1]))
+		} else {
+			fmt.Fprintf(output, "%s\n", bold.Sprint(row[0]))
 		}
 
-		if len(row) > 4 && row[4] != "" {
-			services := truncateString(row[4], 50)
-			fmt.Printf("    🐳 镜像服务: %s\n", services)
+		for j := 2; j < len(row); j++ {
+			if row[j] == "" {
+				continue
+			}
+			label := ""
+			if j < len(headers) {
+				label = headers[j] + ": "
+			}
+			fmt.Fprintf(output, "    %s%s\n", label, truncateString(row[j], 60))
 		}
 
 		if i < len(rows)-1 {
-			fmt.Printf("%s\n", strings.Repeat("─", 50))
+			fmt.Fprintf(output, "%s\n", strings.Repeat("─", 50))
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(output)
 }
 
 // ProgressBar represents a simple progress bar
@@ -266,139 +378,189 @@ type ProgressBar struct {
 	mutex     sync.Mutex // 添加互斥锁防止并发渲染
 }
 
-// MultiProgressBar represents multiple progress bars for multiple files
+// multiProgressRenderInterval 多进度条后台渲染间隔
+const multiProgressRenderInterval = 100 * time.Millisecond
+
+// fileProgress holds the progress state of a single file
+type fileProgress struct {
+	prefix   string
+	percent  int
+	status   string
+	started  bool
+	finished bool
+}
+
+// MultiProgressBar renders one progress bar per file and is safe for concurrent use.
+// A background goroutine redraws all bars in place every 100ms until Finish is called.
 type MultiProgressBar struct {
-	bars  []*ProgressBar
-	mutex sync.Mutex
+	files    []fileProgress
+	width    int
+	dirty    bool
+	rendered bool
+	mutex    sync.Mutex
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	stopOnce sync.Once
 }
 
-// NewMultiProgressBar creates a new multi-progress bar
+// NewMultiProgressBar creates a new multi-progress bar and starts its renderer
 func NewMultiProgressBar(fileNames []string) *MultiProgressBar {
-	bars := make([]*ProgressBar, len(fileNames))
+	files := make([]fileProgress, len(fileNames))
 	for i := range fileNames {
-		bars[i] = &ProgressBar{
-			total:     100, // 使用百分比作为进度
-			current:   0,
-			width:     40,
-			prefix:    fmt.Sprintf("[%d]", i+1),
-			currentOp: "",
-			finished:  false,
-			started:   false,
+		files[i] = fileProgress{
+			prefix: fmt.Sprintf("[%d]", i+1),
 		}
 	}
-	return &MultiProgressBar{
-		bars: bars,
+
+	mpb := &MultiProgressBar{
+		files:  files,
+		width:  40,
+		dirty:  true,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
 	}
+	go mpb.renderLoop()
+
+	return mpb
 }
 
 // UpdateFile updates the progress for a specific file
-func (mpb *MultiProgressBar) UpdateFile(fileIndex int, progress int, message string) {
+func (mpb *MultiProgressBar) UpdateFile(index, percent int, status string) {
 	mpb.mutex.Lock()
 	defer mpb.mutex.Unlock()
 
-	if fileIndex >= len(mpb.bars) {
+	if index < 0 || index >= len(mpb.files) {
 		return
 	}
 
-	bar := mpb.bars[fileIndex]
-	bar.mutex.Lock()
-	bar.current = progress
-	bar.currentOp = message
-	bar.started = true
-	if progress >= 100 {
-		bar.finished = true
-		bar.currentOp = "✅ 完成"
+	// 进度限制在 0 到 100 之间，负数会导致渲染时 strings.Repeat 崩溃
+	if percent < 0 {
+		percent = 0
 	}
-	bar.mutex.Unlock()
 
-	mpb.renderAll()
+	file := &mpb.files[index]
+	file.percent = percent
+	file.status = status
+	file.started = true
+	if percent >= 100 {
+		file.percent = 100
+		file.finished = true
+		if status == "" {
+			file.status = "✅ 完成"
+		}
+	}
+	mpb.dirty = true
 }
 
 // FinishFile marks a file as finished
-func (mpb *MultiProgressBar) FinishFile(fileIndex int) {
+func (mpb *MultiProgressBar) FinishFile(index int) {
 	mpb.mutex.Lock()
 	defer mpb.mutex.Unlock()
 
-	if fileIndex >= len(mpb.bars) {
+	if index < 0 || index >= len(mpb.files) {
 		return
 	}
 
-	bar := mpb.bars[fileIndex]
-	bar.mutex.Lock()
-	bar.current = 100
-	bar.finished = true
-	bar.currentOp = "✅ 完成"
-	bar.mutex.Unlock()
+	file := &mpb.files[index]
+	file.percent = 100
+	file.started = true
+	file.finished = true
+	file.status = "✅ 完成"
+	mpb.dirty = true
+}
+
+// Finish completes all progress bars, stops the renderer and draws the final state
+func (mpb *MultiProgressBar) Finish() {
+	mpb.stopOnce.Do(func() {
+		close(mpb.stopCh)
+		<-mpb.doneCh
+
+		mpb.mutex.Lock()
+		defer mpb.mutex.Unlock()
+
+		for i := range mpb.files {
+			file := &mpb.files[i]
+			if !file.finished {
+				file.percent = 100
+				file.started = true
+				file.finished = true
+				file.status = "✅ 完成"
+			}
+		}
 
-	mpb.renderAll()
+		mpb.renderAll()
+		fmt.Fprintln(output) // 最后换行
+	})
 }
 
-// renderAll renders all progress bars
-func (mpb *MultiProgressBar) renderAll() {
-	// 移动到第一行开始位置
-	if len(mpb.bars) > 1 {
-		fmt.Printf("\033[%dA", len(mpb.bars))
+// renderLoop redraws the bars periodically until Finish is called
+func (mpb *MultiProgressBar) renderLoop() {
+	defer close(mpb.doneCh)
+
+	ticker := time.NewTicker(multiProgressRenderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mpb.stopCh:
+			return
+		case <-ticker.C:
+			mpb.mutex.Lock()
+			if mpb.dirty {
+				mpb.renderAll()
+			}
+			mpb.mutex.Unlock()
+		}
 	}
+}
 
-	for _, bar := range mpb.bars {
-		bar.mutex.Lock()
+// renderAll redraws all progress bars in place; the caller must hold mpb.mutex
+func (mpb *MultiProgressBar) renderAll() {
+	// 光标上移到第一个进度条所在行
+	if mpb.rendered && len(mpb.files) > 0 {
+		fmt.Fprintf(output, "\033[%dA", len(mpb.files))
+	}
 
-		percent := float64(bar.current) / 100.0
-		filled := int(percent * float64(bar.width))
+	for _, file := range mpb.files {
+		filled := file.percent * mpb.width / 100
+		if filled > mpb.width {
+			filled = mpb.width
+		}
 
 		filledBar := strings.Repeat("█", filled)
-		emptyBar := strings.Repeat("░", bar.width-filled)
+		emptyBar := strings.Repeat("░", mpb.width-filled)
 
 		// 清除当前行
-		fmt.Print("\r\033[K")
+		fmt.Fprint(output, "\r\033[K")
 
 		message := ""
-		if bar.currentOp != "" {
-			message = fmt.Sprintf(" - %s", bar.currentOp)
+		if file.status != "" {
+			message = fmt.Sprintf(" - %s", file.status)
 		}
 
-		if bar.finished {
-			fmt.Printf("%s [%s] 100%%%s\n",
-				bar.prefix,
+		if file.finished {
+			fmt.Fprintf(output, "%s [%s] 100%%%s\n",
+				file.prefix,
 				green.Sprint(filledBar+emptyBar),
 				message)
-		} else if bar.started {
-			fmt.Printf("%s [%s] %d%%%s\n",
-				bar.prefix,
+		} else if file.started {
+			fmt.Fprintf(output, "%s [%s] %d%%%s\n",
+				file.prefix,
 				green.Sprint(filledBar)+white.Sprint(emptyBar),
-				bar.current,
+				file.percent,
 				message)
 		} else {
-			fmt.Printf("%s [%s] 0%% - 等待中...\n",
-				bar.prefix,
-				white.Sprint(strings.Repeat("░", bar.width)))
+			fmt.Fprintf(output, "%s [%s] 0%% - 等待中...\n",
+				file.prefix,
+				white.Sprint(strings.Repeat("░", mpb.width)))
 		}
-
-		bar.mutex.Unlock()
 	}
 
+	mpb.rendered = true
+	mpb.dirty = false
 	os.Stdout.Sync()
 }
 
-// Finish completes all progress bars
-func (mpb *MultiProgressBar) Finish() {
-	mpb.mutex.Lock()
-	defer mpb.mutex.Unlock()
-
-	for _, bar := range mpb.bars {
-		bar.mutex.Lock()
-		if !bar.finished {
-			bar.current = 100
-			bar.finished = true
-			bar.currentOp = "✅ 完成"
-		}
-		bar.mutex.Unlock()
-	}
-
-	mpb.renderAll()
-	fmt.Println() // 最后换行
-}
-
 // NewProgressBar creates a new progress bar
 func NewProgressBar(total int, prefix string) *ProgressBar {
 	return &ProgressBar{
@@ -460,7 +622,7 @@ func (pb *ProgressBar) Finish() {
 	pb.render()
 
 	// 确保输出完成后换行
-	fmt.Print("\n")
+	fmt.Fprint(output, "\n")
 	os.Stdout.Sync()
 }
 
@@ -515,11 +677,11 @@ func (pb *ProgressBar) render() {
 	emptyBar := strings.Repeat("░", pb.width-filled)
 
 	// 清除当前行
-	fmt.Print("\r\033[K")
+	fmt.Fprint(output, "\r\033[K")
 
 	// 检查是否完成
 	if pb.current >= pb.total {
-		fmt.Printf("%s [%s] 100%% (%d/%d) ✅ 完成",
+		fmt.Fprintf(output, "%s [%s] 100%% (%d/%d) ✅ 完成",
 			pb.prefix,
 			green.Sprint(filledBar+emptyBar),
 			pb.total,
@@ -529,7 +691,7 @@ func (pb *ProgressBar) render() {
 		if pb.currentOp != "" {
 			message = fmt.Sprintf(" - %s", pb.currentOp)
 		}
-		fmt.Printf("%s [%s] %.0f%% (%d/%d)%s",
+		fmt.Fprintf(output, "%s [%s] %.0f%% (%d/%d)%s",
 			pb.prefix,
 			green.Sprint(filledBar)+white.Sprint(emptyBar),
 			percent*100,
@@ -539,28 +701,63 @@ func (pb *ProgressBar) render() {
 	}
 }
 
-// Confirm asks for user confirmation
+// Confirm asks for user confirmation, defaulting to No
 func Confirm(message string) bool {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("\n%s [y/N]: ", message)
+	return ConfirmWithDefault(message, false)
+}
+
+// ConfirmWithDefault 询问用户确认，直接回车时使用默认答案
+// defaultYes 为 true 时提示 [Y/n]，否则提示 [y/N]；读取输入失败时视为否
+func ConfirmWithDefault(message string, defaultYes bool) bool {
+	prompt := "[y/N]"
+	if defaultYes {
+		prompt = "[Y/n]"
+	}
 
-	response, err := reader.ReadString('\n')
+	PrintEmptyLine()
+	response, err := Input(message+" "+prompt, "")
 	if err != nil {
 		return false
 	}
 
-	response = strings.ToLower(strings.TrimSpace(response))
-	return response == "y" || response == "yes"
+	switch strings.ToLower(response) {
+	case "":
+		return defaultYes
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Input 读取一行输入并去除首尾空白，直接回车时返回 defaultValue
+// 提示为 "prompt: "，defaultValue 不为空时显示为 "prompt [defaultValue]: "
+func Input(prompt, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		prompt = fmt.Sprintf("%s [%s]", prompt, defaultValue)
+	}
+	fmt.Fprintf(output, "%s: ", prompt)
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return defaultValue, nil
+	}
+	return line, nil
 }
 
 // PrintSeparator prints a simple separator line
 func PrintSeparator() {
-	fmt.Printf("%s\n", strings.Repeat("─", 60))
+	fmt.Fprintf(output, "%s\n", strings.Repeat("─", 60))
 }
 
 // PrintEmptyLine prints an empty line
 func PrintEmptyLine() {
-	fmt.Println()
+	fmt.Fprintln(output)
 }
 
 // Fatal prints an error message and exits
@@ -586,13 +783,12 @@ type SelectionItem struct {
 
 // MultiSelect displays a multi-selection menu and returns selected items
 func MultiSelect(title string, items []SelectionItem) ([]SelectionItem, error) {
-	reader := bufio.NewReader(os.Stdin)
 	selected := make([]SelectionItem, len(items))
 	copy(selected, items)
 
 	for {
 		// Clear screen (optional, comment out if not desired)
-		// fmt.Print("\033[H\033[2J")
+		// fmt.Fprint(output, "\033[H\033[2J")
 
 		PrintHeader(title)
 		PrintEmptyLine()
@@ -604,11 +800,11 @@ func MultiSelect(title string, items []SelectionItem) ([]SelectionItem, error) {
 				status = green.Sprint("[✓]")
 			}
 
-			fmt.Printf("%s %d. %s", status, i+1, item.DisplayName)
+			fmt.Fprintf(output, "%s %d. %s", status, i+1, item.DisplayName)
 			if item.Description != "" {
-				fmt.Printf(" - %s", cyan.Sprint(item.Description))
+				fmt.Fprintf(output, " - %s", cyan.Sprint(item.Description))
 			}
-			fmt.Println()
+			fmt.Fprintln(output)
 		}
 
 		PrintEmptyLine()
@@ -620,14 +816,11 @@ func MultiSelect(title string, items []SelectionItem) ([]SelectionItem, error) {
 		PrintItem("• 按 Enter 确认选择")
 		PrintEmptyLine()
 
-		fmt.Print("请输入选择: ")
-		input, err := reader.ReadString('\n')
+		input, err := Input("请输入选择", "")
 		if err != nil {
 			return nil, err
 		}
 
-		input = strings.TrimSpace(input)
-
 		if input == "" {
 			// 返回选中的项目
 			var result []SelectionItem
@@ -725,10 +918,148 @@ func ConfirmSelection(title string, items []SelectionItem) bool {
 	}
 
 	PrintEmptyLine()
-	return Confirm(fmt.Sprintf("确认处理以上 %d 个项目?", len(items)))
+	return ConfirmWithDefault(fmt.Sprintf("确认处理以上 %d 个项目?", len(items)), true)
 }
 
 // PrintSubItem prints a sub-item with indentation
 func PrintSubItem(message string) {
-	fmt.Printf("  %s\n", message)
+	fmt.Fprintf(output, "  %s\n", message)
+}
+
+// Diff 一项新旧值的对比，如服务镜像从旧标签更新到新标签
+type Diff struct {
+	Label string
+	Old   string
+	New   string
+}
+
+// diffOldStyle 旧值使用红色删除线显示
+var diffOldStyle = color.New(color.FgRed, color.CrossedOut)
+
+// formatDiff 返回带颜色的 "旧值 → 新值"
+func formatDiff(old, new string) string {
+	return diffOldStyle.Sprint(old) + white.Sprint(" → ") + green.Sprint(new)
+}
+
+// PrintDiff 打印一项对比：标签加粗，旧值为红色删除线，新值为绿色
+func PrintDiff(label, old, new string) {
+	fmt.Fprintf(output, "  %s: %s\n", bold.Sprint(label), formatDiff(old, new))
+}
+
+// PrintDiffTable 批量打印对比，标签按最长的标签对齐
+func PrintDiffTable(diffs []Diff) {
+	width := 0
+	for _, diff := range diffs {
+		width = max(width, visibleWidth(diff.Label))
+	}
+
+	for _, diff := range diffs {
+		label := padCell(diff.Label, visibleWidth(diff.Label), width, Left)
+		fmt.Fprintf(output, "  %s  %s\n", bold.Sprint(label), formatDiff(diff.Old, diff.New))
+	}
+}
+
+// PrintUpdateResultsTable 以表格打印每个服务的更新结果，失败的服务排在最前，其次是跳过的服务，
+// 同一状态内按服务名排序
+func PrintUpdateResultsTable(results []*types.UpdateResult) {
+	sorted := make([]*types.UpdateResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ri, rj := updateResultRank(sorted[i]), updateResultRank(sorted[j]); ri != rj {
+			return ri < rj
+		}
+		return sorted[i].Service < sorted[j].Service
+	})
+
+	table := NewTable().
+		Column("服务", Left).
+		Column("原镜像", Left).
+		Column("新镜像", Left).
+		Column("状态", Center).
+		Column("耗时", Right).
+		Column("变更", Center)
+
+	for _, result := range sorted {
+		status := "✅"
+		switch updateResultRank(result) {
+		case 0:
+			status = "❌"
+		case 1:
+			status = "⏭"
+		}
+
+		duration := "-"
+		if result.Duration > 0 {
+			duration = result.Duration.Round(100 * time.Millisecond).String()
+		}
+
+		changed := "否"
+		if result.Changed {
+			changed = "是"
+		}
+
+		table.AddRow(result.Service, result.OldImage, result.NewImage, status, duration, changed)
+	}
+
+	table.Print()
+}
+
+// updateResultRank 返回结果的排序优先级：失败为 0，跳过为 1，成功为 2
+func updateResultRank(result *types.UpdateResult) int {
+	switch {
+	case result.SkipReason != "":
+		return 1
+	case !result.Success || result.Error != nil:
+		return 0
+	default:
+		return 2
+	}
+}
+
+// FormatSize 将字节数格式化为易读的大小，如 1.5 GB
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatUpdateSummary 生成单行的更新结果汇总，如
+// [2024-01-15 14:00] Updated: 12, Skipped: 3, Failed: 0 (0.5 GB reclaimed)
+// reclaimed 小于 0 时不显示回收空间
+func FormatUpdateSummary(results []*types.UpdateResult, reclaimed int64) string {
+	succeeded, skipped, failed := countResults(results)
+
+	summary := fmt.Sprintf("[%s] Updated: %d, Skipped: %d, Failed: %d",
+		time.Now().Format("2006-01-02 15:04"), succeeded, skipped, failed)
+	if reclaimed >= 0 {
+		summary += fmt.Sprintf(" (%s reclaimed)", FormatSize(reclaimed))
+	}
+
+	return summary
+}
+
+// PrintUpdateSummary 以单行格式输出更新结果汇总，有失败时以错误颜色显示
+func PrintUpdateSummary(results []*types.UpdateResult) {
+	printUpdateSummaryLine(results, FormatUpdateSummary(results, -1))
+}
+
+// PrintUpdateSummaryWithReclaimed 以单行格式输出更新结果汇总和清理镜像回收的空间
+func PrintUpdateSummaryWithReclaimed(results []*types.UpdateResult, reclaimed int64) {
+	printUpdateSummaryLine(results, FormatUpdateSummary(results, reclaimed))
+}
+
+// printUpdateSummaryLine 根据是否有失败的结果选择输出颜色
+func printUpdateSummaryLine(results []*types.UpdateResult, summary string) {
+	if _, _, failed := countResults(results); failed > 0 {
+		errorStyle.Println(summary)
+		return
+	}
+	fmt.Fprintln(output, summary)
 }
@@ -0,0 +1,64 @@
+package ui
+
+import "strings"
+
+// Alignment 表格列的对齐方式
+type Alignment int
+
+const (
+	Left   Alignment = iota // 左对齐（默认）
+	Right                   // 右对齐，适用于序号、数量、大小等数值列
+	Center                  // 居中对齐
+)
+
+// Table 表格构建器，支持为每一列设置对齐方式
+type Table struct {
+	headers    []string
+	alignments []Alignment
+	rows       [][]string
+}
+
+// NewTable 创建空表格
+func NewTable() *Table {
+	return &Table{}
+}
+
+// Column 添加一列并指定其对齐方式
+func (t *Table) Column(name string, alignment Alignment) *Table {
+	t.headers = append(t.headers, name)
+	t.alignments = append(t.alignments, alignment)
+	return t
+}
+
+// AddRow 添加一行数据，单元格顺序与列的添加顺序一致
+func (t *Table) AddRow(cells ...string) *Table {
+	t.rows = append(t.rows, cells)
+	return t
+}
+
+// Print 打印表格，行为与 PrintTable 相同
+func (t *Table) Print() {
+	printTable(t.headers, t.alignments, t.rows)
+}
+
+// alignmentAt 返回指定列的对齐方式，未设置时为左对齐
+func alignmentAt(alignments []Alignment, i int) Alignment {
+	if i < len(alignments) {
+		return alignments[i]
+	}
+	return Left
+}
+
+// padCell 按对齐方式用空格将内容填充到指定宽度，内容宽度为 cellWidth
+func padCell(cell string, cellWidth, width int, alignment Alignment) string {
+	padding := max(0, width-cellWidth)
+	switch alignment {
+	case Right:
+		return strings.Repeat(" ", padding) + cell
+	case Center:
+		left := padding / 2
+		return strings.Repeat(" ", left) + cell + strings.Repeat(" ", padding-left)
+	default:
+		return cell + strings.Repeat(" ", padding)
+	}
+}
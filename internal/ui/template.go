@@ -0,0 +1,85 @@
+package ui
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"compman/pkg/types"
+
+	"github.com/fatih/color"
+)
+
+//go:embed templates/update_results.tmpl
+var defaultUpdateResultsTemplate string
+
+// templateFuncs 更新结果模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"succeeded": func(results []*types.UpdateResult) int {
+		succeeded, _, _ := countResults(results)
+		return succeeded
+	},
+	"skipped": func(results []*types.UpdateResult) int {
+		_, skipped, _ := countResults(results)
+		return skipped
+	},
+	"failed": func(results []*types.UpdateResult) int {
+		_, _, failed := countResults(results)
+		return failed
+	},
+	"success": func(message string) string { return successStyle.Sprintf("✅ %s", message) },
+	"info":    func(message string) string { return infoStyle.Sprintf("ℹ️  %s", message) },
+	"warning": func(message string) string { return warningStyle.Sprintf("⚠️  %s", message) },
+	"error":   func(message string) string { return errorStyle.Sprintf("❌ %s", message) },
+	"green":   func(v interface{}) string { return color.GreenString("%v", v) },
+	"yellow":  func(v interface{}) string { return color.YellowString("%v", v) },
+	"red":     func(v interface{}) string { return color.RedString("%v", v) },
+	"cyan":    func(v interface{}) string { return color.CyanString("%v", v) },
+	"bold":    func(v interface{}) string { return bold.Sprint(v) },
+}
+
+// countResults 统计成功、跳过和失败的更新结果数量
+func countResults(results []*types.UpdateResult) (succeeded, skipped, failed int) {
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		} else if result.Error != nil {
+			failed++
+		} else {
+			skipped++
+		}
+	}
+	return succeeded, skipped, failed
+}
+
+// LoadUpdateResultsTemplate 读取 Go text/template 格式的更新结果模板，path 为空时使用默认模板
+func LoadUpdateResultsTemplate(path string) (*template.Template, error) {
+	text := defaultUpdateResultsTemplate
+	name := "update_results.tmpl"
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取输出模板失败: %v", err)
+		}
+		text = string(content)
+		name = path
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析输出模板失败: %v", err)
+	}
+
+	return tmpl, nil
+}
+
+// RenderUpdateResults 使用模板输出更新结果，模板数据为 []*types.UpdateResult
+func RenderUpdateResults(w io.Writer, tmpl *template.Template, results []*types.UpdateResult) error {
+	if err := tmpl.Execute(w, results); err != nil {
+		return fmt.Errorf("执行输出模板失败: %v", err)
+	}
+	return nil
+}
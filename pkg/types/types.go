@@ -4,11 +4,14 @@ import "time"
 
 // ComposeFile represents a Docker Compose file structure
 type ComposeFile struct {
-	Version  string                 `yaml:"version"`
-	Services map[string]Service     `yaml:"services"`
-	Networks map[string]interface{} `yaml:"networks,omitempty"`
-	Volumes  map[string]interface{} `yaml:"volumes,omitempty"`
-	FilePath string                 `yaml:"-"` // 文件路径，不序列化
+	Version     string                 `yaml:"version"`
+	Services    map[string]Service     `yaml:"services"`
+	Networks    map[string]interface{} `yaml:"networks,omitempty"`
+	Volumes     map[string]interface{} `yaml:"volumes,omitempty"`
+	XLabels     map[string]string      `yaml:"x-labels,omitempty"` // 项目级标签扩展字段
+	FilePath    string                 `yaml:"-"`                  // 文件路径，不序列化
+	ProjectName string                 `yaml:"-"`                  // 项目名称，默认为文件所在目录名
+	Checksum    string                 `yaml:"-"`                  // 文件原始内容的 SHA-256 摘要，用于判断文件是否变化
 }
 
 // Service represents a service in Docker Compose
@@ -16,15 +19,16 @@ type Service struct {
 	Image       string                 `yaml:"image,omitempty"`
 	Build       *BuildConfig           `yaml:"build,omitempty"`
 	Environment interface{}            `yaml:"environment,omitempty"` // 可以是 []string 或 map[string]string
-	Ports       []string               `yaml:"ports,omitempty"`
+	Ports       []interface{}          `yaml:"ports,omitempty"`       // 短格式字符串或长格式 map
 	Volumes     []string               `yaml:"volumes,omitempty"`
-	DependsOn   []string               `yaml:"depends_on,omitempty"`
+	DependsOn   interface{}            `yaml:"depends_on,omitempty"` // 可以是服务名列表或 服务名 -> {condition} 的映射
 	Networks    []string               `yaml:"networks,omitempty"`
 	Restart     string                 `yaml:"restart,omitempty"`
 	ExtraHosts  []string               `yaml:"extra_hosts,omitempty"`
 	Command     interface{}            `yaml:"command,omitempty"`
 	Labels      map[string]string      `yaml:"labels,omitempty"`
-	Other       map[string]interface{} `yaml:",inline"` // 捕获其他字段
+	Profiles    []string               `yaml:"profiles,omitempty"` // 服务所属的 profiles，为空时总是启用
+	Other       map[string]interface{} `yaml:",inline"`            // 捕获其他字段
 }
 
 // BuildConfig represents build configuration
@@ -37,24 +41,67 @@ type BuildConfig struct {
 
 // Config represents application configuration
 type Config struct {
-	ComposePaths     []string            `yaml:"compose_paths"`      // Compose 文件搜索路径
-	ImageTagStrategy string              `yaml:"image_tag_strategy"` // 镜像标签策略 (latest, semver)
-	Environment      string              `yaml:"environment"`        // 环境 (dev, prod, etc.)
-	SemverPattern    string              `yaml:"semver_pattern"`     // Semver 匹配模式
-	ExcludeImages    []string            `yaml:"exclude_images"`     // 排除的镜像
-	DryRun           bool                `yaml:"dry_run"`            // 干运行模式
-	BackupEnabled    bool                `yaml:"backup_enabled"`     // 是否备份原文件
-	Timeout          time.Duration       `yaml:"timeout"`            // 操作超时时间
-	DockerConfig     DockerConfig        `yaml:"docker_config"`      // Docker 配置
-	SelectedServices map[string][]string `yaml:"-"`                  // 选中的服务 (文件路径 -> 服务名列表)
+	SchemaVersion       int                 `yaml:"schema_version"`        // 配置文件结构版本，用于自动迁移旧版本配置
+	ComposePaths        []string            `yaml:"compose_paths"`         // Compose 文件搜索路径
+	ImageTagStrategy    string              `yaml:"image_tag_strategy"`    // 镜像标签策略 (latest, semver)
+	Environment         string              `yaml:"environment"`           // 环境 (dev, prod, etc.)
+	SemverPattern       string              `yaml:"semver_pattern"`        // Semver 匹配模式
+	RegexPattern        string              `yaml:"regex_pattern"`         // regex 策略匹配标签的正则表达式
+	RegexCaptureGroup   int                 `yaml:"regex_capture_group"`   // regex 策略用于排序的捕获组序号
+	ExcludeImages       []string            `yaml:"exclude_images"`        // 排除的镜像
+	DryRun              bool                `yaml:"dry_run"`               // 干运行模式
+	BackupEnabled       bool                `yaml:"backup_enabled"`        // 是否备份原文件
+	Timeout             time.Duration       `yaml:"timeout"`               // 操作超时时间
+	RegistryAPITimeout  time.Duration       `yaml:"registry_api_timeout"`  // 镜像仓库 API 请求超时时间
+	PullTimeout         time.Duration       `yaml:"pull_timeout"`          // 拉取镜像的超时时间，为 0 时使用 Timeout
+	UpTimeout           time.Duration       `yaml:"up_timeout"`            // up -d 重启服务的超时时间，为 0 时使用 Timeout
+	GracePeriod         time.Duration       `yaml:"grace_period"`          // up -d 重建容器时等待容器停止的宽限期，为 0 时使用 Docker 默认值
+	RemoveOrphans       bool                `yaml:"remove_orphans"`        // up -d 时删除 Compose 文件中已移除的服务遗留的容器
+	RollbackStrategy    string              `yaml:"rollback_strategy"`     // --rollback-on-failure 回滚方式：compose-file 只恢复文件，containers 使用原镜像重建容器，full 两者都做
+	DockerConfig        DockerConfig        `yaml:"docker_config"`         // Docker 配置
+	WebhookURL          string              `yaml:"webhook_url"`           // 更新完成后通知的 Webhook 地址
+	ComposeEnvFile      string              `yaml:"compose_env_file"`      // 传递给 docker-compose 命令的环境变量文件
+	CleanVolumes        bool                `yaml:"clean_volumes"`         // 清理时是否同时清理未使用的数据卷
+	ProjectNameOverride map[string]string   `yaml:"project_name_override"` // 自定义项目名称 (文件路径 -> 项目名称)
+	RestartPolicy       string              `yaml:"restart_policy"`        // 更新时统一设置的服务重启策略，为空时不修改
+	MaxParallel         int                 `yaml:"max_parallel"`          // 同时更新的 Compose 文件数量上限
+	ComposeProfiles     []string            `yaml:"compose_profiles"`      // 启用的 Compose profiles，为空时处理所有服务
+	CompactOutput       bool                `yaml:"compact_output"`        // 以单行格式输出更新结果汇总
+	CleanNetworks       bool                `yaml:"clean_networks"`        // 清理时是否同时清理未使用的网络
+	TagFormat           string              `yaml:"tag_format"`            // 转换 semver 策略返回标签的 Go 模板，如 release-{{.Version}}
+	RespectDependencies bool                `yaml:"respect_dependencies"`  // 按 depends_on 依赖层级依次拉取镜像
+	BackupBeforePull    bool                `yaml:"backup_before_pull"`    // 拉取镜像前备份 Compose 文件，不论是否修改文件
+	SkipUpToDate        bool                `yaml:"skip_up_to_date"`       // 跳过已是目标版本的服务，不拉取镜像也不重启 (仅 semver 策略)
+	UseDirectPull       bool                `yaml:"use_direct_pull"`       // 通过 Docker API 直接拉取镜像并显示每层进度，代替 docker-compose pull
+	Platform            string              `yaml:"platform"`              // 拉取镜像的目标平台 (os/arch[/variant])，为空时使用 Docker 默认平台
+	ComposeVersion      string              `yaml:"compose_version"`       // 使用的 Compose 命令版本：v1 为 docker-compose，v2 为 docker compose 插件，auto 时自动检测
+	ComposeEncoding     string              `yaml:"compose_encoding"`      // Compose 文件编码：utf-8、cp1252 或 latin-1，UTF-8 文件开头的 BOM 会被自动去除
+	UseComposeLibrary   bool                `yaml:"use_compose_library"`   // 扫描时使用 compose-spec/compose-go 加载 Compose 文件，支持 extends、include 和变量插值
+	LogFile             string              `yaml:"log_file"`              // 更新时追加写入的纯文本日志文件，为空时不写日志
+	PathsFile           string              `yaml:"paths_file"`            // 每行一个 Compose 文件搜索路径的文件，其中的路径追加到 ComposePaths
+	PinnedServices      map[string][]string `yaml:"pinned_services"`       // 固定更新的服务 (文件绝对路径 -> 服务名列表)，运行时未选择服务时使用
+	SelectedServices    map[string][]string `yaml:"-"`                     // 选中的服务 (文件路径 -> 服务名列表)
+	ImageTagOverrides   map[string]string   `yaml:"-"`                     // 本次运行指定的镜像标签 (镜像仓库名 -> 标签)，优先于标签策略
+	ImageRegistryMap    map[string]string   `yaml:"-"`                     // 本次运行替换的镜像仓库前缀 (原前缀 -> 新前缀)
+	ComposeArgs         []string            `yaml:"-"`                     // 附加到 docker-compose pull 和 up -d 命令的额外参数
+	ServicesOnly        string              `yaml:"-"`                     // 仅更新服务名匹配该正则表达式的服务，为空时不限制
+	SinceTag            string              `yaml:"-"`                     // 仅更新镜像标签低于该版本的服务，为空时不限制
+	NoUp                bool                `yaml:"-"`                     // 只拉取镜像和更新 Compose 文件，不执行 up -d 重建容器
+	ImageBuild          bool                `yaml:"-"`                     // 重新构建只有 build 配置 (没有 image) 的服务，否则跳过这些服务
+	WaitForStable       time.Duration       `yaml:"-"`                     // 每个 Compose 文件更新成功后等待的时间，再处理下一个文件
+	FailFast            bool                `yaml:"-"`                     // 某个文件更新失败后不再处理剩余的文件
+	UpdateStrategy      string              `yaml:"-"`                     // 更新策略: file-by-file (逐个文件拉取并重启) 或 batch (全部拉取后再依次重启)，为空时为 file-by-file
+	RollbackOnFailure   bool                `yaml:"-"`                     // 更新后服务状态异常时按 RollbackStrategy 回滚
 }
 
 // DockerConfig represents Docker client configuration
 type DockerConfig struct {
-	Host       string `yaml:"host"`        // Docker daemon 地址
-	APIVersion string `yaml:"api_version"` // API 版本
-	TLSVerify  bool   `yaml:"tls_verify"`  // TLS 验证
-	CertPath   string `yaml:"cert_path"`   // 证书路径
+	Host       string `yaml:"host"`         // Docker daemon 地址
+	APIVersion string `yaml:"api_version"`  // API 版本
+	TLSVerify  bool   `yaml:"tls_verify"`   // TLS 验证
+	CertPath   string `yaml:"cert_path"`    // 证书路径
+	SSHHost    string `yaml:"ssh_host"`     // 通过 SSH 连接远程 Docker daemon，格式为 user@host[:port]
+	SSHKeyPath string `yaml:"ssh_key_path"` // SSH 私钥路径，为空时使用 ssh 默认配置
 }
 
 // ImageTagStrategy defines interface for image tag strategies
@@ -88,14 +135,137 @@ type ImageInfo struct {
 	InUse      bool
 }
 
+// VolumeInfo contains information about a Docker volume
+type VolumeInfo struct {
+	Name       string
+	Driver     string
+	Mountpoint string
+	InUse      bool
+	Size       int64 // 仅当 Docker 提供使用数据时有效，否则为 -1
+}
+
+// NetworkInfo contains information about a Docker network
+type NetworkInfo struct {
+	ID     string
+	Name   string
+	Driver string
+}
+
+// ScanStats represents aggregate statistics across scanned compose files
+type ScanStats struct {
+	TotalFiles         int            // Compose 文件数量
+	TotalServices      int            // 服务总数
+	UniqueImages       int            // 不重复的镜像数量
+	LatestTagImages    int            // 使用 latest 标签（或未指定标签）的镜像数量
+	SemverTagImages    int            // 使用语义版本标签的镜像数量
+	OfficialImages     int            // Docker Hub 官方镜像数量
+	ThirdPartyImages   int            // 第三方镜像数量（用户镜像或其他镜像仓库）
+	BuildOnlyServices  int            // 仅通过 build 构建、未指定镜像的服务数量
+	AvgServicesPerFile float64        // 平均每个文件的服务数量
+	ByRegistry         map[string]int // 镜像仓库地址 -> 不重复的镜像数量
+}
+
+// ScanInventory is the YAML inventory of scanned compose projects (scan --output-yaml)
+// 字段名和结构是对外约定的格式，修改时需保持兼容
+type ScanInventory struct {
+	Projects []ProjectInventory `yaml:"projects"`
+}
+
+// ProjectInventory describes a compose project in the scan inventory
+type ProjectInventory struct {
+	Name     string             `yaml:"name"`      // 项目名称
+	FilePath string             `yaml:"file_path"` // Compose 文件路径
+	Services []ServiceInventory `yaml:"services"`  // 服务列表，按服务名排序
+}
+
+// ServiceInventory describes a service in the scan inventory
+type ServiceInventory struct {
+	Name  string `yaml:"name"`  // 服务名称
+	Image string `yaml:"image"` // Compose 文件中的完整镜像引用，仅通过 build 构建的服务为空
+	Tag   string `yaml:"tag"`   // 镜像标签，未指定时为 latest；仅使用摘要或没有镜像时为空
+}
+
+// ProjectInfo represents the runtime state of a Docker Compose project
+type ProjectInfo struct {
+	Name     string          // 项目名称 (com.docker.compose.project 标签)
+	Services []ServiceStatus // 项目中的容器状态，按服务名排序
+}
+
+// ServiceStatus represents the state of a single service container
+type ServiceStatus struct {
+	ServiceName string // 服务名称 (com.docker.compose.service 标签)
+	ContainerID string // 容器 ID
+	ImageID     string // 容器使用的镜像 ID
+	State       string // 容器状态，如 running、exited、restarting
+	Health      string // 健康检查状态 (healthy, unhealthy, starting)，未配置健康检查时为空
+}
+
+// IsHealthy 判断服务是否正在运行且健康检查未失败
+func (s *ServiceStatus) IsHealthy() bool {
+	return s.State == "running" && s.Health != "unhealthy"
+}
+
+// UnhealthyServices 返回未运行或健康检查失败的服务
+func (p *ProjectInfo) UnhealthyServices() []ServiceStatus {
+	var unhealthy []ServiceStatus
+	for _, service := range p.Services {
+		if !service.IsHealthy() {
+			unhealthy = append(unhealthy, service)
+		}
+	}
+	return unhealthy
+}
+
 // UpdateResult represents the result of an update operation
 type UpdateResult struct {
-	Service   string
-	OldImage  string
-	NewImage  string
-	Success   bool
-	Error     error
-	UpdatedAt time.Time
+	Service    string
+	OldImage   string
+	NewImage   string
+	Success    bool
+	Error      error
+	UpdatedAt  time.Time
+	Duration   time.Duration // 处理所在 Compose 文件的耗时
+	SkipReason string        // 跳过原因，未跳过时为空
+	Changed    bool          // 服务是否实际发生变化（如容器被重建）
+}
+
+// UpdateReport represents a persisted report of an update run
+type UpdateReport struct {
+	StartedAt    time.Time           `json:"started_at"`
+	Duration     string              `json:"duration"`
+	ComposePaths []string            `json:"compose_paths"`
+	Results      []UpdateReportEntry `json:"results"`
+}
+
+// UpdateReportEntry is the JSON representation of an UpdateResult
+type UpdateReportEntry struct {
+	Service    string    `json:"service"`
+	OldImage   string    `json:"old_image"`
+	NewImage   string    `json:"new_image"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Duration   string    `json:"duration"`
+	SkipReason string    `json:"skip_reason,omitempty"`
+	Changed    bool      `json:"changed"`
+}
+
+// ReportEntry converts the result into its JSON report representation
+func (r *UpdateResult) ReportEntry() UpdateReportEntry {
+	entry := UpdateReportEntry{
+		Service:    r.Service,
+		OldImage:   r.OldImage,
+		NewImage:   r.NewImage,
+		Success:    r.Success,
+		UpdatedAt:  r.UpdatedAt,
+		Duration:   r.Duration.String(),
+		SkipReason: r.SkipReason,
+		Changed:    r.Changed,
+	}
+	if r.Error != nil {
+		entry.Error = r.Error.Error()
+	}
+	return entry
 }
 
 // ScanResult represents the result of scanning compose files